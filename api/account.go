@@ -13,6 +13,9 @@ const (
 	// API Keycloak out-of-the-box
 	kcAccountLinkedAccountsPath         = "/auth/realms/:realm/account/linked-accounts"
 	kcAccountLinkedAccountsProviderPath = kcAccountLinkedAccountsPath + "/:providerAlias"
+	kcAccountApplicationsPath           = "/auth/realms/:realm/account/applications"
+	kcAccountApplicationConsentPath     = kcAccountApplicationsPath + "/:clientId/consent"
+	kcBrokerTokenPath                   = "/auth/realms/:realm/broker/:providerAlias/token"
 
 	// API keycloak-rest-extensions account
 	ctAccountExtensionAPIPath            = "/auth/realms/master/api/account/realms/:realm"
@@ -135,3 +138,35 @@ func (c *AccountClient) DeleteLinkedAccount(accessToken string, realmName string
 	return c.client.forRealm(accessToken, realmName).
 		delete(accessToken, url.Path(kcAccountLinkedAccountsProviderPath), url.Param("realm", realmName), url.Param("providerAlias", providerAlias), hdrAcceptJSON)
 }
+
+// GetUserConsents returns the applications the user granted a consent to
+func (c *AccountClient) GetUserConsents(accessToken string, realmName string) ([]keycloak.ApplicationRepresentation, error) {
+	var apps = []keycloak.ApplicationRepresentation{}
+	var err = c.client.forRealm(accessToken, realmName).
+		get(accessToken, &apps, url.Path(kcAccountApplicationsPath), url.Param("realm", realmName), hdrAcceptJSON)
+	if err != nil {
+		return nil, err
+	}
+	var resp = []keycloak.ApplicationRepresentation{}
+	for _, app := range apps {
+		if app.Consent != nil {
+			resp = append(resp, app)
+		}
+	}
+	return resp, nil
+}
+
+// RevokeUserConsent revokes the consent and the offline tokens granted by the user to the client
+func (c *AccountClient) RevokeUserConsent(accessToken string, realmName string, clientID string) error {
+	return c.client.forRealm(accessToken, realmName).
+		delete(accessToken, url.Path(kcAccountApplicationConsentPath), url.Param("realm", realmName), url.Param("clientId", clientID), hdrAcceptJSON)
+}
+
+// GetBrokeredIdpToken returns the token issued by the identity provider and stored when the user logged in through it.
+// The identity provider must be configured to store tokens and the user needs the broker read-token role.
+func (c *AccountClient) GetBrokeredIdpToken(accessToken string, realmName string, providerAlias string) (keycloak.BrokeredTokenRepresentation, error) {
+	var resp = keycloak.BrokeredTokenRepresentation{}
+	var err = c.client.forRealm(accessToken, realmName).
+		get(accessToken, &resp, url.Path(kcBrokerTokenPath), url.Param("realm", realmName), url.Param("providerAlias", providerAlias), hdrAcceptJSON)
+	return resp, err
+}
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"github.com/cloudtrust/keycloak-client/v2"
 	"gopkg.in/h2non/gentleman.v2/plugins/url"
 )
 
@@ -22,6 +23,13 @@ func (c *Client) GetAttackDetectionStatus(accessToken string, realmName, userID
 	return resp, err
 }
 
+// GetBruteForceStatus gets the typed status of a user in brute force detection.
+func (c *Client) GetBruteForceStatus(accessToken string, realmName, userID string) (keycloak.BruteForceStatusRepresentation, error) {
+	var resp = keycloak.BruteForceStatusRepresentation{}
+	var err = c.forRealm(accessToken, realmName).get(accessToken, &resp, url.Path(kcAttackDetectionIDPath), url.Param("realm", realmName), url.Param("id", userID))
+	return resp, err
+}
+
 // ClearUserLoginFailures clear any user login failures for the user. This can release temporary disabled user.
 func (c *Client) ClearUserLoginFailures(accessToken string, realmName, userID string) error {
 	return c.forRealm(accessToken, realmName).delete(accessToken, url.Path(kcAttackDetectionIDPath), url.Param("realm", realmName), url.Param("id", userID))
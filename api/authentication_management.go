@@ -117,12 +117,46 @@ func (c *Client) CopyExistingAuthenticationFlow(accessToken string, realmName, f
 }
 
 // GetAuthenticationExecutionForFlow returns the authentication executions for a flow.
+//
+// Deprecated: Keycloak returns a list of executions. Use GetAuthenticationExecutionsForFlow or GetAuthenticationExecutionTreeForFlow.
 func (c *Client) GetAuthenticationExecutionForFlow(accessToken string, realmName, flowAlias string) (keycloak.AuthenticationExecutionInfoRepresentation, error) {
 	var resp = keycloak.AuthenticationExecutionInfoRepresentation{}
 	var err = c.forRealm(accessToken, realmName).get(accessToken, &resp, url.Path(kcAuthenticationManagementPath+"/flows/:flowAlias/executions"), url.Param("realm", realmName), url.Param("flowAlias", flowAlias))
 	return resp, err
 }
 
+// GetAuthenticationExecutionsForFlow returns the list of authentication executions for a flow, sub-flows included. The
+// executions are sorted as in the flow, their level and index give their position in the tree.
+func (c *Client) GetAuthenticationExecutionsForFlow(accessToken string, realmName, flowAlias string) ([]keycloak.AuthenticationExecutionInfoRepresentation, error) {
+	var resp = []keycloak.AuthenticationExecutionInfoRepresentation{}
+	var err = c.forRealm(accessToken, realmName).
+		get(accessToken, &resp, url.Path(kcAuthenticationManagementPath+"/flows/:flowAlias/executions"), url.Param("realm", realmName), url.Param("flowAlias", flowAlias))
+	return resp, err
+}
+
+// GetAuthenticationExecutionTreeForFlow returns the authentication executions for a flow as a tree. The configuration
+// of each execution is resolved.
+func (c *Client) GetAuthenticationExecutionTreeForFlow(accessToken string, realmName, flowAlias string) ([]keycloak.AuthenticationExecutionNode, error) {
+	var executions, err = c.GetAuthenticationExecutionsForFlow(accessToken, realmName, flowAlias)
+	if err != nil {
+		return nil, err
+	}
+	var configs = map[string]keycloak.AuthenticatorConfigRepresentation{}
+	for _, execution := range executions {
+		if execution.AuthenticationConfig == nil {
+			continue
+		}
+		if _, ok := configs[*execution.AuthenticationConfig]; !ok {
+			var config keycloak.AuthenticatorConfigRepresentation
+			if config, err = c.GetAuthenticatorConfig(accessToken, realmName, *execution.AuthenticationConfig); err != nil {
+				return nil, err
+			}
+			configs[*execution.AuthenticationConfig] = config
+		}
+	}
+	return keycloak.BuildAuthenticationExecutionTree(executions, configs), nil
+}
+
 // UpdateAuthenticationExecutionForFlow updates the authentication executions of a flow.
 func (c *Client) UpdateAuthenticationExecutionForFlow(accessToken string, realmName, flowAlias string, authExecInfo keycloak.AuthenticationExecutionInfoRepresentation) error {
 	return c.forRealm(accessToken, realmName).
@@ -153,6 +187,12 @@ func (c *Client) GetAuthenticationFlow(accessToken string, realmName, flowID str
 	return resp, err
 }
 
+// UpdateAuthenticationFlow updates the authentication flow for id.
+func (c *Client) UpdateAuthenticationFlow(accessToken string, realmName, flowID string, authFlow keycloak.AuthenticationFlowRepresentation) error {
+	return c.forRealm(accessToken, realmName).
+		put(accessToken, url.Path(kcAuthenticationManagementPath+"/flows/:id"), url.Param("realm", realmName), url.Param("id", flowID), body.JSON(authFlow))
+}
+
 // DeleteAuthenticationFlow deletes an authentication flow.
 func (c *Client) DeleteAuthenticationFlow(accessToken string, realmName, flowID string) error {
 	return c.forRealm(accessToken, realmName).
@@ -219,6 +259,20 @@ func (c *Client) DeleteRequiredAction(accessToken string, realmName, actionAlias
 		delete(accessToken, url.Path(kcAuthenticationRequiredActionPath), url.Param("realm", realmName), url.Param("alias", actionAlias))
 }
 
+// LowerRequiredActionPriority lowers the required action’s priority.
+func (c *Client) LowerRequiredActionPriority(accessToken string, realmName, actionAlias string) error {
+	_, err := c.forRealm(accessToken, realmName).
+		post(accessToken, nil, url.Path(kcAuthenticationRequiredActionPath+"/lower-priority"), url.Param("realm", realmName), url.Param("alias", actionAlias))
+	return err
+}
+
+// RaiseRequiredActionPriority raises the required action’s priority.
+func (c *Client) RaiseRequiredActionPriority(accessToken string, realmName, actionAlias string) error {
+	_, err := c.forRealm(accessToken, realmName).
+		post(accessToken, nil, url.Path(kcAuthenticationRequiredActionPath+"/raise-priority"), url.Param("realm", realmName), url.Param("alias", actionAlias))
+	return err
+}
+
 // GetUnregisteredRequiredActions returns a list of unregistered required actions.
 func (c *Client) GetUnregisteredRequiredActions(accessToken string, realmName string) ([]map[string]any, error) {
 	var resp = []map[string]any{}
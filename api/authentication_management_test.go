@@ -0,0 +1,53 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetAuthenticationExecutionTreeForFlow(t *testing.T) {
+	var configRequests = 0
+	var ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/auth/admin/realms/my-realm/authentication/flows/my-browser/executions":
+			w.Write([]byte(`[
+				{"id": "cookie", "providerId": "auth-cookie", "requirement": "ALTERNATIVE", "level": 0, "index": 0},
+				{"id": "forms", "displayName": "my-forms", "authenticationFlow": true, "requirement": "ALTERNATIVE", "level": 0, "index": 1},
+				{"id": "otp", "providerId": "auth-otp-form", "authenticationConfig": "otp-config", "level": 1, "index": 0},
+				{"id": "sms", "providerId": "auth-sms-form", "authenticationConfig": "otp-config", "level": 1, "index": 1}
+			]`))
+		case "/auth/admin/realms/my-realm/authentication/config/otp-config":
+			configRequests++
+			w.Write([]byte(`{"id": "otp-config", "alias": "otp", "config": {"length": "6"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+	var c = newTestClient(t, ts.URL)
+
+	t.Run("List of executions", func(t *testing.T) {
+		var executions, err = c.GetAuthenticationExecutionsForFlow("access-token", "my-realm", "my-browser")
+		assert.Nil(t, err)
+		assert.Len(t, executions, 4)
+	})
+	t.Run("Tree of executions", func(t *testing.T) {
+		var tree, err = c.GetAuthenticationExecutionTreeForFlow("access-token", "my-realm", "my-browser")
+		assert.Nil(t, err)
+		assert.Len(t, tree, 2)
+		assert.Nil(t, tree[0].Config)
+		assert.Len(t, tree[1].Children, 2)
+		assert.Equal(t, 1, tree[1].Children[1].GetIndex())
+		assert.Equal(t, "otp", *tree[1].Children[1].Config.Alias)
+		// Configurations shared by several executions are requested once
+		assert.Equal(t, 1, configRequests)
+	})
+	t.Run("Unknown flow", func(t *testing.T) {
+		var _, err = c.GetAuthenticationExecutionTreeForFlow("access-token", "my-realm", "other")
+		assert.NotNil(t, err)
+	})
+}
@@ -31,6 +31,12 @@ func (c *Client) GetClients(accessToken string, realmName string, paramKV ...str
 	return resp, err
 }
 
+// CreateClient creates a client from its ClientRepresentation. The clientId must be unique.
+func (c *Client) CreateClient(accessToken string, realmName string, clientRep keycloak.ClientRepresentation) (string, error) {
+	return c.forRealm(accessToken, realmName).
+		post(accessToken, nil, url.Path(kcClientsPath), url.Param("realm", realmName), body.JSON(clientRep))
+}
+
 // GetClient get the representation of the client. idClient is the id of client (not client-id).
 func (c *Client) GetClient(accessToken string, realmName, idClient string) (keycloak.ClientRepresentation, error) {
 	var resp = keycloak.ClientRepresentation{}
@@ -45,6 +51,12 @@ func (c *Client) UpdateClient(accessToken string, realmName, idClient string, cl
 		put(accessToken, url.Path(kcClientIDPath), url.Param("realm", realmName), url.Param("id", idClient), body.JSON(clientRep))
 }
 
+// DeleteClient deletes the client. idClient is the id of client (not client-id).
+func (c *Client) DeleteClient(accessToken string, realmName, idClient string) error {
+	return c.forRealm(accessToken, realmName).
+		delete(accessToken, url.Path(kcClientIDPath), url.Param("realm", realmName), url.Param("id", idClient))
+}
+
 // GetClientMappers gets mappers of the client specified by id
 func (c *Client) GetClientMappers(accessToken string, realmName, idClient string) ([]keycloak.ClientMapperRepresentation, error) {
 	var resp = []keycloak.ClientMapperRepresentation{}
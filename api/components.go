@@ -35,3 +35,9 @@ func (c *Client) UpdateComponent(accessToken string, realmName, componentID stri
 	return c.forRealm(accessToken, realmName).
 		put(accessToken, url.Path(kcComponentIDPath), url.Param("realm", realmName), url.Param("id", componentID), body.JSON(componentRep))
 }
+
+// DeleteComponent deletes the component.
+func (c *Client) DeleteComponent(accessToken string, realmName, componentID string) error {
+	return c.forRealm(accessToken, realmName).
+		delete(accessToken, url.Path(kcComponentIDPath), url.Param("realm", realmName), url.Param("id", componentID))
+}
@@ -1,6 +1,8 @@
 package api
 
 import (
+	"errors"
+
 	"github.com/cloudtrust/keycloak-client/v2"
 	"gopkg.in/h2non/gentleman.v2/plugins/body"
 	"gopkg.in/h2non/gentleman.v2/plugins/url"
@@ -10,6 +12,8 @@ const (
 	// API Keycloak out-of-the-box
 	kcGroupsPath                          = "/auth/admin/realms/:realm/groups"
 	kcGroupByIDPath                       = kcGroupsPath + "/:id"
+	kcGroupChildrenPath                   = kcGroupByIDPath + "/children"
+	kcGroupMembersPath                    = kcGroupByIDPath + "/members"
 	kcGroupClientRoleMappingPath          = kcGroupByIDPath + "/role-mappings/clients/:clientId"
 	kcAvailableGroupClientRoleMappingPath = kcGroupClientRoleMappingPath + "/available"
 )
@@ -30,12 +34,37 @@ func (c *Client) GetGroup(accessToken string, realmName string, groupID string)
 	return resp, err
 }
 
+// GetGroupMembers gets the users which are direct members of the group.
+// Parameters: first, max, briefRepresentation
+func (c *Client) GetGroupMembers(accessToken string, realmName string, groupID string, paramKV ...string) ([]keycloak.UserRepresentation, error) {
+	var resp = []keycloak.UserRepresentation{}
+	if len(paramKV)%2 != 0 {
+		return resp, errors.New(keycloak.MsgErrInvalidParam + "." + keycloak.EvenParams)
+	}
+	var plugins = append(c.createQueryPlugins(paramKV...), url.Path(kcGroupMembersPath), url.Param("realm", realmName), url.Param("id", groupID))
+	var err = c.forRealm(accessToken, realmName).
+		get(accessToken, &resp, plugins...)
+	return resp, err
+}
+
 // CreateGroup creates the group from its GroupRepresentation. The group name must be unique.
 func (c *Client) CreateGroup(accessToken string, reqRealmName string, group keycloak.GroupRepresentation) (string, error) {
 	return c.forRealm(accessToken, reqRealmName).
 		post(accessToken, nil, url.Path(kcGroupsPath), url.Param("realm", reqRealmName), body.JSON(group))
 }
 
+// CreateChildGroup creates a sub-group of the given parent group. The group name must be unique among its siblings.
+func (c *Client) CreateChildGroup(accessToken string, realmName string, parentGroupID string, group keycloak.GroupRepresentation) (string, error) {
+	return c.forRealm(accessToken, realmName).
+		post(accessToken, nil, url.Path(kcGroupChildrenPath), url.Param("realm", realmName), url.Param("id", parentGroupID), body.JSON(group))
+}
+
+// UpdateGroup updates the group. Sub-groups are ignored.
+func (c *Client) UpdateGroup(accessToken string, realmName string, groupID string, group keycloak.GroupRepresentation) error {
+	return c.forRealm(accessToken, realmName).
+		put(accessToken, url.Path(kcGroupByIDPath), url.Param("realm", realmName), url.Param("id", groupID), body.JSON(group))
+}
+
 // DeleteGroup deletes a specific group’s representation
 func (c *Client) DeleteGroup(accessToken string, realmName string, groupID string) error {
 	return c.forRealm(accessToken, realmName).
@@ -1,17 +1,25 @@
 package api
 
 import (
+	"bytes"
+
 	"github.com/cloudtrust/keycloak-client/v2"
 	"gopkg.in/h2non/gentleman.v2/plugins/body"
+	"gopkg.in/h2non/gentleman.v2/plugins/multipart"
 	"gopkg.in/h2non/gentleman.v2/plugins/url"
 )
 
 const (
 	// API Keycloak out-of-the-box
-	kcIdpsPath        = "/auth/admin/realms/:realm/identity-provider/instances"
-	kcIdpAliasPath    = kcIdpsPath + "/:alias"
-	kcIdpMappersPath  = kcIdpAliasPath + "/mappers"
-	kcIdpMapperIDPath = kcIdpMappersPath + "/:id"
+	kcIdpRootPath         = "/auth/admin/realms/:realm/identity-provider"
+	kcIdpsPath            = kcIdpRootPath + "/instances"
+	kcIdpAliasPath        = kcIdpsPath + "/:alias"
+	kcIdpMappersPath      = kcIdpAliasPath + "/mappers"
+	kcIdpMapperIDPath     = kcIdpMappersPath + "/:id"
+	kcIdpMapperTypesPath  = kcIdpAliasPath + "/mapper-types"
+	kcIdpImportConfigPath = kcIdpRootPath + "/import-config"
+	kcIdpProviderPath     = kcIdpRootPath + "/providers/:providerId"
+	kcServerInfoPath      = "/auth/admin/serverinfo"
 )
 
 // GetIdps gets the list of identity providers
@@ -75,3 +83,62 @@ func (c *Client) DeleteIdpMapper(accessToken string, realmName string, idpAlias
 	return c.forRealm(accessToken, realmName).
 		delete(accessToken, url.Path(kcIdpMapperIDPath), url.Param("realm", realmName), url.Param("alias", idpAlias), url.Param("id", mapperID))
 }
+
+// GetIdpMapperTypes gets the mapper types supported by the specified identity provider. Result is indexed by mapper type ID.
+func (c *Client) GetIdpMapperTypes(accessToken string, realmName string, idpAlias string) (map[string]keycloak.IdentityProviderMapperTypeRepresentation, error) {
+	var resp = map[string]keycloak.IdentityProviderMapperTypeRepresentation{}
+	var err = c.forRealm(accessToken, realmName).
+		get(accessToken, &resp, url.Path(kcIdpMapperTypesPath), url.Param("realm", realmName), url.Param("alias", idpAlias))
+	return resp, err
+}
+
+// GetIdpProviderTypes gets the list of identity provider types (oidc, saml, social providers, ...) available on the server
+func (c *Client) GetIdpProviderTypes(accessToken string, realmName string) ([]map[string]any, error) {
+	var serverInfo keycloak.ServerInfoRepresentation
+	var err = c.forRealm(accessToken, realmName).
+		get(accessToken, &serverInfo, url.Path(kcServerInfoPath))
+	if err != nil {
+		return nil, err
+	}
+	var resp = []map[string]any{}
+	if serverInfo.IdentityProviders != nil {
+		resp = append(resp, *serverInfo.IdentityProviders...)
+	}
+	if serverInfo.SocialProviders != nil {
+		resp = append(resp, *serverInfo.SocialProviders...)
+	}
+	return resp, nil
+}
+
+// GetIdpProvider gets the description of an identity provider type. providerID is the type of identity provider (oidc, saml, ...).
+func (c *Client) GetIdpProvider(accessToken string, realmName string, providerID string) (map[string]any, error) {
+	var resp = map[string]any{}
+	var err = c.forRealm(accessToken, realmName).
+		get(accessToken, &resp, url.Path(kcIdpProviderPath), url.Param("realm", realmName), url.Param("providerId", providerID))
+	return resp, err
+}
+
+// ImportIdpConfig parses an OIDC discovery document or a SAML metadata descriptor available at the given URL.
+// providerID is the type of identity provider (oidc, saml, ...).
+// It returns a configuration which can be used as IdentityProviderRepresentation.Config.
+func (c *Client) ImportIdpConfig(accessToken string, realmName string, providerID string, fromURL string) (map[string]string, error) {
+	var m = map[string]string{"providerId": providerID, "fromUrl": fromURL}
+	var resp = map[string]string{}
+	_, err := c.forRealm(accessToken, realmName).
+		post(accessToken, &resp, url.Path(kcIdpImportConfigPath), url.Param("realm", realmName), body.JSON(m))
+	return resp, err
+}
+
+// ImportIdpConfigFromFile parses an OIDC discovery document or a SAML metadata descriptor given as file content.
+// providerID is the type of identity provider (oidc, saml, ...).
+// It returns a configuration which can be used as IdentityProviderRepresentation.Config.
+func (c *Client) ImportIdpConfigFromFile(accessToken string, realmName string, providerID string, file []byte) (map[string]string, error) {
+	var form = multipart.FormData{
+		Data:  multipart.DataFields{"providerId": {providerID}},
+		Files: []multipart.FormFile{{Name: "file", Reader: bytes.NewReader(file)}},
+	}
+	var resp = map[string]string{}
+	_, err := c.forRealm(accessToken, realmName).
+		post(accessToken, &resp, url.Path(kcIdpImportConfigPath), url.Param("realm", realmName), multipart.Data(form))
+	return resp, err
+}
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
 	"regexp"
 	"strings"
@@ -15,6 +16,7 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/pkg/errors"
 	"gopkg.in/h2non/gentleman.v2"
+	gentleman_context "gopkg.in/h2non/gentleman.v2/context"
 	"gopkg.in/h2non/gentleman.v2/plugin"
 	"gopkg.in/h2non/gentleman.v2/plugins/headers"
 	"gopkg.in/h2non/gentleman.v2/plugins/query"
@@ -27,6 +29,8 @@ type Client struct {
 	httpClient      *gentleman.Client
 	account         *AccountClient
 	issuerManager   toolbox.IssuerManager
+	introspector    toolbox.TokenIntrospector
+	dpopSigner      toolbox.DPoPSigner
 	plugins         []plugin.Plugin
 	perRealmClients map[string]*Client
 	perRealmDefKey  string
@@ -66,6 +70,10 @@ func (ir *internalResponse) GetHeader(name string) string {
 	return ir.gentlemanResponse.Header.Get(name)
 }
 
+func (ir *internalResponse) Cookies() []*http.Cookie {
+	return ir.gentlemanResponse.Cookies
+}
+
 func (ir *internalResponse) Bytes() []byte {
 	if ir.bytes == nil {
 		ir.bytes = ir.gentlemanResponse.Bytes()
@@ -139,6 +147,8 @@ func (c *Client) WithPlugin(p plugin.Plugin) *Client {
 		httpClient:      c.httpClient,
 		account:         c.account,
 		issuerManager:   c.issuerManager,
+		introspector:    c.introspector,
+		dpopSigner:      c.dpopSigner,
 		perRealmClients: map[string]*Client{},
 		plugins:         append(c.plugins, p),
 	}
@@ -170,13 +180,29 @@ func (c *Client) isIssuedByDefaultMaster(accessToken string) bool {
 	if err != nil {
 		return false
 	}
-	var baseURL, realm = splitIssuer(iss)
+	var baseURL, realm = toolbox.SplitIssuer(iss)
 	return realm == "master" && strings.HasPrefix(baseURL, c.baseURL)
 }
 
-func splitIssuer(issuer string) (string, string) {
-	var splitIssuer = strings.Split(issuer, "/realms/")
-	return splitIssuer[0], splitIssuer[len(splitIssuer)-1]
+// SetIssuerManager replaces the issuer manager used by VerifyToken, for instance by one created with
+// toolbox.NewIssuerManagerWithVerifierConfig to check the audience or the roles of the tokens
+func (c *Client) SetIssuerManager(issuerManager toolbox.IssuerManager) {
+	c.issuerManager = issuerManager
+}
+
+// SetTokenIntrospector makes VerifyToken also check with introspection that tokens are still active. Tokens are
+// introspected only once they have been verified locally.
+func (c *Client) SetTokenIntrospector(introspector toolbox.TokenIntrospector) {
+	c.introspector = introspector
+}
+
+// SetDPoPSigner makes the client send DPoP-bound access tokens with a new DPoP proof per request. The signer must be
+// the one of the token provider, see toolbox.DPoPSignerOf.
+func (c *Client) SetDPoPSigner(signer toolbox.DPoPSigner) {
+	c.dpopSigner = signer
+	for _, realmClient := range c.perRealmClients {
+		realmClient.dpopSigner = signer
+	}
 }
 
 // VerifyToken verifies a token. It returns an error it is malformed, expired,...
@@ -190,7 +216,18 @@ func (c *Client) VerifyToken(issuer string, realmName string, accessToken string
 	if err != nil {
 		return err
 	}
-	return verifier.Verify(accessToken)
+	if err = verifier.Verify(accessToken); err != nil || c.introspector == nil {
+		return err
+	}
+
+	result, err := c.introspector.Introspect(context.Background(), issuer, realmName, accessToken)
+	if err != nil {
+		return err
+	}
+	if !result.Active {
+		return keycloak.ClientDetailedError{HTTPStatus: http.StatusUnauthorized, Message: keycloak.MsgErrInactiveToken}
+	}
+	return nil
 }
 
 // AccountClient gets the associated AccountClient
@@ -211,12 +248,19 @@ func (c *Client) checkError(resp *internalResponse) error {
 	}
 }
 
+// rawContent receives the body of a response whatever its content type
+type rawContent []byte
+
 func (c *Client) readContent(resp *internalResponse, data any) (retError error) {
 	defer func() {
 		if err := recover(); err != nil {
 			retError = fmt.Errorf("Unexpected panic. Ensure data is declared with the expected type: %v", err)
 		}
 	}()
+	if raw, ok := data.(*rawContent); ok {
+		*raw = resp.Bytes()
+		return nil
+	}
 	var hdr = resp.GetHeader("Content-Type")
 	switch strings.Split(hdr, ";")[0] {
 	case "application/json":
@@ -227,7 +271,7 @@ func (c *Client) readContent(resp *internalResponse, data any) (retError error)
 	case "text/html":
 		*(data.(*string)) = resp.String()
 		retError = nil
-	case "application/octet-stream", "application/zip", "application/pdf", "text/xml":
+	case "application/octet-stream", "application/zip", "application/pdf", "text/xml", "application/xml", "application/samlmetadata+xml":
 		*(data.(*[]byte)) = resp.Bytes()
 		retError = nil
 	default:
@@ -245,7 +289,7 @@ func (c *Client) get(accessToken string, data any, plugins ...plugin.Plugin) err
 	var req = c.httpClient.Get()
 	req = c.applyPlugins(req, c.plugins...)
 	req = c.applyPlugins(req, plugins...)
-	req = req.SetHeader("Authorization", fmt.Sprintf("Bearer %s", accessToken))
+	req = c.setAuthorization(req, accessToken)
 
 	var gresp *gentleman.Response
 	{
@@ -268,7 +312,7 @@ func (c *Client) post(accessToken string, data any, plugins ...plugin.Plugin) (s
 	var req = c.httpClient.Post()
 	req = c.applyPlugins(req, c.plugins...)
 	req = c.applyPlugins(req, plugins...)
-	req = req.SetHeader("Authorization", fmt.Sprintf("Bearer %s", accessToken))
+	req = c.setAuthorization(req, accessToken)
 
 	var gresp *gentleman.Response
 	{
@@ -287,11 +331,35 @@ func (c *Client) post(accessToken string, data any, plugins ...plugin.Plugin) (s
 	}
 }
 
+// postWithCookies is a HTTP post method which also returns the cookies set by the server.
+func (c *Client) postWithCookies(accessToken string, data any, plugins ...plugin.Plugin) ([]*http.Cookie, error) {
+	var req = c.httpClient.Post()
+	req = c.applyPlugins(req, c.plugins...)
+	req = c.applyPlugins(req, plugins...)
+	req = c.setAuthorization(req, accessToken)
+
+	var gresp *gentleman.Response
+	{
+		var err error
+		gresp, err = req.Do()
+		if err != nil {
+			return nil, errors.Wrap(err, keycloak.MsgErrCannotObtain+"."+keycloak.Response)
+		}
+		var resp = buildInternalResponse(gresp)
+
+		err = c.checkError(resp)
+		if err != nil {
+			return nil, err
+		}
+		return resp.Cookies(), c.readContent(resp, data)
+	}
+}
+
 func (c *Client) delete(accessToken string, plugins ...plugin.Plugin) error {
 	var req = c.httpClient.Delete()
 	req = c.applyPlugins(req, c.plugins...)
 	req = c.applyPlugins(req, plugins...)
-	req = req.SetHeader("Authorization", fmt.Sprintf("Bearer %s", accessToken))
+	req = c.setAuthorization(req, accessToken)
 
 	var resp *gentleman.Response
 	{
@@ -309,7 +377,7 @@ func (c *Client) put(accessToken string, plugins ...plugin.Plugin) error {
 	var req = c.httpClient.Put()
 	req = c.applyPlugins(req, c.plugins...)
 	req = c.applyPlugins(req, plugins...)
-	req = req.SetHeader("Authorization", fmt.Sprintf("Bearer %s", accessToken))
+	req = c.setAuthorization(req, accessToken)
 
 	var resp *gentleman.Response
 	{
@@ -323,6 +391,23 @@ func (c *Client) put(accessToken string, plugins ...plugin.Plugin) error {
 	}
 }
 
+// setAuthorization adds the access token to the request and, for DPoP-bound tokens, a proof created once the URL and
+// the headers of the request are complete
+func (c *Client) setAuthorization(req *gentleman.Request, accessToken string) *gentleman.Request {
+	if c.dpopSigner == nil {
+		return req.SetHeader("Authorization", fmt.Sprintf("Bearer %s", accessToken))
+	}
+	var signer = c.dpopSigner
+	req = req.SetHeader("Authorization", fmt.Sprintf("%s %s", toolbox.TokenTypeDPoP, accessToken))
+	return req.Use(plugin.NewPhasePlugin("before dial", func(ctx *gentleman_context.Context, h gentleman_context.Handler) {
+		if err := toolbox.AddDPoPProof(ctx.Request, signer, accessToken, ""); err != nil {
+			h.Error(ctx, err)
+			return
+		}
+		h.Next(ctx)
+	}))
+}
+
 // applyPlugins apply all the plugins to the request req.
 func (c *Client) applyPlugins(req *gentleman.Request, plugins ...plugin.Plugin) *gentleman.Request {
 	var r = req
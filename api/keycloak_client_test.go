@@ -1,9 +1,15 @@
 package api
 
 import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
+	"github.com/cloudtrust/keycloak-client/v2"
 	"github.com/cloudtrust/keycloak-client/v2/toolbox"
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -55,3 +61,127 @@ func TestForRealm(t *testing.T) {
 		assert.Equal(t, c.perRealmClients["default"], c.forRealm(jwtOther, "master"))
 	})
 }
+
+type fakeIssuerManager struct {
+	verifyErr error
+}
+
+func (f fakeIssuerManager) GetOidcVerifierProvider(string) (toolbox.OidcVerifierProvider, error) {
+	return f, nil
+}
+
+func (f fakeIssuerManager) GetOidcVerifier(string) (toolbox.OidcVerifier, error) {
+	return f, nil
+}
+
+func (f fakeIssuerManager) Verify(string) error {
+	return f.verifyErr
+}
+
+func (f fakeIssuerManager) VerifyAndExtract(string) (*toolbox.TokenClaims, error) {
+	return &toolbox.TokenClaims{}, f.verifyErr
+}
+
+func (f fakeIssuerManager) VerifyDPoP(string, string, string, string) (*toolbox.TokenClaims, error) {
+	return &toolbox.TokenClaims{}, f.verifyErr
+}
+
+type fakeIntrospector struct {
+	result toolbox.IntrospectionResult
+	err    error
+	calls  int
+}
+
+func (f *fakeIntrospector) Introspect(context.Context, string, string, string) (toolbox.IntrospectionResult, error) {
+	f.calls++
+	return f.result, f.err
+}
+
+func TestVerifyTokenWithIntrospection(t *testing.T) {
+	var introspector = &fakeIntrospector{}
+	var c = &Client{}
+	c.SetIssuerManager(fakeIssuerManager{})
+	var issuer = "https://my.domain.test/auth/realms/my-realm"
+
+	t.Run("Without introspection", func(t *testing.T) {
+		assert.Nil(t, c.VerifyToken(issuer, "my-realm", "token"))
+	})
+
+	c.SetTokenIntrospector(introspector)
+	t.Run("Local verification fails", func(t *testing.T) {
+		var c = &Client{issuerManager: fakeIssuerManager{verifyErr: errors.New("expired")}, introspector: introspector}
+		assert.NotNil(t, c.VerifyToken(issuer, "my-realm", "token"))
+		assert.Equal(t, 0, introspector.calls)
+	})
+	t.Run("Introspection fails", func(t *testing.T) {
+		introspector.err = errors.New("unavailable")
+		assert.NotNil(t, c.VerifyToken(issuer, "my-realm", "token"))
+		introspector.err = nil
+	})
+	t.Run("Inactive token", func(t *testing.T) {
+		var err = c.VerifyToken(issuer, "my-realm", "token")
+		assert.Equal(t, keycloak.ClientDetailedError{HTTPStatus: http.StatusUnauthorized, Message: keycloak.MsgErrInactiveToken}, err)
+	})
+	t.Run("Active token", func(t *testing.T) {
+		introspector.result.Active = true
+		assert.Nil(t, c.VerifyToken(issuer, "my-realm", "token"))
+	})
+}
+
+// newTestClient creates a client calling the internal URL of a test server for the realms of https://my.domain.test
+func newTestClient(t *testing.T, internalURL string) *Client {
+	var kcConfig, err = toolbox.NewConfig(func(target any) error {
+		var config = target.(*toolbox.InternalConfig)
+		config.InternalURI = internalURL
+		config.DefaultKey = ptr("default")
+		config.RealmPublicURI = map[string]string{"default": "https://my.domain.test"}
+		return nil
+	})
+	assert.Nil(t, err)
+	var c *Client
+	c, err = New(kcConfig)
+	assert.Nil(t, err)
+	return c
+}
+
+func TestSetDPoPSigner(t *testing.T) {
+	var requests []*http.Request
+	var ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"realm": "my-realm"}`))
+	}))
+	defer ts.Close()
+
+	var c = newTestClient(t, ts.URL)
+
+	t.Run("Bearer token", func(t *testing.T) {
+		requests = nil
+		var _, err = c.GetRealm("access-token", "my-realm")
+		assert.Nil(t, err)
+		assert.Len(t, requests, 1)
+		assert.Equal(t, "Bearer access-token", requests[0].Header.Get("Authorization"))
+		assert.Empty(t, requests[0].Header.Get(toolbox.DPoPHeader))
+	})
+	t.Run("DPoP-bound token", func(t *testing.T) {
+		requests = nil
+		var signer, _ = toolbox.GenerateDPoPSigner()
+		c.SetDPoPSigner(signer)
+		for range 2 {
+			var _, err = c.GetRealm("access-token", "my-realm")
+			assert.Nil(t, err)
+		}
+		assert.Len(t, requests, 2)
+		assert.Equal(t, "DPoP access-token", requests[0].Header.Get("Authorization"))
+		assert.NotEmpty(t, requests[0].Header.Get(toolbox.DPoPHeader))
+		// A new proof is created for each request
+		assert.NotEqual(t, requests[0].Header.Get(toolbox.DPoPHeader), requests[1].Header.Get(toolbox.DPoPHeader))
+
+		var claims = jwt.MapClaims{}
+		var _, _, err = jwt.NewParser().ParseUnverified(requests[0].Header.Get(toolbox.DPoPHeader), claims)
+		assert.Nil(t, err)
+		assert.Equal(t, http.MethodGet, claims["htm"])
+		assert.Equal(t, "https://my.domain.test/auth/admin/realms/my-realm", claims["htu"])
+		assert.NotEmpty(t, claims["ath"])
+	})
+}
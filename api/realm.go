@@ -1,6 +1,8 @@
 package api
 
 import (
+	"errors"
+
 	"github.com/cloudtrust/keycloak-client/v2"
 	"gopkg.in/h2non/gentleman.v2/plugins/body"
 	"gopkg.in/h2non/gentleman.v2/plugins/url"
@@ -11,6 +13,8 @@ const (
 	kcRealmRootPath               = "/auth/admin/realms"
 	kcRealmPath                   = kcRealmRootPath + "/:realm"
 	kcRealmCredentialRegistrators = kcRealmPath + "/credential-registrators"
+	kcRealmEventsPath             = kcRealmPath + "/events"
+	kcRealmPartialExportPath      = kcRealmPath + "/partial-export"
 )
 
 // GetRealms get the top level represention of all the realms. Nested information like users are
@@ -57,3 +61,29 @@ func (c *Client) GetRealmCredentialRegistrators(accessToken string, realmName st
 		get(accessToken, &resp, url.Path(kcRealmCredentialRegistrators), url.Param("realm", realmName), hdrAcceptJSON)
 	return resp, err
 }
+
+// GetEvents returns the user events of the realm.
+// Parameters: client, dateFrom, dateTo, first, ipAddress, max, type (can be repeated), user
+func (c *Client) GetEvents(accessToken string, realmName string, paramKV ...string) ([]keycloak.EventRepresentation, error) {
+	var resp = []keycloak.EventRepresentation{}
+	if len(paramKV)%2 != 0 {
+		return resp, errors.New(keycloak.MsgErrInvalidParam + "." + keycloak.EvenParams)
+	}
+	var plugins = append(c.createQueryPlugins(paramKV...), url.Path(kcRealmEventsPath), url.Param("realm", realmName))
+	var err = c.forRealm(accessToken, realmName).
+		get(accessToken, &resp, plugins...)
+	return resp, err
+}
+
+// PartialExport exports the realm. Secrets are masked by Keycloak.
+// Parameters: exportClients, exportGroupsAndRoles
+func (c *Client) PartialExport(accessToken string, realmName string, paramKV ...string) (keycloak.RealmRepresentation, error) {
+	var resp = keycloak.RealmRepresentation{}
+	if len(paramKV)%2 != 0 {
+		return resp, errors.New(keycloak.MsgErrInvalidParam + "." + keycloak.EvenParams)
+	}
+	var plugins = append(c.createQueryPlugins(paramKV...), url.Path(kcRealmPartialExportPath), url.Param("realm", realmName))
+	var _, err = c.forRealm(accessToken, realmName).
+		post(accessToken, &resp, plugins...)
+	return resp, err
+}
@@ -0,0 +1,37 @@
+package api
+
+import (
+	"context"
+
+	"github.com/cloudtrust/keycloak-client/v2"
+	"github.com/cloudtrust/keycloak-client/v2/toolbox"
+)
+
+// DiffRealms compares the realm sourceRealm of the Keycloak instance of sourceConfig with the realm targetRealm of the
+// instance of targetConfig. Both configurations can be the same to compare two realms of an instance. Each instance is
+// called with a token obtained with its OAuth2Config. See toolbox.DiffRealmSnapshots for the meaning of the returned
+// changes.
+func DiffRealms(ctx context.Context, sourceConfig keycloak.Config, sourceOAuth2Config toolbox.OAuth2Config, sourceRealm string,
+	targetConfig keycloak.Config, targetOAuth2Config toolbox.OAuth2Config, targetRealm string, logger toolbox.Logger) ([]toolbox.RealmChange, error) {
+	var sourceSnapshot, err = takeRealmSnapshot(ctx, sourceConfig, sourceOAuth2Config, sourceRealm, logger)
+	if err != nil {
+		return nil, err
+	}
+	var targetSnapshot toolbox.RealmSnapshot
+	if targetSnapshot, err = takeRealmSnapshot(ctx, targetConfig, targetOAuth2Config, targetRealm, logger); err != nil {
+		return nil, err
+	}
+	return toolbox.DiffRealmSnapshots(sourceSnapshot, targetSnapshot), nil
+}
+
+func takeRealmSnapshot(ctx context.Context, config keycloak.Config, oauth2Config toolbox.OAuth2Config, realmName string, logger toolbox.Logger) (toolbox.RealmSnapshot, error) {
+	var client, err = New(config)
+	if err != nil {
+		return nil, err
+	}
+	var accessToken string
+	if accessToken, err = toolbox.NewOAuth2TokenProvider(config, oauth2Config, logger).ProvideToken(ctx); err != nil {
+		return nil, err
+	}
+	return toolbox.TakeRealmSnapshot(client, accessToken, realmName)
+}
@@ -0,0 +1,55 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/cloudtrust/keycloak-client/v2/toolbox"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffRealms(t *testing.T) {
+	var ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/auth/realms/master/protocol/openid-connect/token":
+			w.Write([]byte(`{"access_token": "access-token", "token_type": "bearer", "expires_in": 300}`))
+		case "/auth/admin/realms/staging":
+			w.Write([]byte(`{"id": "staging-id", "realm": "staging", "displayName": "New"}`))
+		case "/auth/admin/realms/production":
+			w.Write([]byte(`{"id": "production-id", "realm": "production", "displayName": "Old"}`))
+		default:
+			w.Write([]byte(`[]`))
+		}
+	}))
+	defer ts.Close()
+
+	var kcConfig, err = toolbox.NewConfig(func(target any) error {
+		var config = target.(*toolbox.InternalConfig)
+		config.InternalURI = ts.URL
+		config.DefaultKey = ptr("default")
+		config.RealmPublicURI = map[string]string{"default": "https://my.domain.test"}
+		return nil
+	})
+	assert.Nil(t, err)
+	var oauth2Config = toolbox.OAuth2Config{Realm: ptr("master"), ClientID: ptr("realmdiff"), ClientSecret: ptr("secret")}
+
+	t.Run("Realms of the same instance", func(t *testing.T) {
+		var changes, err = DiffRealms(context.TODO(), kcConfig, oauth2Config, "staging", kcConfig, oauth2Config, "production", nil)
+		assert.Nil(t, err)
+		assert.Equal(t, []toolbox.RealmChange{{
+			Action: toolbox.RealmChangeUpdate,
+			Kind:   toolbox.RealmKindRealm,
+			Name:   "settings",
+			Fields: []toolbox.FieldDiff{{Field: "displayName", Current: "Old", Desired: "New"}},
+		}}, changes)
+	})
+	t.Run("Target instance unreachable", func(t *testing.T) {
+		var targetConfig = kcConfig
+		targetConfig.AddrInternalAPI = "http://localhost:1"
+		var _, err = DiffRealms(context.TODO(), kcConfig, oauth2Config, "staging", targetConfig, oauth2Config, "production", nil)
+		assert.NotNil(t, err)
+	})
+}
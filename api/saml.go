@@ -0,0 +1,49 @@
+package api
+
+import (
+	"github.com/cloudtrust/keycloak-client/v2"
+	"gopkg.in/h2non/gentleman.v2/plugins/url"
+)
+
+const (
+	// API Keycloak out-of-the-box
+	kcRealmSAMLDescriptorPath  = "/auth/realms/:realm/protocol/saml/descriptor"
+	kcClientInstallationPath   = kcClientIDPath + "/installation/providers/:providerId"
+	kcSAMLSPDescriptorProvider = "saml-sp-descriptor"
+)
+
+// GetRealmSAMLDescriptor downloads the SAML IdP metadata descriptor of the realm
+func (c *Client) GetRealmSAMLDescriptor(accessToken string, realmName string) ([]byte, error) {
+	var resp = []byte{}
+	var err = c.forRealm(accessToken, realmName).
+		get(accessToken, &resp, url.Path(kcRealmSAMLDescriptorPath), url.Param("realm", realmName))
+	return resp, err
+}
+
+// GetRealmSAMLMetadata downloads and parses the SAML IdP metadata descriptor of the realm
+func (c *Client) GetRealmSAMLMetadata(accessToken string, realmName string) (keycloak.SAMLMetadata, error) {
+	var descriptor, err = c.GetRealmSAMLDescriptor(accessToken, realmName)
+	if err != nil {
+		return keycloak.SAMLMetadata{}, err
+	}
+	return keycloak.ParseSAMLMetadata(descriptor)
+}
+
+// GetClientInstallation downloads an installation file of the client. idClient is the id of client (not client-id).
+// providerID is the installation format (saml-sp-descriptor, keycloak-saml, mod-auth-mellon, keycloak-oidc-keycloak-json, ...).
+// The file is returned as is, whatever its content type.
+func (c *Client) GetClientInstallation(accessToken string, realmName, idClient, providerID string) ([]byte, error) {
+	var resp rawContent
+	var err = c.forRealm(accessToken, realmName).
+		get(accessToken, &resp, url.Path(kcClientInstallationPath), url.Param("realm", realmName), url.Param("id", idClient), url.Param("providerId", providerID))
+	return resp, err
+}
+
+// GetClientSAMLMetadata downloads and parses the SAML SP metadata descriptor of the client. idClient is the id of client (not client-id).
+func (c *Client) GetClientSAMLMetadata(accessToken string, realmName, idClient string) (keycloak.SAMLMetadata, error) {
+	var descriptor, err = c.GetClientInstallation(accessToken, realmName, idClient, kcSAMLSPDescriptorProvider)
+	if err != nil {
+		return keycloak.SAMLMetadata{}, err
+	}
+	return keycloak.ParseSAMLMetadata(descriptor)
+}
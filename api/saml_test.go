@@ -0,0 +1,64 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const samlTestDescriptor = `<md:EntityDescriptor xmlns:md="urn:oasis:names:tc:SAML:2.0:metadata" entityID="https://my.domain.test/auth/realms/my-realm">
+	<md:IDPSSODescriptor protocolSupportEnumeration="urn:oasis:names:tc:SAML:2.0:protocol">
+		<md:SingleSignOnService Binding="urn:oasis:names:tc:SAML:2.0:bindings:HTTP-POST" Location="https://my.domain.test/auth/realms/my-realm/protocol/saml"/>
+	</md:IDPSSODescriptor>
+</md:EntityDescriptor>`
+
+func TestGetSAMLDescriptors(t *testing.T) {
+	var contentType string
+	var paths []string
+	var ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		w.Header().Set("Content-Type", contentType)
+		w.Write([]byte(samlTestDescriptor))
+	}))
+	defer ts.Close()
+	var c = newTestClient(t, ts.URL)
+
+	for _, value := range []string{"text/xml;charset=utf-8", "application/xml", "application/samlmetadata+xml"} {
+		t.Run(value, func(t *testing.T) {
+			contentType = value
+			var descriptor, err = c.GetRealmSAMLDescriptor("access-token", "my-realm")
+			assert.Nil(t, err)
+			assert.Equal(t, samlTestDescriptor, string(descriptor))
+		})
+	}
+	t.Run("Realm metadata", func(t *testing.T) {
+		contentType = "text/xml"
+		paths = nil
+		var metadata, err = c.GetRealmSAMLMetadata("access-token", "my-realm")
+		assert.Nil(t, err)
+		assert.Equal(t, "https://my.domain.test/auth/realms/my-realm", metadata.EntityID)
+		assert.Equal(t, []string{"/auth/realms/my-realm/protocol/saml/descriptor"}, paths)
+	})
+	t.Run("Client installation", func(t *testing.T) {
+		contentType = "application/xml"
+		paths = nil
+		var _, err = c.GetClientSAMLMetadata("access-token", "my-realm", "client-id")
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"/auth/admin/realms/my-realm/clients/client-id/installation/providers/saml-sp-descriptor"}, paths)
+	})
+	t.Run("Installation in another format", func(t *testing.T) {
+		contentType = "application/json"
+		paths = nil
+		var installation, err = c.GetClientInstallation("access-token", "my-realm", "client-id", "keycloak-oidc-keycloak-json")
+		assert.Nil(t, err)
+		assert.Equal(t, samlTestDescriptor, string(installation))
+		assert.Equal(t, []string{"/auth/admin/realms/my-realm/clients/client-id/installation/providers/keycloak-oidc-keycloak-json"}, paths)
+	})
+	t.Run("Unexpected content type", func(t *testing.T) {
+		contentType = "image/png"
+		var _, err = c.GetRealmSAMLDescriptor("access-token", "my-realm")
+		assert.NotNil(t, err)
+	})
+}
@@ -2,6 +2,7 @@ package api
 
 import (
 	"errors"
+	"net/http"
 
 	"github.com/cloudtrust/keycloak-client/v2"
 	"gopkg.in/h2non/gentleman.v2/plugins/body"
@@ -19,6 +20,10 @@ const (
 	kcUserFederationPath = kcUserIDPath + "/federated-identity"
 	kcShadowUser         = kcUserFederationPath + "/:provider"
 	kcProfilePath        = kcUserPath + "/profile"
+	kcUserConsentsPath   = kcUserIDPath + "/consents"
+	kcUserSessionsPath   = kcUserIDPath + "/sessions"
+	kcUserConsentPath    = kcUserConsentsPath + "/:client"
+	kcImpersonationPath  = kcUserIDPath + "/impersonation"
 
 	// API keycloak-rest-api-extensions admin
 	ctAdminRootPath                       = "/auth/realms/:realmReq/api/admin"
@@ -105,6 +110,14 @@ func (c *Client) DeleteGroupFromUser(accessToken string, realmName, userID, grou
 		delete(accessToken, url.Path(kcUserGroupIDPath), url.Param("realm", realmName), url.Param("id", userID), url.Param("groupId", groupID))
 }
 
+// GetUserSessions gets the active sessions of the user.
+func (c *Client) GetUserSessions(accessToken string, realmName, userID string) ([]keycloak.UserSessionRepresentation, error) {
+	var resp = []keycloak.UserSessionRepresentation{}
+	var err = c.forRealm(accessToken, realmName).
+		get(accessToken, &resp, url.Path(kcUserSessionsPath), url.Param("realm", realmName), url.Param("id", userID))
+	return resp, err
+}
+
 // UpdateUser updates the user.
 func (c *Client) UpdateUser(accessToken string, realmName, userID string, user keycloak.UserRepresentation) error {
 	return c.forRealm(accessToken, realmName).
@@ -223,3 +236,27 @@ func (c *Client) GetUserProfile(accessToken string, realmName string) (keycloak.
 		get(accessToken, &profile, url.Path(kcProfilePath), url.Param("realm", realmName))
 	return profile, err
 }
+
+// GetUserConsents gets the consents granted by the user
+func (c *Client) GetUserConsents(accessToken string, realmName string, userID string) ([]keycloak.UserConsentRepresentation, error) {
+	var resp = []keycloak.UserConsentRepresentation{}
+	var err = c.forRealm(accessToken, realmName).
+		get(accessToken, &resp, url.Path(kcUserConsentsPath), url.Param("realm", realmName), url.Param("id", userID))
+	return resp, err
+}
+
+// RevokeUserConsent revokes the consent and the offline tokens granted by the user to the client. clientID is the client-id (not the id of the client).
+func (c *Client) RevokeUserConsent(accessToken string, realmName string, userID string, clientID string) error {
+	return c.forRealm(accessToken, realmName).
+		delete(accessToken, url.Path(kcUserConsentPath), url.Param("realm", realmName), url.Param("id", userID), url.Param("client", clientID))
+}
+
+// ImpersonateUser impersonates the user. It returns the URL where the browser should be redirected to and the
+// session cookies which have to be set in the browser before following the redirection.
+// The cookies are issued for the public host of the realm.
+func (c *Client) ImpersonateUser(accessToken string, realmName string, userID string) (keycloak.ImpersonationRepresentation, []*http.Cookie, error) {
+	var resp = keycloak.ImpersonationRepresentation{}
+	var cookies, err = c.forRealm(accessToken, realmName).
+		postWithCookies(accessToken, &resp, url.Path(kcImpersonationPath), url.Param("realm", realmName), url.Param("id", userID))
+	return resp, cookies, err
+}
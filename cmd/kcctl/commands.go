@@ -0,0 +1,296 @@
+package main
+
+import (
+	"errors"
+	"path"
+	"strconv"
+
+	"github.com/cloudtrust/keycloak-client/v2"
+)
+
+// adminClient is the part of api.Client used by kcctl
+type adminClient interface {
+	GetUser(accessToken string, realmName, userID string) (keycloak.UserRepresentation, error)
+	GetUsers(accessToken string, reqRealmName, targetRealmName string, paramKV ...string) (keycloak.UsersPageRepresentation, error)
+	CreateUser(accessToken string, reqRealmName, targetRealmName string, user keycloak.UserRepresentation, paramKV ...string) (string, error)
+	UpdateUser(accessToken string, realmName, userID string, user keycloak.UserRepresentation) error
+	ResetPassword(accessToken string, realmName, userID string, cred keycloak.CredentialRepresentation) error
+	ExecuteActionsEmail(accessToken string, reqRealmName string, targetRealmName string, userID string, actions []string, paramKV ...string) error
+	GetGroups(accessToken string, realmName string) ([]keycloak.GroupRepresentation, error)
+	GetGroupMembers(accessToken string, realmName string, groupID string, paramKV ...string) ([]keycloak.UserRepresentation, error)
+	GetRoles(accessToken string, realmName string) ([]keycloak.RoleRepresentation, error)
+	GetRole(accessToken string, realmName string, roleID string) (keycloak.RoleRepresentation, error)
+	GetUserSessions(accessToken string, realmName, userID string) ([]keycloak.UserSessionRepresentation, error)
+	LogoutAllSessions(accessToken string, realmName, userID string) error
+	GetCredentials(accessToken string, realmName string, userID string) ([]keycloak.CredentialRepresentation, error)
+	GetEvents(accessToken string, realmName string, paramKV ...string) ([]keycloak.EventRepresentation, error)
+	PartialExport(accessToken string, realmName string, paramKV ...string) (keycloak.RealmRepresentation, error)
+}
+
+type invocation struct {
+	accessToken string
+	opts        options
+	args        []string
+}
+
+// arg returns the positional argument at index, which is mandatory
+func (inv invocation) arg(index int, name string) (string, error) {
+	if index >= len(inv.args) || inv.args[index] == "" {
+		return "", errors.New("missing argument " + name)
+	}
+	return inv.args[index], nil
+}
+
+func (inv invocation) paging() []string {
+	return []string{"first", strconv.Itoa(inv.opts.first), "max", strconv.Itoa(inv.opts.max)}
+}
+
+type command func(client adminClient, inv invocation) (commandResult, error)
+
+var commands = map[string]command{
+	"users get":             getUser,
+	"users search":          searchUsers,
+	"users create":          createUser,
+	"users disable":         disableUser,
+	"users reset-password":  resetPassword,
+	"users execute-actions": executeActions,
+	"groups list":           listGroups,
+	"groups members":        listGroupMembers,
+	"roles list":            listRoles,
+	"roles get":             getRole,
+	"sessions list":         listSessions,
+	"sessions logout":       logoutSessions,
+	"credentials list":      listCredentials,
+	"events list":           listEvents,
+	"realm export":          exportRealm,
+}
+
+var (
+	userColumns       = []string{"id", "username", "email", "firstName", "lastName", "enabled"}
+	groupColumns      = []string{"id", "path"}
+	roleColumns       = []string{"id", "name", "description", "composite"}
+	sessionColumns    = []string{"id", "username", "ipAddress", "start", "lastAccess"}
+	credentialColumns = []string{"id", "type", "userLabel", "createdDate"}
+	eventColumns      = []string{"time", "type", "userId", "clientId", "ipAddress", "error"}
+	statusColumns     = []string{"id", "status"}
+)
+
+func getUser(client adminClient, inv invocation) (commandResult, error) {
+	var userID, err = inv.arg(0, "userID")
+	if err != nil {
+		return commandResult{}, err
+	}
+	var user keycloak.UserRepresentation
+	if user, err = client.GetUser(inv.accessToken, inv.opts.realm, userID); err != nil {
+		return commandResult{}, err
+	}
+	return commandResult{value: user, columns: userColumns}, nil
+}
+
+func searchUsers(client adminClient, inv invocation) (commandResult, error) {
+	var params = inv.paging()
+	if len(inv.args) > 0 {
+		params = append(params, "search", inv.args[0])
+	}
+	var page, err = client.GetUsers(inv.accessToken, inv.opts.authRealm, inv.opts.realm, params...)
+	if err != nil {
+		return commandResult{}, err
+	}
+	return commandResult{value: page.Users, columns: userColumns}, nil
+}
+
+func createUser(client adminClient, inv invocation) (commandResult, error) {
+	var username, err = inv.arg(0, "username")
+	if err != nil {
+		return commandResult{}, err
+	}
+	var enabled = !inv.opts.disabled
+	var user = keycloak.UserRepresentation{Username: &username, Enabled: &enabled}
+	for _, field := range []struct {
+		value  string
+		target **string
+	}{{inv.opts.email, &user.Email}, {inv.opts.firstName, &user.FirstName}, {inv.opts.lastName, &user.LastName}} {
+		if field.value != "" {
+			var value = field.value
+			*field.target = &value
+		}
+	}
+	var location string
+	if location, err = client.CreateUser(inv.accessToken, inv.opts.authRealm, inv.opts.realm, user); err != nil {
+		return commandResult{}, err
+	}
+	return statusResult(path.Base(location), "created"), nil
+}
+
+func disableUser(client adminClient, inv invocation) (commandResult, error) {
+	var userID, err = inv.arg(0, "userID")
+	if err != nil {
+		return commandResult{}, err
+	}
+	// The whole representation is sent back: the fields missing from an update are reset by Keycloak
+	var user keycloak.UserRepresentation
+	if user, err = client.GetUser(inv.accessToken, inv.opts.realm, userID); err != nil {
+		return commandResult{}, err
+	}
+	var enabled = false
+	user.Enabled = &enabled
+	if err = client.UpdateUser(inv.accessToken, inv.opts.realm, userID, user); err != nil {
+		return commandResult{}, err
+	}
+	return statusResult(userID, "disabled"), nil
+}
+
+func resetPassword(client adminClient, inv invocation) (commandResult, error) {
+	var userID, err = inv.arg(0, "userID")
+	if err != nil {
+		return commandResult{}, err
+	}
+	if inv.opts.newPassword == "" {
+		return commandResult{}, errors.New("missing new password")
+	}
+	var credType = "password"
+	var credential = keycloak.CredentialRepresentation{Type: &credType, Value: &inv.opts.newPassword, Temporary: &inv.opts.temporary}
+	if err = client.ResetPassword(inv.accessToken, inv.opts.realm, userID, credential); err != nil {
+		return commandResult{}, err
+	}
+	return statusResult(userID, "password reset"), nil
+}
+
+func executeActions(client adminClient, inv invocation) (commandResult, error) {
+	var userID, err = inv.arg(0, "userID")
+	if err != nil {
+		return commandResult{}, err
+	}
+	if _, err = inv.arg(1, "action"); err != nil {
+		return commandResult{}, err
+	}
+	var params []string
+	if inv.opts.lifespan > 0 {
+		params = append(params, "lifespan", strconv.Itoa(int(inv.opts.lifespan.Seconds())))
+	}
+	if err = client.ExecuteActionsEmail(inv.accessToken, inv.opts.authRealm, inv.opts.realm, userID, inv.args[1:], params...); err != nil {
+		return commandResult{}, err
+	}
+	return statusResult(userID, "email sent"), nil
+}
+
+func listGroups(client adminClient, inv invocation) (commandResult, error) {
+	var groups, err = client.GetGroups(inv.accessToken, inv.opts.realm)
+	if err != nil {
+		return commandResult{}, err
+	}
+	// Sub-groups are listed after their parent
+	var res []keycloak.GroupRepresentation
+	var flatten func(groups []keycloak.GroupRepresentation)
+	flatten = func(groups []keycloak.GroupRepresentation) {
+		for _, group := range groups {
+			var subGroups = group.SubGroups
+			group.SubGroups = nil
+			res = append(res, group)
+			if subGroups != nil {
+				flatten(*subGroups)
+			}
+		}
+	}
+	flatten(groups)
+	return commandResult{value: res, columns: groupColumns}, nil
+}
+
+func listGroupMembers(client adminClient, inv invocation) (commandResult, error) {
+	var groupID, err = inv.arg(0, "groupID")
+	if err != nil {
+		return commandResult{}, err
+	}
+	var members []keycloak.UserRepresentation
+	if members, err = client.GetGroupMembers(inv.accessToken, inv.opts.realm, groupID, inv.paging()...); err != nil {
+		return commandResult{}, err
+	}
+	return commandResult{value: members, columns: userColumns}, nil
+}
+
+func listRoles(client adminClient, inv invocation) (commandResult, error) {
+	var roles, err = client.GetRoles(inv.accessToken, inv.opts.realm)
+	if err != nil {
+		return commandResult{}, err
+	}
+	return commandResult{value: roles, columns: roleColumns}, nil
+}
+
+func getRole(client adminClient, inv invocation) (commandResult, error) {
+	var roleID, err = inv.arg(0, "roleID")
+	if err != nil {
+		return commandResult{}, err
+	}
+	var role keycloak.RoleRepresentation
+	if role, err = client.GetRole(inv.accessToken, inv.opts.realm, roleID); err != nil {
+		return commandResult{}, err
+	}
+	return commandResult{value: role, columns: roleColumns}, nil
+}
+
+func listSessions(client adminClient, inv invocation) (commandResult, error) {
+	var userID, err = inv.arg(0, "userID")
+	if err != nil {
+		return commandResult{}, err
+	}
+	var sessions []keycloak.UserSessionRepresentation
+	if sessions, err = client.GetUserSessions(inv.accessToken, inv.opts.realm, userID); err != nil {
+		return commandResult{}, err
+	}
+	return commandResult{value: sessions, columns: sessionColumns}, nil
+}
+
+func logoutSessions(client adminClient, inv invocation) (commandResult, error) {
+	var userID, err = inv.arg(0, "userID")
+	if err != nil {
+		return commandResult{}, err
+	}
+	if err = client.LogoutAllSessions(inv.accessToken, inv.opts.realm, userID); err != nil {
+		return commandResult{}, err
+	}
+	return statusResult(userID, "logged out"), nil
+}
+
+func listCredentials(client adminClient, inv invocation) (commandResult, error) {
+	var userID, err = inv.arg(0, "userID")
+	if err != nil {
+		return commandResult{}, err
+	}
+	var credentials []keycloak.CredentialRepresentation
+	if credentials, err = client.GetCredentials(inv.accessToken, inv.opts.realm, userID); err != nil {
+		return commandResult{}, err
+	}
+	return commandResult{value: credentials, columns: credentialColumns}, nil
+}
+
+func listEvents(client adminClient, inv invocation) (commandResult, error) {
+	var params = inv.paging()
+	for _, eventType := range inv.opts.eventTypes {
+		params = append(params, "type", eventType)
+	}
+	if inv.opts.eventUser != "" {
+		params = append(params, "user", inv.opts.eventUser)
+	}
+	if inv.opts.eventClient != "" {
+		params = append(params, "client", inv.opts.eventClient)
+	}
+	var events, err = client.GetEvents(inv.accessToken, inv.opts.realm, params...)
+	if err != nil {
+		return commandResult{}, err
+	}
+	return commandResult{value: events, columns: eventColumns}, nil
+}
+
+func exportRealm(client adminClient, inv invocation) (commandResult, error) {
+	var realm, err = client.PartialExport(inv.accessToken, inv.opts.realm,
+		"exportClients", strconv.FormatBool(inv.opts.withClients), "exportGroupsAndRoles", strconv.FormatBool(inv.opts.withGroups))
+	if err != nil {
+		return commandResult{}, err
+	}
+	// A realm export can't be displayed as a table
+	return commandResult{value: realm}, nil
+}
+
+func statusResult(id string, status string) commandResult {
+	return commandResult{value: map[string]string{"id": id, "status": status}, columns: statusColumns}
+}
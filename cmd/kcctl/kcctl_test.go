@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/cloudtrust/keycloak-client/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeClient implements the methods used by the tests, the other ones panic
+type fakeClient struct {
+	adminClient
+	params  []string
+	updated keycloak.UserRepresentation
+	actions []string
+	err     error
+}
+
+func (f *fakeClient) GetUsers(_ string, _, _ string, paramKV ...string) (keycloak.UsersPageRepresentation, error) {
+	f.params = paramKV
+	var username, enabled = "jdoe", true
+	return keycloak.UsersPageRepresentation{Users: []keycloak.UserRepresentation{{Username: &username, Enabled: &enabled}}}, f.err
+}
+
+func (f *fakeClient) GetUser(_ string, _, userID string) (keycloak.UserRepresentation, error) {
+	var username, email, enabled = "jdoe", "jdoe@example.com", true
+	return keycloak.UserRepresentation{ID: &userID, Username: &username, Email: &email, Enabled: &enabled}, f.err
+}
+
+func (f *fakeClient) CreateUser(_ string, _, _ string, user keycloak.UserRepresentation, _ ...string) (string, error) {
+	f.updated = user
+	return "http://localhost/auth/admin/realms/test/users/new-id", f.err
+}
+
+func (f *fakeClient) UpdateUser(_ string, _, _ string, user keycloak.UserRepresentation) error {
+	f.updated = user
+	return f.err
+}
+
+func (f *fakeClient) ExecuteActionsEmail(_ string, _ string, _ string, _ string, actions []string, paramKV ...string) error {
+	f.actions = actions
+	f.params = paramKV
+	return f.err
+}
+
+func TestCommands(t *testing.T) {
+	var inv = invocation{accessToken: "token", opts: options{authRealm: "master", realm: "test", max: 10}}
+
+	t.Run("Search users", func(t *testing.T) {
+		var client = &fakeClient{}
+		inv.args = []string{"doe"}
+		var res, err = searchUsers(client, inv)
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"first", "0", "max", "10", "search", "doe"}, client.params)
+		assert.Len(t, res.value, 1)
+	})
+	t.Run("Create user", func(t *testing.T) {
+		var client = &fakeClient{}
+		inv.args = []string{"jdoe"}
+		inv.opts.email = "jdoe@example.com"
+		var res, err = createUser(client, inv)
+		assert.Nil(t, err)
+		assert.Equal(t, "jdoe@example.com", *client.updated.Email)
+		assert.Nil(t, client.updated.FirstName)
+		assert.True(t, *client.updated.Enabled)
+		assert.Equal(t, map[string]string{"id": "new-id", "status": "created"}, res.value)
+	})
+	t.Run("Disable user", func(t *testing.T) {
+		var client = &fakeClient{}
+		inv.args = []string{"user-id"}
+		var res, err = disableUser(client, inv)
+		assert.Nil(t, err)
+		assert.False(t, *client.updated.Enabled)
+		assert.Equal(t, "user-id", *client.updated.ID)
+		assert.Equal(t, "jdoe", *client.updated.Username)
+		assert.Equal(t, "jdoe@example.com", *client.updated.Email)
+		assert.Equal(t, map[string]string{"id": "user-id", "status": "disabled"}, res.value)
+	})
+	t.Run("Disable user fails", func(t *testing.T) {
+		var client = &fakeClient{err: errors.New("failure")}
+		inv.args = []string{"user-id"}
+		var _, err = disableUser(client, inv)
+		assert.NotNil(t, err)
+		assert.Nil(t, client.updated.Enabled)
+	})
+	t.Run("Execute actions", func(t *testing.T) {
+		var client = &fakeClient{}
+		inv.args = []string{"user-id"}
+		var _, err = executeActions(client, inv)
+		assert.NotNil(t, err)
+
+		inv.args = []string{"user-id", "VERIFY_EMAIL", "UPDATE_PASSWORD"}
+		inv.opts.lifespan = time.Hour
+		_, err = executeActions(client, inv)
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"VERIFY_EMAIL", "UPDATE_PASSWORD"}, client.actions)
+		assert.Equal(t, []string{"lifespan", "3600"}, client.params)
+	})
+	t.Run("Missing argument", func(t *testing.T) {
+		inv.args = nil
+		var _, err = getUser(&fakeClient{}, inv)
+		assert.NotNil(t, err)
+	})
+}
+
+func TestWriteResult(t *testing.T) {
+	var username, enabled = "jdoe", true
+	var result = commandResult{value: []keycloak.UserRepresentation{{Username: &username, Enabled: &enabled}}, columns: []string{"username", "email", "enabled"}}
+
+	t.Run("Table", func(t *testing.T) {
+		var out bytes.Buffer
+		assert.Nil(t, writeResult(&out, result, outputTable))
+		assert.Equal(t, "USERNAME  EMAIL  ENABLED\njdoe             true\n", out.String())
+	})
+	t.Run("Single row", func(t *testing.T) {
+		var out bytes.Buffer
+		assert.Nil(t, writeResult(&out, statusResult("id", "created"), outputTable))
+		assert.Equal(t, "ID  STATUS\nid  created\n", out.String())
+	})
+	t.Run("JSON", func(t *testing.T) {
+		var out bytes.Buffer
+		assert.Nil(t, writeResult(&out, result, outputJSON))
+		assert.JSONEq(t, `[{"username": "jdoe", "enabled": true}]`, out.String())
+	})
+	t.Run("No columns", func(t *testing.T) {
+		var out bytes.Buffer
+		assert.Nil(t, writeResult(&out, commandResult{value: map[string]int{"count": 1}}, outputTable))
+		assert.JSONEq(t, `{"count": 1}`, out.String())
+	})
+}
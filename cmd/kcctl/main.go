@@ -0,0 +1,187 @@
+// Command kcctl administrates a Keycloak realm with the same client as the services.
+//
+// Usage:
+//
+//	kcctl [flags] <resource> <action> [arguments]
+//
+// Resources and actions:
+//
+//	users get <userID>
+//	users search [query]
+//	users create <username>
+//	users disable <userID>
+//	users reset-password <userID>
+//	users execute-actions <userID> <action>...
+//	groups list
+//	groups members <groupID>
+//	roles list
+//	roles get <roleID>
+//	sessions list <userID>
+//	sessions logout <userID>
+//	credentials list <userID>
+//	events list
+//	realm export
+//
+// Secrets can be given with the KCCTL_PASSWORD, KCCTL_CLIENT_SECRET and KCCTL_NEW_PASSWORD environment variables.
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	api "github.com/cloudtrust/keycloak-client/v2/api"
+	"github.com/cloudtrust/keycloak-client/v2/toolbox"
+	"github.com/spf13/pflag"
+)
+
+type stderrLogger struct{}
+
+func (l *stderrLogger) Warn(_ context.Context, keyvals ...any) {
+	fmt.Fprintln(os.Stderr, keyvals...)
+}
+
+// options are the flags of kcctl. Flags which do not apply to a command are ignored.
+type options struct {
+	url          string
+	internalURL  string
+	timeout      time.Duration
+	authRealm    string
+	username     string
+	password     string
+	clientID     string
+	clientSecret string
+	realm        string
+	output       string
+
+	first       int
+	max         int
+	email       string
+	firstName   string
+	lastName    string
+	disabled    bool
+	newPassword string
+	temporary   bool
+	lifespan    time.Duration
+	eventTypes  []string
+	eventUser   string
+	eventClient string
+	withClients bool
+	withGroups  bool
+}
+
+func parseOptions() (options, []string) {
+	var opts options
+	pflag.StringVar(&opts.url, "url", "", "Keycloak public URL")
+	pflag.StringVar(&opts.internalURL, "internal-url", "", "Keycloak internal URL used for the admin API (defaults to the public URL)")
+	pflag.DurationVar(&opts.timeout, "timeout", 30*time.Second, "timeout of the HTTP requests")
+	pflag.StringVar(&opts.authRealm, "auth-realm", "master", "realm used to authenticate")
+	pflag.StringVar(&opts.username, "username", "admin", "username used to authenticate")
+	pflag.StringVar(&opts.password, "password", "", "password used to authenticate (or KCCTL_PASSWORD)")
+	pflag.StringVar(&opts.clientID, "client-id", "admin-cli", "client used to authenticate")
+	pflag.StringVar(&opts.clientSecret, "client-secret", "", "secret of the client: when set, the client credentials grant is used (or KCCTL_CLIENT_SECRET)")
+	pflag.StringVar(&opts.realm, "realm", "", "administrated realm (defaults to the authentication realm)")
+	pflag.StringVarP(&opts.output, "output", "o", outputTable, "output format: table or json")
+
+	pflag.IntVar(&opts.first, "first", 0, "index of the first result")
+	pflag.IntVar(&opts.max, "max", 100, "maximum number of results")
+	pflag.StringVar(&opts.email, "email", "", "email of the created user")
+	pflag.StringVar(&opts.firstName, "first-name", "", "first name of the created user")
+	pflag.StringVar(&opts.lastName, "last-name", "", "last name of the created user")
+	pflag.BoolVar(&opts.disabled, "disabled", false, "create the user disabled")
+	pflag.StringVar(&opts.newPassword, "new-password", "", "new password of the user (or KCCTL_NEW_PASSWORD)")
+	pflag.BoolVar(&opts.temporary, "temporary", true, "the user must change the new password at next login")
+	pflag.DurationVar(&opts.lifespan, "lifespan", 0, "validity of the execute actions link (defaults to the realm setting)")
+	pflag.StringSliceVar(&opts.eventTypes, "type", nil, "types of the listed events")
+	pflag.StringVar(&opts.eventUser, "user", "", "user ID of the listed events")
+	pflag.StringVar(&opts.eventClient, "client", "", "client of the listed events")
+	pflag.BoolVar(&opts.withClients, "clients", false, "include the clients in the realm export")
+	pflag.BoolVar(&opts.withGroups, "groups-and-roles", false, "include the groups and roles in the realm export")
+	pflag.Parse()
+
+	// Secrets are read from the environment after parsing so that the usage never prints them as default values
+	for _, secret := range []struct {
+		value *string
+		env   string
+	}{{&opts.password, "KCCTL_PASSWORD"}, {&opts.clientSecret, "KCCTL_CLIENT_SECRET"}, {&opts.newPassword, "KCCTL_NEW_PASSWORD"}} {
+		if *secret.value == "" {
+			*secret.value = os.Getenv(secret.env)
+		}
+	}
+	if opts.realm == "" {
+		opts.realm = opts.authRealm
+	}
+	if opts.internalURL == "" {
+		opts.internalURL = opts.url
+	}
+	return opts, pflag.Args()
+}
+
+// newClient creates the Keycloak client and its token provider from the flags, the same way services do from their
+// configuration files
+func newClient(opts options) (*api.Client, toolbox.OidcTokenProvider, error) {
+	var config, err = toolbox.NewConfig(func(target any) error {
+		var internalConfig, ok = target.(*toolbox.InternalConfig)
+		if !ok {
+			return fmt.Errorf("unexpected configuration type %T", target)
+		}
+		*internalConfig = toolbox.InternalConfig{
+			InternalURI:    opts.internalURL,
+			RealmPublicURI: map[string]string{"default": opts.url},
+			Timeout:        opts.timeout,
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	var client *api.Client
+	if client, err = api.New(config); err != nil {
+		return nil, nil, err
+	}
+	var oauth2Config = toolbox.OAuth2Config{
+		Realm:    &opts.authRealm,
+		Username: &opts.username,
+		Password: &opts.password,
+		ClientID: &opts.clientID,
+	}
+	if opts.clientSecret != "" {
+		oauth2Config.ClientSecret = &opts.clientSecret
+	}
+	return client, toolbox.NewOAuth2TokenProvider(config, oauth2Config, &stderrLogger{}), nil
+}
+
+func main() {
+	var opts, args = parseOptions()
+	if opts.url == "" || len(args) < 2 || (opts.output != outputTable && opts.output != outputJSON) {
+		pflag.Usage()
+		os.Exit(2)
+	}
+	var command, ok = commands[args[0]+" "+args[1]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "kcctl: unknown command %s %s\n", args[0], args[1])
+		os.Exit(2)
+	}
+
+	var client, tokenProvider, err = newClient(opts)
+	if err != nil {
+		fail(err)
+	}
+	var accessToken string
+	if accessToken, err = tokenProvider.ProvideToken(context.Background()); err != nil {
+		fail(err)
+	}
+	var result commandResult
+	if result, err = command(client, invocation{accessToken: accessToken, opts: opts, args: args[2:]}); err != nil {
+		fail(err)
+	}
+	if err = writeResult(os.Stdout, result, opts.output); err != nil {
+		fail(err)
+	}
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, "kcctl:", err)
+	os.Exit(1)
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+// Output formats
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+)
+
+// commandResult is a single representation or a slice of representations. columns are the JSON fields displayed in
+// table mode: results without columns are always written as JSON.
+type commandResult struct {
+	value   any
+	columns []string
+}
+
+func writeResult(out io.Writer, result commandResult, format string) error {
+	if format == outputJSON || len(result.columns) == 0 {
+		var encoder = json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(result.value)
+	}
+
+	var rows, err = toRows(result.value)
+	if err != nil {
+		return err
+	}
+	var w = tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	var header []string
+	for _, column := range result.columns {
+		header = append(header, strings.ToUpper(column))
+	}
+	fmt.Fprintln(w, strings.Join(header, "\t"))
+	for _, row := range rows {
+		var cells []string
+		for _, column := range result.columns {
+			cells = append(cells, formatCell(row[column]))
+		}
+		fmt.Fprintln(w, strings.Join(cells, "\t"))
+	}
+	return w.Flush()
+}
+
+// toRows converts the result to its JSON fields, a single representation being a table of one row
+func toRows(value any) ([]map[string]any, error) {
+	var bytes, err = json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var rows []map[string]any
+	if len(bytes) > 0 && bytes[0] == '[' {
+		err = json.Unmarshal(bytes, &rows)
+		return rows, err
+	}
+	var row map[string]any
+	err = json.Unmarshal(bytes, &row)
+	return []map[string]any{row}, err
+}
+
+func formatCell(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return fmt.Sprintf("%.0f", v)
+	default:
+		var bytes, _ = json.Marshal(v)
+		return string(bytes)
+	}
+}
@@ -0,0 +1,140 @@
+// Command realmdiff compares the configuration of two Keycloak realms, for instance the same realm on a staging and
+// on a production instance, and prints the changes which would align the target realm on the source one.
+//
+// Usage:
+//
+//	realmdiff --source-url https://kc.staging --source-realm myrealm --target-url https://kc.prod [--format json]
+//	realmdiff --source-url https://kc --source-realm staging --target-realm production
+//
+// Passwords can also be given with the SOURCE_PASSWORD and TARGET_PASSWORD environment variables.
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/cloudtrust/keycloak-client/v2"
+	api "github.com/cloudtrust/keycloak-client/v2/api"
+	"github.com/cloudtrust/keycloak-client/v2/toolbox"
+	"github.com/spf13/pflag"
+)
+
+type stderrLogger struct{}
+
+func (l *stderrLogger) Warn(_ context.Context, keyvals ...any) {
+	fmt.Fprintln(os.Stderr, keyvals...)
+}
+
+type instanceFlags struct {
+	flags       *pflag.FlagSet
+	prefix      string
+	url         *string
+	internalURL *string
+	realm       *string
+	authRealm   *string
+	username    *string
+	password    *string
+	clientID    *string
+}
+
+// Names of the flags of an instance, without their prefix
+var instanceFlagNames = []string{"url", "internal-url", "realm", "auth-realm", "username", "password", "client-id"}
+
+func newInstanceFlags(flags *pflag.FlagSet, prefix string) instanceFlags {
+	return instanceFlags{
+		flags:       flags,
+		prefix:      prefix,
+		url:         flags.String(prefix+"-url", "", prefix+" Keycloak public URL"),
+		internalURL: flags.String(prefix+"-internal-url", "", prefix+" Keycloak internal URL used for the admin API (defaults to the public URL)"),
+		realm:       flags.String(prefix+"-realm", "", prefix+" realm"),
+		authRealm:   flags.String(prefix+"-auth-realm", "master", "realm used to authenticate on the "+prefix+" instance"),
+		username:    flags.String(prefix+"-username", "admin", "username used to authenticate on the "+prefix+" instance"),
+		password:    flags.String(prefix+"-password", "", "password used to authenticate on the "+prefix+" instance"),
+		clientID:    flags.String(prefix+"-client-id", "admin-cli", "client used to authenticate on the "+prefix+" instance"),
+	}
+}
+
+// inherit uses the values of the source instance for the flags which are not given. A flag keeping its default value
+// is not given, unless it was set on the command line: --target-auth-realm master is kept even if the source
+// authentication realm is another one. Values set from the environment, such as the passwords, are kept.
+func (f instanceFlags) inherit(source instanceFlags) {
+	for _, name := range instanceFlagNames {
+		var flag = f.flags.Lookup(f.prefix + "-" + name)
+		if !flag.Changed && flag.Value.String() == flag.DefValue {
+			_ = flag.Value.Set(source.flags.Lookup(source.prefix + "-" + name).Value.String())
+		}
+	}
+}
+
+func (f instanceFlags) config(timeout time.Duration) (keycloak.Config, toolbox.OAuth2Config, error) {
+	var uriProvider, err = toolbox.NewKeycloakURIProviderFromArray([]string{*f.url})
+	if err != nil {
+		return keycloak.Config{}, toolbox.OAuth2Config{}, err
+	}
+	var internalURL = *f.internalURL
+	if internalURL == "" {
+		internalURL = *f.url
+	}
+	var config = keycloak.Config{
+		URIProvider:     uriProvider,
+		AddrInternalAPI: internalURL,
+		Timeout:         timeout,
+	}
+	return config, toolbox.OAuth2Config{
+		Realm:    f.authRealm,
+		Username: f.username,
+		Password: f.password,
+		ClientID: f.clientID,
+	}, nil
+}
+
+func main() {
+	var source = newInstanceFlags(pflag.CommandLine, "source")
+	var target = newInstanceFlags(pflag.CommandLine, "target")
+	var format = pflag.String("format", "text", "output format: text or json")
+	var timeout = pflag.Duration("timeout", 30*time.Second, "timeout of the HTTP requests")
+	pflag.Parse()
+
+	if *source.password == "" {
+		*source.password = os.Getenv("SOURCE_PASSWORD")
+	}
+	if *target.password == "" {
+		*target.password = os.Getenv("TARGET_PASSWORD")
+	}
+	target.inherit(source)
+	if *source.url == "" || *source.realm == "" || (*format != "text" && *format != "json") {
+		pflag.Usage()
+		os.Exit(2)
+	}
+
+	var changes, err = diff(context.Background(), source, target, *timeout)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "realmdiff:", err)
+		os.Exit(1)
+	}
+	if *format == "json" {
+		var output []byte
+		if output, err = toolbox.FormatRealmChangesJSON(changes); err != nil {
+			fmt.Fprintln(os.Stderr, "realmdiff:", err)
+			os.Exit(1)
+		}
+		fmt.Println(string(output))
+		return
+	}
+	fmt.Print(toolbox.FormatRealmChanges(changes))
+}
+
+func diff(ctx context.Context, source instanceFlags, target instanceFlags, timeout time.Duration) ([]toolbox.RealmChange, error) {
+	var sourceConfig, sourceOAuth2Config, err = source.config(timeout)
+	if err != nil {
+		return nil, fmt.Errorf("source: %w", err)
+	}
+	var targetConfig keycloak.Config
+	var targetOAuth2Config toolbox.OAuth2Config
+	if targetConfig, targetOAuth2Config, err = target.config(timeout); err != nil {
+		return nil, fmt.Errorf("target: %w", err)
+	}
+	return api.DiffRealms(ctx, sourceConfig, sourceOAuth2Config, *source.realm, targetConfig, targetOAuth2Config, *target.realm, &stderrLogger{})
+}
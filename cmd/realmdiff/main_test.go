@@ -0,0 +1,51 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInheritFlags(t *testing.T) {
+	var parse = func(args ...string) (instanceFlags, instanceFlags) {
+		var flags = pflag.NewFlagSet("realmdiff", pflag.ContinueOnError)
+		var source = newInstanceFlags(flags, "source")
+		var target = newInstanceFlags(flags, "target")
+		assert.Nil(t, flags.Parse(args))
+		return source, target
+	}
+
+	t.Run("Flags which are not given are inherited", func(t *testing.T) {
+		var source, target = parse("--source-url", "https://kc.staging", "--source-realm", "myrealm", "--source-auth-realm", "admins",
+			"--source-username", "root", "--source-client-id", "cli", "--source-password", "secret", "--target-url", "https://kc.prod")
+		target.inherit(source)
+		assert.Equal(t, "https://kc.prod", *target.url)
+		assert.Equal(t, "myrealm", *target.realm)
+		assert.Equal(t, "admins", *target.authRealm)
+		assert.Equal(t, "root", *target.username)
+		assert.Equal(t, "cli", *target.clientID)
+		assert.Equal(t, "secret", *target.password)
+	})
+	t.Run("Given flags are kept, even with their default value", func(t *testing.T) {
+		var source, target = parse("--source-url", "https://kc", "--source-realm", "staging", "--source-username", "root",
+			"--target-realm", "production", "--target-username", "admin")
+		target.inherit(source)
+		assert.Equal(t, "https://kc", *target.url)
+		assert.Equal(t, "production", *target.realm)
+		assert.Equal(t, "admin", *target.username)
+	})
+	t.Run("Passwords from the environment are kept", func(t *testing.T) {
+		var source, target = parse("--source-url", "https://kc", "--source-realm", "staging", "--source-password", "secret")
+		*target.password = "target-secret"
+		target.inherit(source)
+		assert.Equal(t, "target-secret", *target.password)
+	})
+	t.Run("Defaults are used when neither instance gives them", func(t *testing.T) {
+		var source, target = parse("--source-url", "https://kc", "--source-realm", "staging")
+		target.inherit(source)
+		assert.Equal(t, "master", *target.authRealm)
+		assert.Equal(t, "admin", *target.username)
+		assert.Equal(t, "admin-cli", *target.clientID)
+	})
+}
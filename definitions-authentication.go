@@ -0,0 +1,77 @@
+package keycloak
+
+// AuthenticationExecutionNode is an execution of an authentication flow with its resolved configuration and, when the
+// execution is a sub-flow, its own executions
+type AuthenticationExecutionNode struct {
+	Execution AuthenticationExecutionInfoRepresentation `json:"execution"`
+	Config    *AuthenticatorConfigRepresentation        `json:"config,omitempty"`
+	Children  []AuthenticationExecutionNode             `json:"children,omitempty"`
+}
+
+// GetLevel returns the depth of the execution in the flow
+func (n AuthenticationExecutionNode) GetLevel() int {
+	if n.Execution.Level == nil {
+		return 0
+	}
+	return int(*n.Execution.Level)
+}
+
+// GetIndex returns the position of the execution in its parent flow
+func (n AuthenticationExecutionNode) GetIndex() int {
+	if n.Execution.Index == nil {
+		return 0
+	}
+	return int(*n.Execution.Index)
+}
+
+// IsFlow tells whether the execution is a sub-flow
+func (n AuthenticationExecutionNode) IsFlow() bool {
+	return n.Execution.AuthenticationFlow != nil && *n.Execution.AuthenticationFlow
+}
+
+// BuildAuthenticationExecutionTree builds a tree from the flat list of executions returned by Keycloak. The list is
+// expected in depth-first order, each execution being followed by the executions of its sub-flow with a greater level.
+// configs is used to resolve the authenticationConfig of each execution and may be nil.
+func BuildAuthenticationExecutionTree(executions []AuthenticationExecutionInfoRepresentation, configs map[string]AuthenticatorConfigRepresentation) []AuthenticationExecutionNode {
+	var res, _ = buildAuthenticationExecutionLevel(executions, 0, configs)
+	return res
+}
+
+func buildAuthenticationExecutionLevel(executions []AuthenticationExecutionInfoRepresentation, level int, configs map[string]AuthenticatorConfigRepresentation) ([]AuthenticationExecutionNode, int) {
+	var res []AuthenticationExecutionNode
+	var idx = 0
+	for idx < len(executions) {
+		var node = AuthenticationExecutionNode{Execution: executions[idx]}
+		if node.GetLevel() < level {
+			break
+		}
+		if config, ok := configs[stringValue(node.Execution.AuthenticationConfig)]; ok {
+			node.Config = &config
+		}
+		idx++
+		if idx < len(executions) {
+			var children, consumed = buildAuthenticationExecutionLevel(executions[idx:], node.GetLevel()+1, configs)
+			node.Children = children
+			idx += consumed
+		}
+		res = append(res, node)
+	}
+	return res, idx
+}
+
+// WalkAuthenticationExecutionTree calls fn on each node of the tree, parents before their children, and stops as soon as fn returns false
+func WalkAuthenticationExecutionTree(nodes []AuthenticationExecutionNode, fn func(node AuthenticationExecutionNode) bool) bool {
+	for _, node := range nodes {
+		if !fn(node) || !WalkAuthenticationExecutionTree(node.Children, fn) {
+			return false
+		}
+	}
+	return true
+}
+
+func stringValue(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
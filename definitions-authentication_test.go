@@ -0,0 +1,79 @@
+package keycloak
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func createExecutionInfo(displayName string, level int32, index int32, isFlow bool, configID *string) AuthenticationExecutionInfoRepresentation {
+	return AuthenticationExecutionInfoRepresentation{
+		DisplayName:          &displayName,
+		Level:                &level,
+		Index:                &index,
+		AuthenticationFlow:   &isFlow,
+		AuthenticationConfig: configID,
+	}
+}
+
+func TestBuildAuthenticationExecutionTree(t *testing.T) {
+	var configID = "config-id"
+	var configAlias = "otp-config"
+	var executions = []AuthenticationExecutionInfoRepresentation{
+		createExecutionInfo("Cookie", 0, 0, false, nil),
+		createExecutionInfo("Forms", 0, 1, true, nil),
+		createExecutionInfo("Username Password Form", 1, 0, false, nil),
+		createExecutionInfo("Conditional OTP", 1, 1, true, nil),
+		createExecutionInfo("Condition - user configured", 2, 0, false, nil),
+		createExecutionInfo("OTP Form", 2, 1, false, &configID),
+		createExecutionInfo("Identity Provider Redirector", 0, 2, false, nil),
+	}
+	var configs = map[string]AuthenticatorConfigRepresentation{
+		configID: {ID: &configID, Alias: &configAlias},
+	}
+
+	t.Run("Empty list", func(t *testing.T) {
+		assert.Len(t, BuildAuthenticationExecutionTree(nil, nil), 0)
+	})
+	t.Run("Nested flows", func(t *testing.T) {
+		var tree = BuildAuthenticationExecutionTree(executions, configs)
+		assert.Len(t, tree, 3)
+		assert.Equal(t, "Cookie", *tree[0].Execution.DisplayName)
+		assert.Len(t, tree[0].Children, 0)
+		assert.True(t, tree[1].IsFlow())
+		assert.Equal(t, 1, tree[1].GetIndex())
+		assert.Len(t, tree[1].Children, 2)
+		assert.Equal(t, 1, tree[1].Children[1].GetLevel())
+		assert.Len(t, tree[1].Children[1].Children, 2)
+		var otp = tree[1].Children[1].Children[1]
+		assert.Equal(t, 2, otp.GetLevel())
+		assert.Equal(t, configAlias, *otp.Config.Alias)
+		assert.Equal(t, "Identity Provider Redirector", *tree[2].Execution.DisplayName)
+		assert.Nil(t, tree[2].Config)
+	})
+	t.Run("Missing level and index", func(t *testing.T) {
+		var node = AuthenticationExecutionNode{}
+		assert.Equal(t, 0, node.GetLevel())
+		assert.Equal(t, 0, node.GetIndex())
+		assert.False(t, node.IsFlow())
+	})
+	t.Run("Walk", func(t *testing.T) {
+		var tree = BuildAuthenticationExecutionTree(executions, configs)
+		var names []string
+		assert.True(t, WalkAuthenticationExecutionTree(tree, func(node AuthenticationExecutionNode) bool {
+			names = append(names, *node.Execution.DisplayName)
+			return true
+		}))
+		assert.Len(t, names, len(executions))
+		for idx, execution := range executions {
+			assert.Equal(t, *execution.DisplayName, names[idx])
+		}
+
+		names = nil
+		assert.False(t, WalkAuthenticationExecutionTree(tree, func(node AuthenticationExecutionNode) bool {
+			names = append(names, *node.Execution.DisplayName)
+			return !node.IsFlow()
+		}))
+		assert.Equal(t, []string{"Cookie", "Forms"}, names)
+	})
+}
@@ -0,0 +1,421 @@
+package keycloak
+
+import (
+	"errors"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+
+	commonhttp "github.com/cloudtrust/common-service/v2/errors"
+)
+
+// Password policy identifiers
+const (
+	PasswordPolicyLength                     = "length"
+	PasswordPolicyMaxLength                  = "maxLength"
+	PasswordPolicyDigits                     = "digits"
+	PasswordPolicyLowerCase                  = "lowerCase"
+	PasswordPolicyUpperCase                  = "upperCase"
+	PasswordPolicySpecialChars               = "specialChars"
+	PasswordPolicyNotUsername                = "notUsername"
+	PasswordPolicyNotContainsUsername        = "notContainsUsername"
+	PasswordPolicyNotEmail                   = "notEmail"
+	PasswordPolicyRegexPattern               = "regexPattern"
+	PasswordPolicyPasswordHistory            = "passwordHistory"
+	PasswordPolicyPasswordBlacklist          = "passwordBlacklist"
+	PasswordPolicyHashAlgorithm              = "hashAlgorithm"
+	PasswordPolicyHashIterations             = "hashIterations"
+	PasswordPolicyForceExpiredPasswordChange = "forceExpiredPasswordChange"
+
+	passwordPolicySeparator = " and "
+	passwordPolicyUndefined = "undefined"
+)
+
+// Error messages returned by Keycloak when a password does not match the password policy
+const (
+	MsgInvalidPasswordMinLength           = "invalidPasswordMinLengthMessage"
+	MsgInvalidPasswordMaxLength           = "invalidPasswordMaxLengthMessage"
+	MsgInvalidPasswordMinDigits           = "invalidPasswordMinDigitsMessage"
+	MsgInvalidPasswordMinLowerCaseChars   = "invalidPasswordMinLowerCaseCharsMessage"
+	MsgInvalidPasswordMinUpperCaseChars   = "invalidPasswordMinUpperCaseCharsMessage"
+	MsgInvalidPasswordMinSpecialChars     = "invalidPasswordMinSpecialCharsMessage"
+	MsgInvalidPasswordNotUsername         = "invalidPasswordNotUsernameMessage"
+	MsgInvalidPasswordNotContainsUsername = "invalidPasswordNotContainsUsernameMessage"
+	MsgInvalidPasswordNotEmail            = "invalidPasswordNotEmailMessage"
+	MsgInvalidPasswordRegexPattern        = "invalidPasswordRegexPatternMessage"
+)
+
+var passwordPolicyDefaultValues = map[string]string{
+	PasswordPolicyLength:                     "8",
+	PasswordPolicyMaxLength:                  "64",
+	PasswordPolicyDigits:                     "1",
+	PasswordPolicyLowerCase:                  "1",
+	PasswordPolicyUpperCase:                  "1",
+	PasswordPolicySpecialChars:               "1",
+	PasswordPolicyPasswordHistory:            "3",
+	PasswordPolicyHashAlgorithm:              "pbkdf2-sha256",
+	PasswordPolicyForceExpiredPasswordChange: "365",
+}
+
+var passwordPolicyIntValues = map[string]bool{
+	PasswordPolicyLength:                     true,
+	PasswordPolicyMaxLength:                  true,
+	PasswordPolicyDigits:                     true,
+	PasswordPolicyLowerCase:                  true,
+	PasswordPolicyUpperCase:                  true,
+	PasswordPolicySpecialChars:               true,
+	PasswordPolicyPasswordHistory:            true,
+	PasswordPolicyHashIterations:             true,
+	PasswordPolicyForceExpiredPasswordChange: true,
+}
+
+// PasswordPolicyItem is a single policy of a password policy
+type PasswordPolicyItem struct {
+	ID    string
+	Value string
+}
+
+// PasswordPolicy is the typed version of RealmRepresentation.PasswordPolicy
+type PasswordPolicy struct {
+	Items []PasswordPolicyItem
+}
+
+// NewPasswordPolicy creates an empty password policy
+func NewPasswordPolicy() *PasswordPolicy {
+	return &PasswordPolicy{}
+}
+
+// passwordPolicyNextItem matches what may follow the closing parenthesis of a value: the end of the policy or the
+// identifier of the next item. It allows values such as regular expressions to contain parentheses.
+var passwordPolicyNextItem = regexp.MustCompile(`^\s*($|and\s+[A-Za-z][\w-]*\s*(\(|$|and\s))`)
+
+// ParsePasswordPolicy parses a password policy as stored in RealmRepresentation.PasswordPolicy,
+// e.g. "length(8) and digits(1) and notUsername(undefined)". Regular expressions are kept as is as they are evaluated
+// by Keycloak with the Java syntax.
+func ParsePasswordPolicy(policy string) (*PasswordPolicy, error) {
+	var res = NewPasswordPolicy()
+	var rest = strings.TrimSpace(policy)
+	for rest != "" {
+		var id, value string
+		var open = strings.Index(rest, "(")
+		var separator = strings.Index(rest, passwordPolicySeparator)
+		if open < 0 || (separator >= 0 && separator < open) {
+			// Item without value
+			id, rest = rest, ""
+			if separator >= 0 {
+				id, rest = id[:separator], id[separator+len(passwordPolicySeparator):]
+			}
+			id = strings.TrimSpace(id)
+		} else {
+			id = strings.TrimSpace(rest[:open])
+			var end = passwordPolicyValueEnd(rest[open+1:])
+			if end < 0 {
+				return nil, errors.New(MsgErrCannotParse + ".passwordPolicy." + id)
+			}
+			value = rest[open+1 : open+1+end]
+			rest = strings.TrimPrefix(strings.TrimSpace(rest[open+1+end+1:]), "and")
+		}
+		rest = strings.TrimSpace(rest)
+		if id == "" {
+			return nil, errors.New(MsgErrCannotParse + ".passwordPolicy")
+		}
+		if value == passwordPolicyUndefined {
+			value = ""
+		}
+		if value == "" {
+			value = passwordPolicyDefaultValues[id]
+		}
+		// hashIterations has no default value: Keycloak uses the one of the hash algorithm
+		if passwordPolicyIntValues[id] && value != "" {
+			if _, err := strconv.Atoi(value); err != nil {
+				return nil, errors.New(MsgErrInvalidParam + ".passwordPolicy." + id)
+			}
+		}
+		res.Items = append(res.Items, PasswordPolicyItem{ID: id, Value: value})
+	}
+	return res, nil
+}
+
+// passwordPolicyValueEnd returns the index of the parenthesis closing the value which starts value, or -1
+func passwordPolicyValueEnd(value string) int {
+	for i, r := range value {
+		if r == ')' && passwordPolicyNextItem.MatchString(value[i+1:]) {
+			return i
+		}
+	}
+	return -1
+}
+
+// String returns the password policy in the format expected by RealmRepresentation.PasswordPolicy
+func (p *PasswordPolicy) String() string {
+	var parts []string
+	for _, item := range p.Items {
+		var value = item.Value
+		if value == "" {
+			value = passwordPolicyUndefined
+		}
+		parts = append(parts, item.ID+"("+value+")")
+	}
+	return strings.Join(parts, passwordPolicySeparator)
+}
+
+// Get returns the value of the first policy with the given identifier
+func (p *PasswordPolicy) Get(id string) (string, bool) {
+	for _, item := range p.Items {
+		if item.ID == id {
+			return item.Value, true
+		}
+	}
+	return "", false
+}
+
+// GetInt returns the value of the first policy with the given identifier as an integer
+func (p *PasswordPolicy) GetInt(id string) (int, bool) {
+	var value, ok = p.Get(id)
+	if !ok {
+		return 0, false
+	}
+	var res, err = strconv.Atoi(value)
+	return res, err == nil
+}
+
+// Set sets the value of a policy. An existing policy with the same identifier is replaced.
+func (p *PasswordPolicy) Set(id string, value string) *PasswordPolicy {
+	for i := range p.Items {
+		if p.Items[i].ID == id {
+			p.Items[i].Value = value
+			return p
+		}
+	}
+	p.Items = append(p.Items, PasswordPolicyItem{ID: id, Value: value})
+	return p
+}
+
+// Remove removes all the policies with the given identifier
+func (p *PasswordPolicy) Remove(id string) *PasswordPolicy {
+	var items []PasswordPolicyItem
+	for _, item := range p.Items {
+		if item.ID != id {
+			items = append(items, item)
+		}
+	}
+	p.Items = items
+	return p
+}
+
+func (p *PasswordPolicy) setInt(id string, value int) *PasswordPolicy {
+	return p.Set(id, strconv.Itoa(value))
+}
+
+// Length sets the minimum length of the password
+func (p *PasswordPolicy) Length(value int) *PasswordPolicy {
+	return p.setInt(PasswordPolicyLength, value)
+}
+
+// MaxLength sets the maximum length of the password
+func (p *PasswordPolicy) MaxLength(value int) *PasswordPolicy {
+	return p.setInt(PasswordPolicyMaxLength, value)
+}
+
+// Digits sets the minimum number of digits
+func (p *PasswordPolicy) Digits(value int) *PasswordPolicy {
+	return p.setInt(PasswordPolicyDigits, value)
+}
+
+// LowerCase sets the minimum number of lower case characters
+func (p *PasswordPolicy) LowerCase(value int) *PasswordPolicy {
+	return p.setInt(PasswordPolicyLowerCase, value)
+}
+
+// UpperCase sets the minimum number of upper case characters
+func (p *PasswordPolicy) UpperCase(value int) *PasswordPolicy {
+	return p.setInt(PasswordPolicyUpperCase, value)
+}
+
+// SpecialChars sets the minimum number of special characters
+func (p *PasswordPolicy) SpecialChars(value int) *PasswordPolicy {
+	return p.setInt(PasswordPolicySpecialChars, value)
+}
+
+// NotUsername forbids a password equal to the username
+func (p *PasswordPolicy) NotUsername() *PasswordPolicy {
+	return p.Set(PasswordPolicyNotUsername, "")
+}
+
+// NotContainsUsername forbids a password containing the username
+func (p *PasswordPolicy) NotContainsUsername() *PasswordPolicy {
+	return p.Set(PasswordPolicyNotContainsUsername, "")
+}
+
+// NotEmail forbids a password equal to the email
+func (p *PasswordPolicy) NotEmail() *PasswordPolicy {
+	return p.Set(PasswordPolicyNotEmail, "")
+}
+
+// RegexPattern sets a pattern the whole password must match
+func (p *PasswordPolicy) RegexPattern(value string) *PasswordPolicy {
+	return p.Set(PasswordPolicyRegexPattern, value)
+}
+
+// PasswordHistory sets the number of previous passwords which can't be reused
+func (p *PasswordPolicy) PasswordHistory(value int) *PasswordPolicy {
+	return p.setInt(PasswordPolicyPasswordHistory, value)
+}
+
+// PasswordBlacklist sets the name of the blacklist file
+func (p *PasswordPolicy) PasswordBlacklist(value string) *PasswordPolicy {
+	return p.Set(PasswordPolicyPasswordBlacklist, value)
+}
+
+// HashAlgorithm sets the hashing algorithm
+func (p *PasswordPolicy) HashAlgorithm(value string) *PasswordPolicy {
+	return p.Set(PasswordPolicyHashAlgorithm, value)
+}
+
+// HashIterations sets the number of hashing iterations. When it is not set, Keycloak uses the default of the hash
+// algorithm
+func (p *PasswordPolicy) HashIterations(value int) *PasswordPolicy {
+	return p.setInt(PasswordPolicyHashIterations, value)
+}
+
+// ForceExpiredPasswordChange sets the number of days after which the password must be changed
+func (p *PasswordPolicy) ForceExpiredPasswordChange(value int) *PasswordPolicy {
+	return p.setInt(PasswordPolicyForceExpiredPasswordChange, value)
+}
+
+// CheckSupported checks that the policies are known by the Keycloak server, as listed in ServerInfoRepresentation.PasswordPolicies
+func (p *PasswordPolicy) CheckSupported(policyTypes []PasswordPolicyTypeRepresentation) error {
+	var supported = map[string]PasswordPolicyTypeRepresentation{}
+	for _, policyType := range policyTypes {
+		if policyType.ID != nil {
+			supported[*policyType.ID] = policyType
+		}
+	}
+	var seen = map[string]bool{}
+	for _, item := range p.Items {
+		var policyType, ok = supported[item.ID]
+		if !ok {
+			return errors.New(MsgErrInvalidParam + ".passwordPolicy." + item.ID)
+		}
+		if seen[item.ID] && (policyType.MultipleSupported == nil || !*policyType.MultipleSupported) {
+			return errors.New(MsgErrExistingValue + ".passwordPolicy." + item.ID)
+		}
+		seen[item.ID] = true
+	}
+	return nil
+}
+
+// CheckPassword returns the keys of the messages Keycloak would return for each policy the password does not match.
+// Policies which can't be checked locally (history, blacklist) are ignored.
+func (p *PasswordPolicy) CheckPassword(password string, username string, email string) []string {
+	var res, _ = p.CheckPasswordLocally(password, username, email)
+	return res
+}
+
+// CheckPasswordLocally returns the same messages as CheckPassword and the identifiers of the policies which could not be
+// checked locally, such as the password history, the blacklists or regular expressions using Java features unknown
+// to Go. Keycloak may still reject a password which matches the policies checked locally.
+func (p *PasswordPolicy) CheckPasswordLocally(password string, username string, email string) ([]string, []string) {
+	var messages, unchecked []string
+	for _, item := range p.Items {
+		var message, checked = item.check(password, username, email)
+		if !checked {
+			unchecked = append(unchecked, item.ID)
+		} else if message != "" {
+			messages = append(messages, message)
+		}
+	}
+	return messages, unchecked
+}
+
+// ValidatePassword checks the password against the policy and returns the error Keycloak would return for the first
+// policy the password does not match
+func (p *PasswordPolicy) ValidatePassword(password string, username string, email string) error {
+	var messages = p.CheckPassword(password, username, email)
+	if len(messages) == 0 {
+		return nil
+	}
+	return commonhttp.Error{
+		Status:  http.StatusBadRequest,
+		Message: "keycloak." + messages[0],
+	}
+}
+
+// check returns the message of the policy if the password does not match it, and false if it can't be checked locally
+func (item PasswordPolicyItem) check(password string, username string, email string) (string, bool) {
+	var intValue, _ = strconv.Atoi(item.Value)
+	switch item.ID {
+	case PasswordPolicyLength:
+		if javaLength(password) < intValue {
+			return MsgInvalidPasswordMinLength, true
+		}
+	case PasswordPolicyMaxLength:
+		if javaLength(password) > intValue {
+			return MsgInvalidPasswordMaxLength, true
+		}
+	case PasswordPolicyDigits:
+		if countRunes(password, unicode.IsDigit) < intValue {
+			return MsgInvalidPasswordMinDigits, true
+		}
+	case PasswordPolicyLowerCase:
+		if countRunes(password, unicode.IsLower) < intValue {
+			return MsgInvalidPasswordMinLowerCaseChars, true
+		}
+	case PasswordPolicyUpperCase:
+		if countRunes(password, unicode.IsUpper) < intValue {
+			return MsgInvalidPasswordMinUpperCaseChars, true
+		}
+	case PasswordPolicySpecialChars:
+		if countRunes(password, isSpecialChar) < intValue {
+			return MsgInvalidPasswordMinSpecialChars, true
+		}
+	case PasswordPolicyNotUsername:
+		if username != "" && strings.EqualFold(password, username) {
+			return MsgInvalidPasswordNotUsername, true
+		}
+	case PasswordPolicyNotContainsUsername:
+		if username != "" && strings.Contains(strings.ToLower(password), strings.ToLower(username)) {
+			return MsgInvalidPasswordNotContainsUsername, true
+		}
+	case PasswordPolicyNotEmail:
+		if email != "" && strings.EqualFold(password, email) {
+			return MsgInvalidPasswordNotEmail, true
+		}
+	case PasswordPolicyRegexPattern:
+		// Keycloak requires the whole password to match the pattern
+		var reg, err = regexp.Compile("^(?:" + item.Value + ")$")
+		if err != nil {
+			return "", false
+		}
+		if !reg.MatchString(password) {
+			return MsgInvalidPasswordRegexPattern, true
+		}
+	case PasswordPolicyHashAlgorithm, PasswordPolicyHashIterations, PasswordPolicyForceExpiredPasswordChange:
+		// Not related to the value of the password
+	default:
+		return "", false
+	}
+	return "", true
+}
+
+func countRunes(value string, predicate func(rune) bool) int {
+	var count = 0
+	for _, r := range value {
+		if predicate(r) {
+			count++
+		}
+	}
+	return count
+}
+
+func isSpecialChar(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+}
+
+// javaLength returns the length of the password as computed by Keycloak, in UTF-16 code units
+func javaLength(password string) int {
+	return len(utf16.Encode([]rune(password)))
+}
@@ -0,0 +1,124 @@
+package keycloak
+
+import (
+	"net/http"
+	"testing"
+
+	commonhttp "github.com/cloudtrust/common-service/v2/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParsePasswordPolicy(t *testing.T) {
+	t.Run("Empty policy", func(t *testing.T) {
+		var policy, err = ParsePasswordPolicy("  ")
+		assert.Nil(t, err)
+		assert.Len(t, policy.Items, 0)
+		assert.Equal(t, "", policy.String())
+	})
+	t.Run("Valid policy", func(t *testing.T) {
+		var policy, err = ParsePasswordPolicy("length(12) and digits and notUsername(undefined) and regexPattern(^[a-z].*) and hashAlgorithm(pbkdf2-sha512)")
+		assert.Nil(t, err)
+		assert.Equal(t, []PasswordPolicyItem{
+			{ID: PasswordPolicyLength, Value: "12"},
+			{ID: PasswordPolicyDigits, Value: "1"},
+			{ID: PasswordPolicyNotUsername, Value: ""},
+			{ID: PasswordPolicyRegexPattern, Value: "^[a-z].*"},
+			{ID: PasswordPolicyHashAlgorithm, Value: "pbkdf2-sha512"},
+		}, policy.Items)
+		assert.Equal(t, "length(12) and digits(1) and notUsername(undefined) and regexPattern(^[a-z].*) and hashAlgorithm(pbkdf2-sha512)", policy.String())
+
+		var length, ok = policy.GetInt(PasswordPolicyLength)
+		assert.True(t, ok)
+		assert.Equal(t, 12, length)
+		_, ok = policy.GetInt(PasswordPolicyMaxLength)
+		assert.False(t, ok)
+	})
+	t.Run("Java regular expressions", func(t *testing.T) {
+		var policy, err = ParsePasswordPolicy("regexPattern((?=.*[A-Z])(?=.*(and|or)).*) and length(8) and regexPattern(a and (b)) and digits")
+		assert.Nil(t, err)
+		assert.Equal(t, []PasswordPolicyItem{
+			{ID: PasswordPolicyRegexPattern, Value: "(?=.*[A-Z])(?=.*(and|or)).*"},
+			{ID: PasswordPolicyLength, Value: "8"},
+			{ID: PasswordPolicyRegexPattern, Value: "a and (b)"},
+			{ID: PasswordPolicyDigits, Value: "1"},
+		}, policy.Items)
+		assert.Equal(t, "regexPattern((?=.*[A-Z])(?=.*(and|or)).*) and length(8) and regexPattern(a and (b)) and digits(1)", policy.String())
+	})
+	t.Run("Hash iterations without value", func(t *testing.T) {
+		for _, value := range []string{"hashIterations", "hashIterations(undefined)"} {
+			var policy, err = ParsePasswordPolicy(value)
+			assert.Nil(t, err)
+			assert.Equal(t, "hashIterations(undefined)", policy.String())
+			var _, ok = policy.GetInt(PasswordPolicyHashIterations)
+			assert.False(t, ok)
+		}
+	})
+	t.Run("Invalid policies", func(t *testing.T) {
+		for _, value := range []string{"length(8", "(8)", "digits(two)", "length(8) digits(1)"} {
+			var _, err = ParsePasswordPolicy(value)
+			assert.NotNil(t, err, value)
+		}
+	})
+}
+
+func TestPasswordPolicyBuilder(t *testing.T) {
+	var policy = NewPasswordPolicy().Length(8).Digits(2).UpperCase(1).LowerCase(1).SpecialChars(1).NotUsername().NotEmail().
+		PasswordHistory(3).HashIterations(27500).Length(10)
+	assert.Equal(t, "length(10) and digits(2) and upperCase(1) and lowerCase(1) and specialChars(1) and notUsername(undefined) and notEmail(undefined) and passwordHistory(3) and hashIterations(27500)", policy.String())
+
+	policy.Remove(PasswordPolicyPasswordHistory).Remove(PasswordPolicyHashIterations)
+	var parsed, err = ParsePasswordPolicy(policy.String())
+	assert.Nil(t, err)
+	assert.Equal(t, policy, parsed)
+}
+
+func TestPasswordPolicyCheckSupported(t *testing.T) {
+	var length, blacklist, multiple = PasswordPolicyLength, PasswordPolicyPasswordBlacklist, true
+	var types = []PasswordPolicyTypeRepresentation{{ID: &length}, {ID: &blacklist, MultipleSupported: &multiple}}
+
+	assert.Nil(t, NewPasswordPolicy().Length(8).PasswordBlacklist("a.txt").CheckSupported(types))
+	assert.NotNil(t, NewPasswordPolicy().Digits(1).CheckSupported(types))
+
+	var policy = &PasswordPolicy{Items: []PasswordPolicyItem{{ID: PasswordPolicyPasswordBlacklist, Value: "a.txt"}, {ID: PasswordPolicyPasswordBlacklist, Value: "b.txt"}}}
+	assert.Nil(t, policy.CheckSupported(types))
+	policy.Items = append(policy.Items, PasswordPolicyItem{ID: PasswordPolicyLength, Value: "8"}, PasswordPolicyItem{ID: PasswordPolicyLength, Value: "9"})
+	assert.NotNil(t, policy.CheckSupported(types))
+}
+
+func TestPasswordPolicyValidatePassword(t *testing.T) {
+	var policy = NewPasswordPolicy().Length(8).MaxLength(16).Digits(2).LowerCase(1).UpperCase(1).SpecialChars(1).
+		NotUsername().NotEmail().RegexPattern("[^ ]*").PasswordHistory(3)
+
+	t.Run("Valid password", func(t *testing.T) {
+		assert.Nil(t, policy.CheckPassword("Abcdef12!", "john", "john@domain.ch"))
+		assert.Nil(t, policy.ValidatePassword("Abcdef12!", "john", "john@domain.ch"))
+	})
+	t.Run("Too short and missing characters", func(t *testing.T) {
+		assert.Equal(t, []string{MsgInvalidPasswordMinLength, MsgInvalidPasswordMinDigits, MsgInvalidPasswordMinUpperCaseChars, MsgInvalidPasswordMinSpecialChars},
+			policy.CheckPassword("abc1", "john", ""))
+		assert.Equal(t, commonhttp.Error{Status: http.StatusBadRequest, Message: "keycloak." + MsgInvalidPasswordMinLength},
+			policy.ValidatePassword("abc1", "john", ""))
+	})
+	t.Run("Too long and does not match pattern", func(t *testing.T) {
+		assert.Equal(t, []string{MsgInvalidPasswordMaxLength, MsgInvalidPasswordRegexPattern}, policy.CheckPassword("Abcdef 12! Abcdef 12!", "", ""))
+	})
+	t.Run("Length in UTF-16 code units", func(t *testing.T) {
+		// Characters outside of the BMP are counted twice, as done by Keycloak
+		assert.Nil(t, NewPasswordPolicy().Length(2).CheckPassword("😀", "", ""))
+		assert.Equal(t, []string{MsgInvalidPasswordMaxLength}, NewPasswordPolicy().MaxLength(3).CheckPassword("😀😀", "", ""))
+	})
+	t.Run("Policies not checked locally", func(t *testing.T) {
+		var javaPolicy = NewPasswordPolicy().Length(8).RegexPattern("(?=.*[A-Z]).*").PasswordHistory(3).HashIterations(27500)
+		var messages, unchecked = javaPolicy.CheckPasswordLocally("abc", "", "")
+		assert.Equal(t, []string{MsgInvalidPasswordMinLength}, messages)
+		assert.Equal(t, []string{PasswordPolicyRegexPattern, PasswordPolicyPasswordHistory}, unchecked)
+		assert.Equal(t, messages, javaPolicy.CheckPassword("abc", "", ""))
+	})
+	t.Run("Username and email", func(t *testing.T) {
+		var usernamePolicy = NewPasswordPolicy().NotUsername().NotEmail()
+		assert.Equal(t, []string{MsgInvalidPasswordNotUsername}, usernamePolicy.CheckPassword("JOHN", "john", ""))
+		assert.Equal(t, []string{MsgInvalidPasswordNotEmail}, usernamePolicy.CheckPassword("john@domain.ch", "", "John@Domain.ch"))
+		assert.Nil(t, usernamePolicy.CheckPassword("john1", "john", ""))
+		assert.Equal(t, []string{MsgInvalidPasswordNotContainsUsername}, NewPasswordPolicy().NotContainsUsername().CheckPassword("my-john1", "john", ""))
+	})
+}
@@ -0,0 +1,138 @@
+package keycloak
+
+import (
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"strings"
+)
+
+const (
+	samlEntityDescriptor   = "EntityDescriptor"
+	samlEntitiesDescriptor = "EntitiesDescriptor"
+	samlKeyUseSigning      = "signing"
+	samlKeyUseEncryption   = "encryption"
+)
+
+// SAMLMetadata is the typed content of a SAML metadata descriptor
+type SAMLMetadata struct {
+	EntityID string
+	IDP      *SAMLRoleMetadata
+	SP       *SAMLRoleMetadata
+}
+
+// SAMLRoleMetadata is the content of an IDPSSODescriptor or of a SPSSODescriptor
+type SAMLRoleMetadata struct {
+	SingleSignOnServices      []SAMLEndpoint
+	SingleLogoutServices      []SAMLEndpoint
+	AssertionConsumerServices []SAMLEndpoint
+	NameIDFormats             []string
+	SigningCertificates       []string
+	EncryptionCertificates    []string
+}
+
+// SAMLEndpoint struct
+type SAMLEndpoint struct {
+	Binding   string `xml:"Binding,attr"`
+	Location  string `xml:"Location,attr"`
+	Index     *int   `xml:"index,attr"`
+	IsDefault *bool  `xml:"isDefault,attr"`
+}
+
+type samlKeyDescriptor struct {
+	Use          string   `xml:"use,attr"`
+	Certificates []string `xml:"KeyInfo>X509Data>X509Certificate"`
+}
+
+type samlRoleDescriptor struct {
+	KeyDescriptors            []samlKeyDescriptor `xml:"KeyDescriptor"`
+	NameIDFormats             []string            `xml:"NameIDFormat"`
+	SingleLogoutServices      []SAMLEndpoint      `xml:"SingleLogoutService"`
+	SingleSignOnServices      []SAMLEndpoint      `xml:"SingleSignOnService"`
+	AssertionConsumerServices []SAMLEndpoint      `xml:"AssertionConsumerService"`
+}
+
+type samlDescriptor struct {
+	XMLName           xml.Name
+	EntityID          string              `xml:"entityID,attr"`
+	IDPSSODescriptor  *samlRoleDescriptor `xml:"IDPSSODescriptor"`
+	SPSSODescriptor   *samlRoleDescriptor `xml:"SPSSODescriptor"`
+	EntityDescriptors []samlDescriptor    `xml:"EntityDescriptor"`
+}
+
+// ParseSAMLMetadata parses a SAML metadata document. When the document is an EntitiesDescriptor, the first
+// EntityDescriptor it contains is used.
+func ParseSAMLMetadata(data []byte) (SAMLMetadata, error) {
+	var descriptor samlDescriptor
+	if err := xml.Unmarshal(data, &descriptor); err != nil {
+		return SAMLMetadata{}, err
+	}
+	if descriptor.XMLName.Local == samlEntitiesDescriptor {
+		if len(descriptor.EntityDescriptors) == 0 {
+			return SAMLMetadata{}, errors.New(MsgErrMissingParam + "." + samlEntityDescriptor)
+		}
+		descriptor = descriptor.EntityDescriptors[0]
+	} else if descriptor.XMLName.Local != samlEntityDescriptor {
+		return SAMLMetadata{}, errors.New(MsgErrInvalidParam + "." + descriptor.XMLName.Local)
+	}
+	return SAMLMetadata{
+		EntityID: descriptor.EntityID,
+		IDP:      descriptor.IDPSSODescriptor.toRoleMetadata(),
+		SP:       descriptor.SPSSODescriptor.toRoleMetadata(),
+	}, nil
+}
+
+func (rd *samlRoleDescriptor) toRoleMetadata() *SAMLRoleMetadata {
+	if rd == nil {
+		return nil
+	}
+	var res = &SAMLRoleMetadata{
+		SingleSignOnServices:      rd.SingleSignOnServices,
+		SingleLogoutServices:      rd.SingleLogoutServices,
+		AssertionConsumerServices: rd.AssertionConsumerServices,
+	}
+	for _, format := range rd.NameIDFormats {
+		res.NameIDFormats = append(res.NameIDFormats, strings.TrimSpace(format))
+	}
+	for _, key := range rd.KeyDescriptors {
+		for _, cert := range key.Certificates {
+			// A key descriptor without use is used for both signing and encryption
+			cert = strings.Join(strings.Fields(cert), "")
+			if key.Use != samlKeyUseEncryption {
+				res.SigningCertificates = append(res.SigningCertificates, cert)
+			}
+			if key.Use != samlKeyUseSigning {
+				res.EncryptionCertificates = append(res.EncryptionCertificates, cert)
+			}
+		}
+	}
+	return res
+}
+
+// GetSigningX509Certificates decodes the signing certificates
+func (rm *SAMLRoleMetadata) GetSigningX509Certificates() ([]*x509.Certificate, error) {
+	var res []*x509.Certificate
+	for _, cert := range rm.SigningCertificates {
+		der, err := base64.StdEncoding.DecodeString(cert)
+		if err != nil {
+			return nil, err
+		}
+		x509Cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, x509Cert)
+	}
+	return res, nil
+}
+
+// GetSingleSignOnServiceURL returns the location of the single sign-on service using the given binding
+func (rm *SAMLRoleMetadata) GetSingleSignOnServiceURL(binding string) *string {
+	for _, sso := range rm.SingleSignOnServices {
+		if sso.Binding == binding {
+			return &sso.Location
+		}
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package keycloak
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	samlTestCertificate = "MIICnTCCAYUCBgFvrUPBDTANBgkqhkiG9w0BAQsFADASMRAwDgYDVQQDDAd0cnVzdGlkMB4XDTIwMDExNjA3Mjk1NloXDTMwMDExNjA3MzEzNlowEjEQMA4GA1UEAwwHdHJ1c3RpZDCCASIwDQYJKoZIhvcNAQEBBQADggEPADCCAQoCggEBALZnC3z8Z5YoJRDwii4fILVnHJluqbevvLQEuyCMfgGDPwLZtDy6X2ksg0/IMRxf+2k1qkiTlQodEdOm0Ypl9AB78wUj9Anh0mkWOSv2Xv2Qqq4TXhygyPcHi0DaP+slCjeyBNdXWm4CEVV81ylWy1wqPM8JTBCkOirGsC8IaVguov+41p/uj8oCrg7X0hdlVNfIGABiuYagoG6JfAL/jL2pykY8UOb3BPtTHGeS9lZCdOf6sPIonhc9BynU2cigfjoimhFgbJqBXMlnl+OB8YyT/cp/+Jrms3Tm6UjhdoC50EKTIK0Wis6jFf+S9dwlhGlgaa9poNI2SvY3W9HTqSMCAwEAATANBgkqhkiG9w0BAQsFAAOCAQEAYnM8axU9w9lQtt9lkEitTn9yhy9cCjTWM0utllutq1y7rVyLhuu+P//6SXUv1fuZQKAxFr3rteX55dBPvwCoT+lKl0SoLXgZv9DD1WUfiRApYNXfBsw3mluaYFwZceIaTBhHu0b6blTpl9wJndZx69TcLFsMKcbP/CifwFfutG6S0SToQHSoLi6rGKEVFKL6UIKFF35k0AG7Qg9ZwAc61GxKoCXm8U0JUdMc8Sq6yiFuqHQiKbuRW7KQVD36/whVInVEPRPLdQIu2A4TT5+dG/Vwz+2egS4ItsjKGN5B74ljK3jvH817HLxmBUdnWXM86LbHj7C6U/sQK56TSIUfyw=="
+	samlPostBinding     = "urn:oasis:names:tc:SAML:2.0:bindings:HTTP-POST"
+	samlRedirectBinding = "urn:oasis:names:tc:SAML:2.0:bindings:HTTP-Redirect"
+)
+
+var samlIdpDescriptor = fmt.Sprintf(`<md:EntityDescriptor xmlns="urn:oasis:names:tc:SAML:2.0:metadata" xmlns:md="urn:oasis:names:tc:SAML:2.0:metadata" xmlns:ds="http://www.w3.org/2000/09/xmldsig#" entityID="https://idp.domain.test/auth/realms/myrealm">
+	<md:IDPSSODescriptor WantAuthnRequestsSigned="true" protocolSupportEnumeration="urn:oasis:names:tc:SAML:2.0:protocol">
+		<md:KeyDescriptor use="signing">
+			<ds:KeyInfo>
+				<ds:KeyName>kid</ds:KeyName>
+				<ds:X509Data>
+					<ds:X509Certificate>%s</ds:X509Certificate>
+				</ds:X509Data>
+			</ds:KeyInfo>
+		</md:KeyDescriptor>
+		<md:SingleLogoutService Binding="%s" Location="https://idp.domain.test/auth/realms/myrealm/protocol/saml"/>
+		<md:NameIDFormat>urn:oasis:names:tc:SAML:2.0:nameid-format:persistent</md:NameIDFormat>
+		<md:SingleSignOnService Binding="%s" Location="https://idp.domain.test/auth/realms/myrealm/protocol/saml"/>
+		<md:SingleSignOnService Binding="%s" Location="https://idp.domain.test/auth/realms/myrealm/protocol/saml/redirect"/>
+	</md:IDPSSODescriptor>
+</md:EntityDescriptor>`, samlTestCertificate, samlPostBinding, samlPostBinding, samlRedirectBinding)
+
+var samlSpDescriptor = fmt.Sprintf(`<md:EntitiesDescriptor xmlns:md="urn:oasis:names:tc:SAML:2.0:metadata" Name="urn:keycloak">
+	<md:EntityDescriptor entityID="my-sp">
+		<md:SPSSODescriptor AuthnRequestsSigned="true" protocolSupportEnumeration="urn:oasis:names:tc:SAML:2.0:protocol">
+			<md:KeyDescriptor>
+				<dsig:KeyInfo xmlns:dsig="http://www.w3.org/2000/09/xmldsig#">
+					<dsig:X509Data>
+						<dsig:X509Certificate>
+							%s
+						</dsig:X509Certificate>
+					</dsig:X509Data>
+				</dsig:KeyInfo>
+			</md:KeyDescriptor>
+			<md:AssertionConsumerService Binding="%s" Location="https://sp.domain.test/saml" index="1" isDefault="true"/>
+		</md:SPSSODescriptor>
+	</md:EntityDescriptor>
+</md:EntitiesDescriptor>`, samlTestCertificate, samlPostBinding)
+
+func TestParseSAMLMetadata(t *testing.T) {
+	t.Run("Invalid XML", func(t *testing.T) {
+		var _, err = ParseSAMLMetadata([]byte(`<md:EntityDescriptor`))
+		assert.NotNil(t, err)
+	})
+	t.Run("Unexpected root element", func(t *testing.T) {
+		var _, err = ParseSAMLMetadata([]byte(`<Other/>`))
+		assert.NotNil(t, err)
+	})
+	t.Run("Empty EntitiesDescriptor", func(t *testing.T) {
+		var _, err = ParseSAMLMetadata([]byte(`<EntitiesDescriptor/>`))
+		assert.NotNil(t, err)
+	})
+	t.Run("IDP descriptor", func(t *testing.T) {
+		var metadata, err = ParseSAMLMetadata([]byte(samlIdpDescriptor))
+		assert.Nil(t, err)
+		assert.Equal(t, "https://idp.domain.test/auth/realms/myrealm", metadata.EntityID)
+		assert.Nil(t, metadata.SP)
+		assert.NotNil(t, metadata.IDP)
+		assert.Len(t, metadata.IDP.SingleSignOnServices, 2)
+		assert.Len(t, metadata.IDP.SingleLogoutServices, 1)
+		assert.Equal(t, []string{"urn:oasis:names:tc:SAML:2.0:nameid-format:persistent"}, metadata.IDP.NameIDFormats)
+		assert.Equal(t, []string{samlTestCertificate}, metadata.IDP.SigningCertificates)
+		assert.Nil(t, metadata.IDP.EncryptionCertificates)
+		assert.Equal(t, "https://idp.domain.test/auth/realms/myrealm/protocol/saml/redirect", *metadata.IDP.GetSingleSignOnServiceURL(samlRedirectBinding))
+		assert.Nil(t, metadata.IDP.GetSingleSignOnServiceURL("unknown"))
+
+		certs, err := metadata.IDP.GetSigningX509Certificates()
+		assert.Nil(t, err)
+		assert.Len(t, certs, 1)
+		assert.Equal(t, "trustid", certs[0].Subject.CommonName)
+	})
+	t.Run("SP descriptor", func(t *testing.T) {
+		var metadata, err = ParseSAMLMetadata([]byte(samlSpDescriptor))
+		assert.Nil(t, err)
+		assert.Equal(t, "my-sp", metadata.EntityID)
+		assert.Nil(t, metadata.IDP)
+		assert.NotNil(t, metadata.SP)
+		assert.Len(t, metadata.SP.AssertionConsumerServices, 1)
+		assert.Equal(t, 1, *metadata.SP.AssertionConsumerServices[0].Index)
+		assert.True(t, *metadata.SP.AssertionConsumerServices[0].IsDefault)
+		assert.Equal(t, []string{samlTestCertificate}, metadata.SP.SigningCertificates)
+		assert.Equal(t, []string{samlTestCertificate}, metadata.SP.EncryptionCertificates)
+	})
+	t.Run("Invalid certificate", func(t *testing.T) {
+		var rm = SAMLRoleMetadata{SigningCertificates: []string{"not base64!"}}
+		var _, err = rm.GetSigningX509Certificates()
+		assert.NotNil(t, err)
+
+		rm.SigningCertificates = []string{"AAEC"}
+		_, err = rm.GetSigningX509Certificates()
+		assert.NotNil(t, err)
+	})
+}
@@ -87,6 +87,14 @@ type AuthenticatorConfigRepresentation struct {
 	ID     *string         `json:"id,omitempty"`
 }
 
+// BruteForceStatusRepresentation struct
+type BruteForceStatusRepresentation struct {
+	Disabled      *bool   `json:"disabled,omitempty"`
+	NumFailures   *int32  `json:"numFailures,omitempty"`
+	LastFailure   *int64  `json:"lastFailure,omitempty"`
+	LastIPFailure *string `json:"lastIPFailure,omitempty"`
+}
+
 // CertificateRepresentation struct
 type CertificateRepresentation struct {
 	Certificate *string `json:"certificate,omitempty"`
@@ -131,43 +139,44 @@ type ClientMappingsRepresentation struct {
 
 // ClientRepresentation struct
 type ClientRepresentation struct {
-	Access                       *map[string]any                 `json:"access,omitempty"`
-	AdminURL                     *string                         `json:"adminUrl,omitempty"`
-	Attributes                   *map[string]any                 `json:"attributes,omitempty"`
-	AuthorizationServicesEnabled *bool                           `json:"authorizationServicesEnabled,omitempty"`
-	AuthorizationSettings        *ResourceServerRepresentation   `json:"authorizationSettings,omitempty"`
-	BaseURL                      *string                         `json:"baseUrl,omitempty"`
-	BearerOnly                   *bool                           `json:"bearerOnly,omitempty"`
-	ClientAuthenticatorType      *string                         `json:"clientAuthenticatorType,omitempty"`
-	ClientID                     *string                         `json:"clientId,omitempty"`
-	ClientTemplate               *string                         `json:"clientTemplate,omitempty"`
-	ConsentRequired              *bool                           `json:"consentRequired,omitempty"`
-	DefaultRoles                 *[]string                       `json:"defaultRoles,omitempty"`
-	Description                  *string                         `json:"description,omitempty"`
-	DirectAccessGrantsEnabled    *bool                           `json:"directAccessGrantsEnabled,omitempty"`
-	Enabled                      *bool                           `json:"enabled,omitempty"`
-	FrontchannelLogout           *bool                           `json:"frontchannelLogout,omitempty"`
-	FullScopeAllowed             *bool                           `json:"fullScopeAllowed,omitempty"`
-	ID                           *string                         `json:"id,omitempty"`
-	ImplicitFlowEnabled          *bool                           `json:"implicitFlowEnabled,omitempty"`
-	Name                         *string                         `json:"name,omitempty"`
-	NodeReRegistrationTimeout    *int32                          `json:"nodeReRegistrationTimeout,omitempty"`
-	NotBefore                    *int32                          `json:"notBefore,omitempty"`
-	Protocol                     *string                         `json:"protocol,omitempty"`
-	ProtocolMappers              *[]ProtocolMapperRepresentation `json:"protocolMappers,omitempty"`
-	PublicClient                 *bool                           `json:"publicClient,omitempty"`
-	RedirectUris                 *[]string                       `json:"redirectUris,omitempty"`
-	RegisteredNodes              *map[string]any                 `json:"registeredNodes,omitempty"`
-	RegistrationAccessToken      *string                         `json:"registrationAccessToken,omitempty"`
-	RootURL                      *string                         `json:"rootUrl,omitempty"`
-	Secret                       *string                         `json:"secret,omitempty"`
-	ServiceAccountsEnabled       *bool                           `json:"serviceAccountsEnabled,omitempty"`
-	StandardFlowEnabled          *bool                           `json:"standardFlowEnabled,omitempty"`
-	SurrogateAuthRequired        *bool                           `json:"surrogateAuthRequired,omitempty"`
-	UseTemplateConfig            *bool                           `json:"useTemplateConfig,omitempty"`
-	UseTemplateMappers           *bool                           `json:"useTemplateMappers,omitempty"`
-	UseTemplateScope             *bool                           `json:"useTemplateScope,omitempty"`
-	WebOrigins                   *[]string                       `json:"webOrigins,omitempty"`
+	Access                             *map[string]any                 `json:"access,omitempty"`
+	AdminURL                           *string                         `json:"adminUrl,omitempty"`
+	Attributes                         *map[string]any                 `json:"attributes,omitempty"`
+	AuthenticationFlowBindingOverrides *map[string]string              `json:"authenticationFlowBindingOverrides,omitempty"`
+	AuthorizationServicesEnabled       *bool                           `json:"authorizationServicesEnabled,omitempty"`
+	AuthorizationSettings              *ResourceServerRepresentation   `json:"authorizationSettings,omitempty"`
+	BaseURL                            *string                         `json:"baseUrl,omitempty"`
+	BearerOnly                         *bool                           `json:"bearerOnly,omitempty"`
+	ClientAuthenticatorType            *string                         `json:"clientAuthenticatorType,omitempty"`
+	ClientID                           *string                         `json:"clientId,omitempty"`
+	ClientTemplate                     *string                         `json:"clientTemplate,omitempty"`
+	ConsentRequired                    *bool                           `json:"consentRequired,omitempty"`
+	DefaultRoles                       *[]string                       `json:"defaultRoles,omitempty"`
+	Description                        *string                         `json:"description,omitempty"`
+	DirectAccessGrantsEnabled          *bool                           `json:"directAccessGrantsEnabled,omitempty"`
+	Enabled                            *bool                           `json:"enabled,omitempty"`
+	FrontchannelLogout                 *bool                           `json:"frontchannelLogout,omitempty"`
+	FullScopeAllowed                   *bool                           `json:"fullScopeAllowed,omitempty"`
+	ID                                 *string                         `json:"id,omitempty"`
+	ImplicitFlowEnabled                *bool                           `json:"implicitFlowEnabled,omitempty"`
+	Name                               *string                         `json:"name,omitempty"`
+	NodeReRegistrationTimeout          *int32                          `json:"nodeReRegistrationTimeout,omitempty"`
+	NotBefore                          *int32                          `json:"notBefore,omitempty"`
+	Protocol                           *string                         `json:"protocol,omitempty"`
+	ProtocolMappers                    *[]ProtocolMapperRepresentation `json:"protocolMappers,omitempty"`
+	PublicClient                       *bool                           `json:"publicClient,omitempty"`
+	RedirectUris                       *[]string                       `json:"redirectUris,omitempty"`
+	RegisteredNodes                    *map[string]any                 `json:"registeredNodes,omitempty"`
+	RegistrationAccessToken            *string                         `json:"registrationAccessToken,omitempty"`
+	RootURL                            *string                         `json:"rootUrl,omitempty"`
+	Secret                             *string                         `json:"secret,omitempty"`
+	ServiceAccountsEnabled             *bool                           `json:"serviceAccountsEnabled,omitempty"`
+	StandardFlowEnabled                *bool                           `json:"standardFlowEnabled,omitempty"`
+	SurrogateAuthRequired              *bool                           `json:"surrogateAuthRequired,omitempty"`
+	UseTemplateConfig                  *bool                           `json:"useTemplateConfig,omitempty"`
+	UseTemplateMappers                 *bool                           `json:"useTemplateMappers,omitempty"`
+	UseTemplateScope                   *bool                           `json:"useTemplateScope,omitempty"`
+	WebOrigins                         *[]string                       `json:"webOrigins,omitempty"`
 }
 
 // ClientTemplateRepresentation struct
@@ -287,6 +296,15 @@ type IdentityProviderMapperRepresentation struct {
 	Name                   *string           `json:"name,omitempty"`
 }
 
+// IdentityProviderMapperTypeRepresentation struct
+type IdentityProviderMapperTypeRepresentation struct {
+	Category   *string                         `json:"category,omitempty"`
+	HelpText   *string                         `json:"helpText,omitempty"`
+	ID         *string                         `json:"id,omitempty"`
+	Name       *string                         `json:"name,omitempty"`
+	Properties *[]ConfigPropertyRepresentation `json:"properties,omitempty"`
+}
+
 // IdentityProviderRepresentation struct
 type IdentityProviderRepresentation struct {
 	AddReadTokenRoleOnCreate  *bool             `json:"addReadTokenRoleOnCreate,omitempty"`
@@ -305,6 +323,12 @@ type IdentityProviderRepresentation struct {
 	TrustEmail                *bool             `json:"trustEmail,omitempty"`
 }
 
+// ImpersonationRepresentation struct
+type ImpersonationRepresentation struct {
+	SameRealm *bool   `json:"sameRealm,omitempty"`
+	Redirect  *string `json:"redirect,omitempty"`
+}
+
 // KeysMetadataRepresentation struct
 type KeysMetadataRepresentation struct {
 	Active *map[string]any                                        `json:"active,omitempty"`
@@ -663,6 +687,7 @@ type UserConsentRepresentation struct {
 	ClientID               *string         `json:"clientId,omitempty"`
 	CreatedDate            *int64          `json:"createdDate,omitempty"`
 	GrantedClientRoles     *map[string]any `json:"grantedClientRoles,omitempty"`
+	GrantedClientScopes    *[]string       `json:"grantedClientScopes,omitempty"`
 	GrantedProtocolMappers *map[string]any `json:"grantedProtocolMappers,omitempty"`
 	GrantedRealmRoles      *[]string       `json:"grantedRealmRoles,omitempty"`
 	LastUpdatedDate        *int64          `json:"lastUpdatedDate,omitempty"`
@@ -826,3 +851,47 @@ type LinkedAccountRepresentation struct {
 	DisplayName    *string `json:"displayName,omitempty"`
 	LinkedUsername *string `json:"linkedUsername,omitempty"`
 }
+
+// ApplicationRepresentation struct
+type ApplicationRepresentation struct {
+	ClientID            *string                `json:"clientId,omitempty"`
+	ClientName          *string                `json:"clientName,omitempty"`
+	Description         *string                `json:"description,omitempty"`
+	UserConsentRequired *bool                  `json:"userConsentRequired,omitempty"`
+	InUse               *bool                  `json:"inUse,omitempty"`
+	OfflineAccess       *bool                  `json:"offlineAccess,omitempty"`
+	RootURL             *string                `json:"rootUrl,omitempty"`
+	BaseURL             *string                `json:"baseUrl,omitempty"`
+	EffectiveURL        *string                `json:"effectiveUrl,omitempty"`
+	Consent             *ConsentRepresentation `json:"consent,omitempty"`
+	LogoURI             *string                `json:"logoUri,omitempty"`
+	PolicyURI           *string                `json:"policyUri,omitempty"`
+	TosURI              *string                `json:"tosUri,omitempty"`
+}
+
+// ConsentRepresentation struct
+type ConsentRepresentation struct {
+	GrantedScopes   *[]ConsentScopeRepresentation `json:"grantedScopes,omitempty"`
+	CreatedDate     *int64                        `json:"createdDate,omitempty"`
+	LastUpdatedDate *int64                        `json:"lastUpdatedDate,omitempty"`
+}
+
+// ConsentScopeRepresentation struct
+type ConsentScopeRepresentation struct {
+	ID          *string `json:"id,omitempty"`
+	Name        *string `json:"name,omitempty"`
+	DisplayText *string `json:"displayText,omitempty"`
+}
+
+// BrokeredTokenRepresentation struct
+type BrokeredTokenRepresentation struct {
+	AccessToken      *string `json:"access_token,omitempty"`
+	ExpiresIn        *int64  `json:"expires_in,omitempty"`
+	RefreshExpiresIn *int64  `json:"refresh_expires_in,omitempty"`
+	RefreshToken     *string `json:"refresh_token,omitempty"`
+	TokenType        *string `json:"token_type,omitempty"`
+	IDToken          *string `json:"id_token,omitempty"`
+	NotBeforePolicy  *int64  `json:"not-before-policy,omitempty"`
+	SessionState     *string `json:"session_state,omitempty"`
+	Scope            *string `json:"scope,omitempty"`
+}
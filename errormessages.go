@@ -18,6 +18,9 @@ const (
 	MsgErrExistingValue             = "existing"
 	MsgErrReadOnly                  = "readOnlyValue"
 	MsgErrCannotGetIssuer           = "cannotGetIssuer"
+	MsgErrInactiveToken             = "inactiveToken"
+	MsgErrInvalidToken              = "invalidToken"
+	MsgErrInsufficientToken         = "insufficientToken"
 
 	EvenParams       = "key/valParametersShouldBeEven"
 	TokenProviderURL = "tokenProviderURL"
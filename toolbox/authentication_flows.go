@@ -0,0 +1,506 @@
+package toolbox
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/cloudtrust/keycloak-client/v2"
+)
+
+// Kinds of changes applied by the flow reconciler
+const (
+	FlowChangeCreate      = "create"
+	FlowChangeDelete      = "delete"
+	FlowChangeRequirement = "requirement"
+	FlowChangeConfigure   = "configure"
+	FlowChangeRaise       = "raise"
+	FlowChangeDescribe    = "describe"
+
+	defaultFlowType         = "basic-flow"
+	requirementDisabled     = "DISABLED"
+	defaultFormFlowProvider = "registration-page-form"
+)
+
+var flowRequirements = []string{"REQUIRED", "ALTERNATIVE", "CONDITIONAL", requirementDisabled}
+
+// AuthenticationFlowsClient is the part of the Keycloak client used to manage authentication flows
+type AuthenticationFlowsClient interface {
+	GetAuthenticationFlows(accessToken string, realmName string) ([]keycloak.AuthenticationFlowRepresentation, error)
+	CreateAuthenticationFlow(accessToken string, realmName string, authFlow keycloak.AuthenticationFlowRepresentation) error
+	GetAuthenticationFlow(accessToken string, realmName, flowID string) (keycloak.AuthenticationFlowRepresentation, error)
+	UpdateAuthenticationFlow(accessToken string, realmName, flowID string, authFlow keycloak.AuthenticationFlowRepresentation) error
+	GetAuthenticationExecutionsForFlow(accessToken string, realmName, flowAlias string) ([]keycloak.AuthenticationExecutionInfoRepresentation, error)
+	UpdateAuthenticationExecutionForFlow(accessToken string, realmName, flowAlias string, authExecInfo keycloak.AuthenticationExecutionInfoRepresentation) error
+	CreateAuthenticationExecutionForFlow(accessToken string, realmName, flowAlias, provider string) (string, error)
+	CreateFlowWithExecutionForExistingFlow(accessToken string, realmName, flowAlias, alias, flowType, provider, description string) (string, error)
+	DeleteAuthenticationExecution(accessToken string, realmName, executionID string) error
+	RaiseExecutionPriority(accessToken string, realmName, executionID string) error
+	GetAuthenticatorConfig(accessToken string, realmName, configID string) (keycloak.AuthenticatorConfigRepresentation, error)
+	UpdateAuthenticatorConfig(accessToken string, realmName, configID string, config keycloak.AuthenticatorConfigRepresentation) error
+	UpdateAuthenticationExecution(accessToken string, realmName, executionID string, authConfig keycloak.AuthenticatorConfigRepresentation) error
+}
+
+// FlowSpec is the declarative description of an authentication flow or of a sub-flow. Sub-flows whose type changed
+// are deleted and created again.
+type FlowSpec struct {
+	Alias       string          `json:"alias"`
+	Description string          `json:"description,omitempty"` // Left unchanged when empty
+	Type        string          `json:"type,omitempty"`        // basic-flow (default), client-flow for top level flows or form-flow for sub-flows
+	Provider    string          `json:"provider,omitempty"`    // Form provider of a form-flow sub-flow. Defaults to registration-page-form
+	Executions  []ExecutionSpec `json:"executions,omitempty"`
+}
+
+// ExecutionSpec is the declarative description of an execution. An execution is either an authenticator or a sub-flow
+type ExecutionSpec struct {
+	Authenticator string                   `json:"authenticator,omitempty"` // Provider ID of the authenticator. Empty when the execution is a sub-flow
+	Flow          *FlowSpec                `json:"flow,omitempty"`          // Sub-flow. Nil when the execution is an authenticator
+	Requirement   string                   `json:"requirement,omitempty"`   // REQUIRED, ALTERNATIVE, CONDITIONAL or DISABLED. Left unchanged when empty
+	Config        *AuthenticatorConfigSpec `json:"config,omitempty"`
+}
+
+// AuthenticatorConfigSpec is the declarative description of an authenticator configuration
+type AuthenticatorConfigSpec struct {
+	Alias  string            `json:"alias"`
+	Config map[string]string `json:"config,omitempty"`
+}
+
+// FlowChange is a change applied or planned by the flow reconciler
+type FlowChange struct {
+	Kind   string
+	Path   string
+	Detail string
+}
+
+func (fc FlowChange) String() string {
+	if fc.Detail == "" {
+		return fmt.Sprintf("%-12s %s", fc.Kind, fc.Path)
+	}
+	return fmt.Sprintf("%-12s %s (%s)", fc.Kind, fc.Path, fc.Detail)
+}
+
+// FlowReconciler makes authentication flows match their declarative description
+type FlowReconciler struct {
+	client AuthenticationFlowsClient
+}
+
+// NewFlowReconciler creates a FlowReconciler
+func NewFlowReconciler(client AuthenticationFlowsClient) *FlowReconciler {
+	return &FlowReconciler{
+		client: client,
+	}
+}
+
+type flowReconciliation struct {
+	client      AuthenticationFlowsClient
+	accessToken string
+	realmName   string
+	dryRun      bool
+	changes     []FlowChange
+}
+
+// Plan computes the changes needed to make the flow match its description without applying them. An error is returned
+// when the description is invalid.
+func (fr *FlowReconciler) Plan(accessToken string, realmName string, flow FlowSpec) ([]FlowChange, error) {
+	return fr.reconcile(accessToken, realmName, flow, true)
+}
+
+// Apply applies the minimal set of changes needed to make the flow match its description
+func (fr *FlowReconciler) Apply(accessToken string, realmName string, flow FlowSpec) ([]FlowChange, error) {
+	return fr.reconcile(accessToken, realmName, flow, false)
+}
+
+func (fr *FlowReconciler) reconcile(accessToken string, realmName string, flow FlowSpec, dryRun bool) ([]FlowChange, error) {
+	if err := flow.validate(); err != nil {
+		return nil, err
+	}
+	var r = &flowReconciliation{
+		client:      fr.client,
+		accessToken: accessToken,
+		realmName:   realmName,
+		dryRun:      dryRun,
+	}
+	var flows, err = r.client.GetAuthenticationFlows(accessToken, realmName)
+	if err != nil {
+		return nil, err
+	}
+	var exists = false
+	for _, f := range flows {
+		if f.Alias != nil && *f.Alias == flow.Alias {
+			exists = true
+			if err = r.reconcileDescription(flow.Alias, f, flow.Description); err != nil {
+				return r.changes, err
+			}
+			break
+		}
+	}
+	if !exists {
+		var flowType = flow.flowType()
+		var topLevel, builtIn = true, false
+		if err = r.apply(FlowChange{Kind: FlowChangeCreate, Path: flow.Alias, Detail: flowType}, func() error {
+			return r.client.CreateAuthenticationFlow(accessToken, realmName, keycloak.AuthenticationFlowRepresentation{
+				Alias:       &flow.Alias,
+				Description: &flow.Description,
+				ProviderID:  &flowType,
+				TopLevel:    &topLevel,
+				BuiltIn:     &builtIn,
+			})
+		}); err != nil {
+			return r.changes, err
+		}
+	}
+	err = r.reconcileFlow(flow.Alias, flow.Alias, flow.Executions, exists || !dryRun)
+	return r.changes, err
+}
+
+// apply records the change and executes it unless in dry-run mode
+func (r *flowReconciliation) apply(change FlowChange, action func() error) error {
+	r.changes = append(r.changes, change)
+	if r.dryRun {
+		return nil
+	}
+	return action()
+}
+
+// getChildren returns the direct children of a flow
+func (r *flowReconciliation) getChildren(flowAlias string) ([]keycloak.AuthenticationExecutionInfoRepresentation, error) {
+	var executions, err = r.client.GetAuthenticationExecutionsForFlow(r.accessToken, r.realmName, flowAlias)
+	if err != nil {
+		return nil, err
+	}
+	var res []keycloak.AuthenticationExecutionInfoRepresentation
+	for _, execution := range executions {
+		if execution.Level == nil || *execution.Level == 0 {
+			res = append(res, execution)
+		}
+	}
+	return res, nil
+}
+
+func (r *flowReconciliation) reconcileFlow(path string, flowAlias string, desired []ExecutionSpec, exists bool) error {
+	var live []keycloak.AuthenticationExecutionInfoRepresentation
+	if exists {
+		var err error
+		if live, err = r.getChildren(flowAlias); err != nil {
+			return err
+		}
+	}
+
+	// Structural changes: delete unexpected executions then create missing ones
+	var matches = matchExecutions(desired, live)
+	if err := r.reconcileSubFlows(path, desired, live, matches); err != nil {
+		return err
+	}
+	var structuralChanges = false
+	for idx, execution := range live {
+		if !containsIndex(matches, idx) {
+			structuralChanges = true
+			if err := r.apply(FlowChange{Kind: FlowChangeDelete, Path: path + "/" + executionName(execution)}, func() error {
+				return r.client.DeleteAuthenticationExecution(r.accessToken, r.realmName, *execution.ID)
+			}); err != nil {
+				return err
+			}
+		}
+	}
+	for idx, spec := range desired {
+		if matches[idx] < 0 {
+			structuralChanges = true
+			if err := r.apply(FlowChange{Kind: FlowChangeCreate, Path: path + "/" + spec.name()}, func() error {
+				return r.createExecution(flowAlias, spec)
+			}); err != nil {
+				return err
+			}
+		}
+	}
+	if structuralChanges && !r.dryRun {
+		var err error
+		if live, err = r.getChildren(flowAlias); err != nil {
+			return err
+		}
+		matches = matchExecutions(desired, live)
+		for idx, spec := range desired {
+			if matches[idx] < 0 {
+				return errors.New(keycloak.MsgErrCannotObtain + ".execution." + spec.name())
+			}
+		}
+	}
+
+	// Requirements and configurations
+	for idx, spec := range desired {
+		var execution *keycloak.AuthenticationExecutionInfoRepresentation
+		if matches[idx] >= 0 {
+			execution = &live[matches[idx]]
+		}
+		if err := r.reconcileRequirement(path+"/"+spec.name(), flowAlias, spec, execution); err != nil {
+			return err
+		}
+		if err := r.reconcileConfig(path+"/"+spec.name(), spec, execution); err != nil {
+			return err
+		}
+	}
+
+	// Order
+	if err := r.reconcileOrder(path, desired, live, matches); err != nil {
+		return err
+	}
+
+	// Sub-flows
+	for idx, spec := range desired {
+		if spec.Flow != nil {
+			if err := r.reconcileFlow(path+"/"+spec.Flow.Alias, spec.Flow.Alias, spec.Flow.Executions, matches[idx] >= 0); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (r *flowReconciliation) createExecution(flowAlias string, spec ExecutionSpec) error {
+	var err error
+	if spec.Flow == nil {
+		_, err = r.client.CreateAuthenticationExecutionForFlow(r.accessToken, r.realmName, flowAlias, spec.Authenticator)
+	} else {
+		var flowType = spec.Flow.flowType()
+		var provider = spec.Flow.Provider
+		if provider == "" {
+			provider = defaultFormFlowProvider
+		}
+		_, err = r.client.CreateFlowWithExecutionForExistingFlow(r.accessToken, r.realmName, flowAlias, spec.Flow.Alias, flowType, provider, spec.Flow.Description)
+	}
+	return err
+}
+
+// reconcileSubFlows updates the description of the matching sub-flows. The ones whose type changed are unmatched to be
+// created again.
+func (r *flowReconciliation) reconcileSubFlows(path string, desired []ExecutionSpec, live []keycloak.AuthenticationExecutionInfoRepresentation, matches []int) error {
+	for idx, spec := range desired {
+		if spec.Flow == nil || matches[idx] < 0 || live[matches[idx]].FlowID == nil {
+			continue
+		}
+		var flow, err = r.client.GetAuthenticationFlow(r.accessToken, r.realmName, *live[matches[idx]].FlowID)
+		if err != nil {
+			return err
+		}
+		if flow.ProviderID != nil && *flow.ProviderID != spec.Flow.flowType() {
+			matches[idx] = -1
+			continue
+		}
+		if err = r.reconcileDescription(path+"/"+spec.Flow.Alias, flow, spec.Flow.Description); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *flowReconciliation) reconcileDescription(path string, flow keycloak.AuthenticationFlowRepresentation, description string) error {
+	var current string
+	if flow.Description != nil {
+		current = *flow.Description
+	}
+	if description == "" || description == current || flow.ID == nil {
+		return nil
+	}
+	var updated = flow
+	updated.Description = &description
+	return r.apply(FlowChange{Kind: FlowChangeDescribe, Path: path}, func() error {
+		return r.client.UpdateAuthenticationFlow(r.accessToken, r.realmName, *flow.ID, updated)
+	})
+}
+
+// reconcileRequirement and reconcileConfig get a nil execution in dry-run mode when it does not exist yet. The values
+// used by the actions are captured before they are built, the actions fail when the execution is still missing.
+func (r *flowReconciliation) reconcileRequirement(path string, flowAlias string, spec ExecutionSpec, execution *keycloak.AuthenticationExecutionInfoRepresentation) error {
+	if spec.Requirement == "" {
+		return nil
+	}
+	var updated keycloak.AuthenticationExecutionInfoRepresentation
+	if execution != nil {
+		updated = *execution
+	}
+	var current = requirementDisabled
+	if updated.Requirement != nil {
+		current = *updated.Requirement
+	}
+	if strings.EqualFold(current, spec.Requirement) {
+		return nil
+	}
+	var requirement = spec.Requirement
+	updated.Requirement = &requirement
+	var missing = execution == nil
+	return r.apply(FlowChange{Kind: FlowChangeRequirement, Path: path, Detail: current + " -> " + requirement}, func() error {
+		if missing {
+			return errors.New(keycloak.MsgErrCannotObtain + ".execution." + spec.name())
+		}
+		return r.client.UpdateAuthenticationExecutionForFlow(r.accessToken, r.realmName, flowAlias, updated)
+	})
+}
+
+func (r *flowReconciliation) reconcileConfig(path string, spec ExecutionSpec, execution *keycloak.AuthenticationExecutionInfoRepresentation) error {
+	if spec.Config == nil {
+		return nil
+	}
+	var alias = spec.Config.Alias
+	var config = keycloak.AuthenticatorConfigRepresentation{
+		Alias:  &alias,
+		Config: toAnyMap(spec.Config.Config),
+	}
+	if execution == nil || execution.AuthenticationConfig == nil {
+		var executionID string
+		if execution != nil && execution.ID != nil {
+			executionID = *execution.ID
+		}
+		return r.apply(FlowChange{Kind: FlowChangeConfigure, Path: path, Detail: "create " + alias}, func() error {
+			if executionID == "" {
+				return errors.New(keycloak.MsgErrCannotObtain + ".execution." + spec.name())
+			}
+			return r.client.UpdateAuthenticationExecution(r.accessToken, r.realmName, executionID, config)
+		})
+	}
+	var current, err = r.client.GetAuthenticatorConfig(r.accessToken, r.realmName, *execution.AuthenticationConfig)
+	if err != nil {
+		return err
+	}
+	if current.Alias != nil && *current.Alias == alias && sameConfig(current.Config, spec.Config.Config) {
+		return nil
+	}
+	var configID = *execution.AuthenticationConfig
+	if current.ID != nil {
+		configID = *current.ID
+	}
+	config.ID = &configID
+	return r.apply(FlowChange{Kind: FlowChangeConfigure, Path: path, Detail: "update " + alias}, func() error {
+		return r.client.UpdateAuthenticatorConfig(r.accessToken, r.realmName, configID, config)
+	})
+}
+
+// reconcileOrder sorts the executions using adjacent moves only. In dry-run mode, created executions are supposed to
+// be appended at the end of the flow.
+func (r *flowReconciliation) reconcileOrder(path string, desired []ExecutionSpec, live []keycloak.AuthenticationExecutionInfoRepresentation, matches []int) error {
+	// current contains indexes of desired executions, sorted by their current position in the flow
+	var current []int
+	for liveIdx := range live {
+		for desiredIdx, matchIdx := range matches {
+			if matchIdx == liveIdx {
+				current = append(current, desiredIdx)
+			}
+		}
+	}
+	for desiredIdx, matchIdx := range matches {
+		if matchIdx < 0 {
+			current = append(current, desiredIdx)
+		}
+	}
+	for i := 1; i < len(current); i++ {
+		for j := i; j > 0 && current[j] < current[j-1]; j-- {
+			var spec = desired[current[j]]
+			var matchIdx = matches[current[j]]
+			if err := r.apply(FlowChange{Kind: FlowChangeRaise, Path: path + "/" + spec.name()}, func() error {
+				return r.client.RaiseExecutionPriority(r.accessToken, r.realmName, *live[matchIdx].ID)
+			}); err != nil {
+				return err
+			}
+			current[j-1], current[j] = current[j], current[j-1]
+		}
+	}
+	return nil
+}
+
+// matchExecutions returns, for each desired execution, the index of the matching live execution or -1
+func matchExecutions(desired []ExecutionSpec, live []keycloak.AuthenticationExecutionInfoRepresentation) []int {
+	var res = make([]int, len(desired))
+	var used = map[int]bool{}
+	for desiredIdx, spec := range desired {
+		res[desiredIdx] = -1
+		for liveIdx, execution := range live {
+			if !used[liveIdx] && spec.matches(execution) {
+				res[desiredIdx] = liveIdx
+				used[liveIdx] = true
+				break
+			}
+		}
+	}
+	return res
+}
+
+func (es ExecutionSpec) matches(execution keycloak.AuthenticationExecutionInfoRepresentation) bool {
+	if es.Flow != nil {
+		return isTrue(execution.AuthenticationFlow) && execution.DisplayName != nil && *execution.DisplayName == es.Flow.Alias
+	}
+	return !isTrue(execution.AuthenticationFlow) && execution.ProviderID != nil && *execution.ProviderID == es.Authenticator
+}
+
+// validate checks that each execution is either an authenticator or a sub-flow and that its requirement is known
+func (fs FlowSpec) validate() error {
+	for _, spec := range fs.Executions {
+		if (spec.Authenticator == "") == (spec.Flow == nil) {
+			return errors.New(keycloak.MsgErrInvalidParam + ".execution." + fs.Alias)
+		}
+		if spec.Requirement != "" && !slices.ContainsFunc(flowRequirements, func(requirement string) bool {
+			return strings.EqualFold(requirement, spec.Requirement)
+		}) {
+			return errors.New(keycloak.MsgErrInvalidParam + ".requirement." + spec.name())
+		}
+		if spec.Flow != nil {
+			if err := spec.Flow.validate(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (fs FlowSpec) flowType() string {
+	if fs.Type == "" {
+		return defaultFlowType
+	}
+	return fs.Type
+}
+
+func (es ExecutionSpec) name() string {
+	if es.Flow != nil {
+		return es.Flow.Alias
+	}
+	return es.Authenticator
+}
+
+func executionName(execution keycloak.AuthenticationExecutionInfoRepresentation) string {
+	if isTrue(execution.AuthenticationFlow) && execution.DisplayName != nil {
+		return *execution.DisplayName
+	}
+	if execution.ProviderID != nil {
+		return *execution.ProviderID
+	}
+	if execution.DisplayName != nil {
+		return *execution.DisplayName
+	}
+	return ""
+}
+
+func containsIndex(indexes []int, value int) bool {
+	for _, idx := range indexes {
+		if idx == value {
+			return true
+		}
+	}
+	return false
+}
+
+func toAnyMap(values map[string]string) *map[string]any {
+	var res = map[string]any{}
+	for k, v := range values {
+		res[k] = v
+	}
+	return &res
+}
+
+func sameConfig(current *map[string]any, expected map[string]string) bool {
+	var currentValues = map[string]string{}
+	if current != nil {
+		for k, v := range *current {
+			currentValues[k] = fmt.Sprint(v)
+		}
+	}
+	return maps.Equal(currentValues, expected)
+}
@@ -0,0 +1,242 @@
+package toolbox
+
+import (
+	"testing"
+
+	"github.com/cloudtrust/keycloak-client/v2"
+	"github.com/cloudtrust/keycloak-client/v2/toolbox/mock"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+func createExecution(id string, providerID string, requirement string, level int32) keycloak.AuthenticationExecutionInfoRepresentation {
+	return keycloak.AuthenticationExecutionInfoRepresentation{
+		ID:          &id,
+		ProviderID:  &providerID,
+		DisplayName: &providerID,
+		Requirement: &requirement,
+		Level:       &level,
+	}
+}
+
+func createSubFlowExecution(id string, alias string, requirement string, level int32) keycloak.AuthenticationExecutionInfoRepresentation {
+	var execution = createExecution(id, "", requirement, level)
+	var isFlow = true
+	execution.ProviderID = nil
+	execution.DisplayName = &alias
+	execution.AuthenticationFlow = &isFlow
+	return execution
+}
+
+func TestFlowChangeString(t *testing.T) {
+	assert.Equal(t, "delete       flow/cookie", FlowChange{Kind: FlowChangeDelete, Path: "flow/cookie"}.String())
+	assert.Equal(t, "create       flow (basic-flow)", FlowChange{Kind: FlowChangeCreate, Path: "flow", Detail: "basic-flow"}.String())
+}
+
+func TestFlowReconciler(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	var mockClient = mock.NewAuthenticationFlowsClient(mockCtrl)
+	var reconciler = NewFlowReconciler(mockClient)
+
+	var flowAlias = "my-browser"
+	var flowID = "flow-id"
+	var existingFlows = []keycloak.AuthenticationFlowRepresentation{{ID: &flowID, Alias: &flowAlias}}
+	var spec = FlowSpec{
+		Alias: flowAlias,
+		Executions: []ExecutionSpec{
+			{Authenticator: "auth-cookie", Requirement: "ALTERNATIVE"},
+			{Flow: &FlowSpec{Alias: "my-forms", Executions: []ExecutionSpec{
+				{Authenticator: "auth-username-password-form", Requirement: "REQUIRED"},
+				{Authenticator: "auth-otp-form", Requirement: "REQUIRED", Config: &AuthenticatorConfigSpec{Alias: "otp", Config: map[string]string{"key": "value"}}},
+			}}, Requirement: "ALTERNATIVE"},
+		},
+	}
+
+	t.Run("Can't get flows", func(t *testing.T) {
+		mockClient.EXPECT().GetAuthenticationFlows(token, realm).Return(nil, errAny)
+		var _, err = reconciler.Plan(token, realm, spec)
+		assert.Equal(t, errAny, err)
+	})
+	t.Run("Plan for a new flow", func(t *testing.T) {
+		mockClient.EXPECT().GetAuthenticationFlows(token, realm).Return(nil, nil)
+		var changes, err = reconciler.Plan(token, realm, spec)
+		assert.Nil(t, err)
+		assert.Equal(t, []FlowChange{
+			{Kind: FlowChangeCreate, Path: "my-browser", Detail: "basic-flow"},
+			{Kind: FlowChangeCreate, Path: "my-browser/auth-cookie"},
+			{Kind: FlowChangeCreate, Path: "my-browser/my-forms"},
+			{Kind: FlowChangeRequirement, Path: "my-browser/auth-cookie", Detail: "DISABLED -> ALTERNATIVE"},
+			{Kind: FlowChangeRequirement, Path: "my-browser/my-forms", Detail: "DISABLED -> ALTERNATIVE"},
+			{Kind: FlowChangeCreate, Path: "my-browser/my-forms/auth-username-password-form"},
+			{Kind: FlowChangeCreate, Path: "my-browser/my-forms/auth-otp-form"},
+			{Kind: FlowChangeRequirement, Path: "my-browser/my-forms/auth-username-password-form", Detail: "DISABLED -> REQUIRED"},
+			{Kind: FlowChangeRequirement, Path: "my-browser/my-forms/auth-otp-form", Detail: "DISABLED -> REQUIRED"},
+			{Kind: FlowChangeConfigure, Path: "my-browser/my-forms/auth-otp-form", Detail: "create otp"},
+		}, changes)
+	})
+	t.Run("Create flow fails", func(t *testing.T) {
+		mockClient.EXPECT().GetAuthenticationFlows(token, realm).Return(nil, nil)
+		mockClient.EXPECT().CreateAuthenticationFlow(token, realm, gomock.Any()).Return(errAny)
+		var _, err = reconciler.Apply(token, realm, spec)
+		assert.Equal(t, errAny, err)
+	})
+	t.Run("Can't get executions", func(t *testing.T) {
+		mockClient.EXPECT().GetAuthenticationFlows(token, realm).Return(existingFlows, nil)
+		mockClient.EXPECT().GetAuthenticationExecutionsForFlow(token, realm, flowAlias).Return(nil, errAny)
+		var _, err = reconciler.Apply(token, realm, spec)
+		assert.Equal(t, errAny, err)
+	})
+	t.Run("Nothing to do", func(t *testing.T) {
+		var configID = "config-id"
+		var otp = createExecution("id-otp", "auth-otp-form", "REQUIRED", 1)
+		otp.AuthenticationConfig = &configID
+		var otpChild = createExecution("id-otp", "auth-otp-form", "REQUIRED", 0)
+		otpChild.AuthenticationConfig = &configID
+		var forms = createSubFlowExecution("id-forms", "my-forms", "ALTERNATIVE", 0)
+		forms.FlowID = ptr("forms-id")
+		mockClient.EXPECT().GetAuthenticationFlows(token, realm).Return(existingFlows, nil)
+		mockClient.EXPECT().GetAuthenticationExecutionsForFlow(token, realm, flowAlias).Return([]keycloak.AuthenticationExecutionInfoRepresentation{
+			createExecution("id-cookie", "auth-cookie", "ALTERNATIVE", 0),
+			forms,
+			createExecution("id-upf", "auth-username-password-form", "REQUIRED", 1),
+			otp,
+		}, nil)
+		mockClient.EXPECT().GetAuthenticationFlow(token, realm, "forms-id").Return(keycloak.AuthenticationFlowRepresentation{
+			ID:         ptr("forms-id"),
+			Alias:      ptr("my-forms"),
+			ProviderID: ptr("basic-flow"),
+		}, nil)
+		mockClient.EXPECT().GetAuthenticationExecutionsForFlow(token, realm, "my-forms").Return([]keycloak.AuthenticationExecutionInfoRepresentation{
+			createExecution("id-upf", "auth-username-password-form", "REQUIRED", 0),
+			otpChild,
+		}, nil)
+		mockClient.EXPECT().GetAuthenticatorConfig(token, realm, configID).Return(keycloak.AuthenticatorConfigRepresentation{
+			ID:     &configID,
+			Alias:  ptr("otp"),
+			Config: &map[string]any{"key": "value"},
+		}, nil)
+		var changes, err = reconciler.Apply(token, realm, spec)
+		assert.Nil(t, err)
+		assert.Len(t, changes, 0)
+	})
+	t.Run("Apply changes", func(t *testing.T) {
+		var configID = "config-id"
+		var subFlow = FlowSpec{Alias: "sub", Executions: []ExecutionSpec{
+			{Authenticator: "auth-a", Requirement: "REQUIRED"},
+			{Authenticator: "auth-b", Config: &AuthenticatorConfigSpec{Alias: "cfg-b", Config: map[string]string{"key": "new"}}},
+			{Authenticator: "auth-c", Requirement: "REQUIRED"},
+		}}
+		var execB = createExecution("id-b", "auth-b", "DISABLED", 0)
+		execB.AuthenticationConfig = &configID
+
+		mockClient.EXPECT().GetAuthenticationFlows(token, realm).Return([]keycloak.AuthenticationFlowRepresentation{{Alias: ptr("sub")}}, nil)
+		gomock.InOrder(
+			mockClient.EXPECT().GetAuthenticationExecutionsForFlow(token, realm, "sub").Return([]keycloak.AuthenticationExecutionInfoRepresentation{
+				createExecution("id-old", "auth-old", "REQUIRED", 0),
+				createExecution("id-b", "auth-b", "DISABLED", 0),
+				createExecution("id-a", "auth-a", "REQUIRED", 0),
+			}, nil),
+			mockClient.EXPECT().DeleteAuthenticationExecution(token, realm, "id-old").Return(nil),
+			mockClient.EXPECT().CreateAuthenticationExecutionForFlow(token, realm, "sub", "auth-c").Return("", nil),
+			mockClient.EXPECT().GetAuthenticationExecutionsForFlow(token, realm, "sub").Return([]keycloak.AuthenticationExecutionInfoRepresentation{
+				execB,
+				createExecution("id-a", "auth-a", "REQUIRED", 0),
+				createExecution("id-c", "auth-c", "DISABLED", 0),
+			}, nil),
+		)
+		mockClient.EXPECT().GetAuthenticatorConfig(token, realm, configID).Return(keycloak.AuthenticatorConfigRepresentation{
+			ID:     &configID,
+			Alias:  ptr("cfg-b"),
+			Config: &map[string]any{"key": "old"},
+		}, nil)
+		mockClient.EXPECT().UpdateAuthenticatorConfig(token, realm, configID, gomock.Any()).Return(nil)
+		mockClient.EXPECT().UpdateAuthenticationExecutionForFlow(token, realm, "sub", gomock.Any()).DoAndReturn(
+			func(_, _, _ string, execution keycloak.AuthenticationExecutionInfoRepresentation) error {
+				assert.Equal(t, "id-c", *execution.ID)
+				assert.Equal(t, "REQUIRED", *execution.Requirement)
+				return nil
+			})
+		mockClient.EXPECT().RaiseExecutionPriority(token, realm, "id-a").Return(nil)
+
+		var changes, err = reconciler.Apply(token, realm, subFlow)
+		assert.Nil(t, err)
+		assert.Equal(t, []FlowChange{
+			{Kind: FlowChangeDelete, Path: "sub/auth-old"},
+			{Kind: FlowChangeCreate, Path: "sub/auth-c"},
+			{Kind: FlowChangeConfigure, Path: "sub/auth-b", Detail: "update cfg-b"},
+			{Kind: FlowChangeRequirement, Path: "sub/auth-c", Detail: "DISABLED -> REQUIRED"},
+			{Kind: FlowChangeRaise, Path: "sub/auth-a"},
+		}, changes)
+	})
+	t.Run("Sub-flow type and description", func(t *testing.T) {
+		var subFlows = FlowSpec{Alias: flowAlias, Executions: []ExecutionSpec{
+			{Flow: &FlowSpec{Alias: "forms", Type: "form-flow"}},
+			{Flow: &FlowSpec{Alias: "other", Description: "New"}},
+		}}
+		var forms = createSubFlowExecution("id-forms", "forms", "REQUIRED", 0)
+		forms.FlowID = ptr("forms-id")
+		var other = createSubFlowExecution("id-other", "other", "REQUIRED", 0)
+		other.FlowID = ptr("other-id")
+		mockClient.EXPECT().GetAuthenticationFlows(token, realm).Return(existingFlows, nil)
+		mockClient.EXPECT().GetAuthenticationExecutionsForFlow(token, realm, flowAlias).Return([]keycloak.AuthenticationExecutionInfoRepresentation{forms, other}, nil)
+		mockClient.EXPECT().GetAuthenticationFlow(token, realm, "forms-id").Return(keycloak.AuthenticationFlowRepresentation{
+			ID: ptr("forms-id"), Alias: ptr("forms"), ProviderID: ptr("basic-flow"),
+		}, nil)
+		mockClient.EXPECT().GetAuthenticationFlow(token, realm, "other-id").Return(keycloak.AuthenticationFlowRepresentation{
+			ID: ptr("other-id"), Alias: ptr("other"), ProviderID: ptr("basic-flow"), Description: ptr("Old"),
+		}, nil)
+		mockClient.EXPECT().GetAuthenticationExecutionsForFlow(token, realm, "other").Return(nil, nil)
+
+		var changes, err = reconciler.Plan(token, realm, subFlows)
+		assert.Nil(t, err)
+		assert.Equal(t, []FlowChange{
+			{Kind: FlowChangeDescribe, Path: "my-browser/other"},
+			{Kind: FlowChangeDelete, Path: "my-browser/forms"},
+			{Kind: FlowChangeCreate, Path: "my-browser/forms"},
+			{Kind: FlowChangeRaise, Path: "my-browser/forms"},
+		}, changes)
+	})
+	t.Run("Update the description", func(t *testing.T) {
+		var flows = []keycloak.AuthenticationFlowRepresentation{{ID: &flowID, Alias: &flowAlias, Description: ptr("Old")}}
+		mockClient.EXPECT().GetAuthenticationFlows(token, realm).Return(flows, nil)
+		mockClient.EXPECT().UpdateAuthenticationFlow(token, realm, flowID, gomock.Any()).DoAndReturn(
+			func(_, _, _ string, flow keycloak.AuthenticationFlowRepresentation) error {
+				assert.Equal(t, flowAlias, *flow.Alias)
+				assert.Equal(t, "New", *flow.Description)
+				return nil
+			})
+		mockClient.EXPECT().GetAuthenticationExecutionsForFlow(token, realm, flowAlias).Return(nil, nil)
+		var changes, err = reconciler.Apply(token, realm, FlowSpec{Alias: flowAlias, Description: "New"})
+		assert.Nil(t, err)
+		assert.Equal(t, []FlowChange{{Kind: FlowChangeDescribe, Path: flowAlias}}, changes)
+	})
+	t.Run("Invalid description", func(t *testing.T) {
+		for _, executions := range [][]ExecutionSpec{
+			{{}},
+			{{Authenticator: "auth-a", Flow: &FlowSpec{Alias: "sub"}}},
+			{{Authenticator: "auth-a", Requirement: "OPTIONAL"}},
+			{{Flow: &FlowSpec{Alias: "sub", Executions: []ExecutionSpec{{Authenticator: "auth-a", Requirement: "unknown"}}}}},
+		} {
+			var _, err = reconciler.Plan(token, realm, FlowSpec{Alias: flowAlias, Executions: executions})
+			assert.NotNil(t, err)
+		}
+	})
+	t.Run("Created execution is not found", func(t *testing.T) {
+		var subFlow = FlowSpec{Alias: "sub", Executions: []ExecutionSpec{{Authenticator: "auth-a"}}}
+		mockClient.EXPECT().GetAuthenticationFlows(token, realm).Return([]keycloak.AuthenticationFlowRepresentation{{Alias: ptr("sub")}}, nil)
+		mockClient.EXPECT().GetAuthenticationExecutionsForFlow(token, realm, "sub").Return(nil, nil).Times(2)
+		mockClient.EXPECT().CreateAuthenticationExecutionForFlow(token, realm, "sub", "auth-a").Return("", nil)
+		var _, err = reconciler.Apply(token, realm, subFlow)
+		assert.NotNil(t, err)
+	})
+	t.Run("Missing execution", func(t *testing.T) {
+		var reconciliation = &flowReconciliation{client: mockClient, accessToken: token, realmName: realm}
+		var spec = ExecutionSpec{Authenticator: "auth-a", Requirement: "REQUIRED", Config: &AuthenticatorConfigSpec{Alias: "cfg-a"}}
+		var err = reconciliation.reconcileRequirement("sub/auth-a", "sub", spec, nil)
+		assert.Equal(t, keycloak.MsgErrCannotObtain+".execution.auth-a", err.Error())
+		err = reconciliation.reconcileConfig("sub/auth-a", spec, nil)
+		assert.Equal(t, keycloak.MsgErrCannotObtain+".execution.auth-a", err.Error())
+	})
+}
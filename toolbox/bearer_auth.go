@@ -0,0 +1,249 @@
+package toolbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/cloudtrust/keycloak-client/v2"
+	"github.com/coreos/go-oidc/v3/oidc"
+	kit_endpoint "github.com/go-kit/kit/endpoint"
+	http_transport "github.com/go-kit/kit/transport/http"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Error codes of the bearer token authentication (RFC 6750)
+const (
+	BearerErrInvalidRequest    = "invalid_request"
+	BearerErrInvalidToken      = "invalid_token"
+	BearerErrInsufficientScope = "insufficient_scope"
+	BearerErrInvalidDPoPProof  = "invalid_dpop_proof" // RFC 9449
+)
+
+type contextKey int
+
+const (
+	contextKeyTokenClaims contextKey = iota
+	contextKeyAccessToken
+)
+
+// AuthRequirements are the scopes and roles required by a route in addition to a valid token
+type AuthRequirements struct {
+	Scopes      []string
+	RealmRoles  []string
+	ClientRoles map[string][]string
+}
+
+// BearerAuthError is returned when a request can't be authenticated. It implements the StatusCoder and Headerer
+// interfaces of the go-kit HTTP transport so that the default error encoder writes the expected response.
+type BearerAuthError struct {
+	Scheme      string // Scheme of the challenge, Bearer when empty
+	Status      int
+	Code        string // Empty when the request has no token
+	Description string
+	Realm       string
+	Scope       string // Scopes required when Code is insufficient_scope
+}
+
+func (e BearerAuthError) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("%d:%s", e.Status, http.StatusText(e.Status))
+	}
+	return fmt.Sprintf("%d:%s", e.Status, e.Code)
+}
+
+// StatusCode implements go-kit StatusCoder
+func (e BearerAuthError) StatusCode() int {
+	return e.Status
+}
+
+// Headers implements go-kit Headerer and returns the WWW-Authenticate challenge of the error
+func (e BearerAuthError) Headers() http.Header {
+	if e.Status == http.StatusServiceUnavailable {
+		return http.Header{}
+	}
+	var params []string
+	for _, param := range [][2]string{
+		{"realm", e.Realm},
+		{"error", e.Code},
+		{"error_description", e.Description},
+		{"scope", e.Scope},
+	} {
+		if param[1] != "" {
+			params = append(params, fmt.Sprintf(`%s="%s"`, param[0], strings.ReplaceAll(param[1], `"`, "'")))
+		}
+	}
+	var challenge = e.Scheme
+	if challenge == "" {
+		challenge = "Bearer"
+	}
+	if len(params) > 0 {
+		challenge += " " + strings.Join(params, ", ")
+	}
+	var res = http.Header{}
+	res.Set("WWW-Authenticate", challenge)
+	return res
+}
+
+// ContextWithTokenClaims returns a context containing the claims of the authenticated token
+func ContextWithTokenClaims(ctx context.Context, claims *TokenClaims) context.Context {
+	return context.WithValue(ctx, contextKeyTokenClaims, claims)
+}
+
+// TokenClaimsFromContext returns the claims stored by the bearer authentication middlewares
+func TokenClaimsFromContext(ctx context.Context) (*TokenClaims, bool) {
+	var claims, ok = ctx.Value(contextKeyTokenClaims).(*TokenClaims)
+	return claims, ok
+}
+
+// AccessTokenFromContext returns the access token authenticated by the bearer authentication middlewares
+func AccessTokenFromContext(ctx context.Context) (string, bool) {
+	var accessToken, ok = ctx.Value(contextKeyAccessToken).(string)
+	return accessToken, ok
+}
+
+// HTTPBearerAuthOptions tells MakeHTTPBearerAuthMiddlewareWithOptions how to get the URL of the requests as seen by
+// the clients, which must be the htu of the DPoP proofs. By default, the Host header and the TLS state of the request
+// are used.
+type HTTPBearerAuthOptions struct {
+	PublicURL      *url.URL // Scheme and host of the service as seen by the clients, for instance behind a proxy
+	TrustForwarded bool     // Use the host and protocol of the Forwarded header. Only set it when a proxy overwrites this header
+}
+
+// MakeHTTPBearerAuthMiddleware creates a middleware which verifies the bearer token of the requests and stores its
+// claims in the request context. Requests are rejected with a RFC 6750 challenge when the token is missing or invalid,
+// or when it does not grant the requirements. DPoP-bound tokens are accepted with the DPoP scheme and the DPoP proof
+// of the request (RFC 9449).
+func MakeHTTPBearerAuthMiddleware(issuerManager IssuerManager, requirements AuthRequirements) func(http.Handler) http.Handler {
+	return MakeHTTPBearerAuthMiddlewareWithOptions(issuerManager, requirements, HTTPBearerAuthOptions{})
+}
+
+// MakeHTTPBearerAuthMiddlewareWithOptions creates a middleware like MakeHTTPBearerAuthMiddleware whose DPoP proofs are
+// checked against the public URL given by options
+func MakeHTTPBearerAuthMiddlewareWithOptions(issuerManager IssuerManager, requirements AuthRequirements, options HTTPBearerAuthOptions) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			var dpop = &dpopRequest{
+				proofs: req.Header.Values(DPoPHeader),
+				method: req.Method,
+				url:    incomingRequestURL(req, options),
+			}
+			var ctx, err = authenticateBearer(req.Context(), issuerManager, requirements, req.Header.Values("Authorization"), dpop)
+			if err != nil {
+				for name, values := range err.Headers() {
+					for _, value := range values {
+						w.Header().Add(name, value)
+					}
+				}
+				http.Error(w, err.Error(), err.StatusCode())
+				return
+			}
+			next.ServeHTTP(w, req.WithContext(ctx))
+		})
+	}
+}
+
+// MakeEndpointBearerAuthMiddleware creates a go-kit endpoint middleware which verifies the bearer token found in the
+// context by http_transport.PopulateRequestContext and stores its claims in the context. The returned errors are
+// BearerAuthError. DPoP-bound tokens are rejected as the proofs are not part of the context, MakeHTTPBearerAuthMiddleware
+// must be used to accept them.
+func MakeEndpointBearerAuthMiddleware(issuerManager IssuerManager, requirements AuthRequirements) kit_endpoint.Middleware {
+	return func(next kit_endpoint.Endpoint) kit_endpoint.Endpoint {
+		return func(ctx context.Context, request any) (any, error) {
+			var authorization []string
+			if value, _ := ctx.Value(http_transport.ContextKeyRequestAuthorization).(string); value != "" {
+				authorization = []string{value}
+			}
+			var authCtx, err = authenticateBearer(ctx, issuerManager, requirements, authorization, nil)
+			if err != nil {
+				return nil, *err
+			}
+			return next(authCtx, request)
+		}
+	}
+}
+
+// dpopRequest contains the DPoP proofs of a request and the method and URL they must be created for
+type dpopRequest struct {
+	proofs []string
+	method string
+	url    string
+}
+
+// authenticateBearer verifies the token of the Authorization header. dpop is nil when DPoP-bound tokens can't be
+// verified.
+func authenticateBearer(ctx context.Context, issuerManager IssuerManager, requirements AuthRequirements, authorization []string, dpop *dpopRequest) (context.Context, *BearerAuthError) {
+	if len(authorization) == 0 {
+		return nil, &BearerAuthError{Status: http.StatusUnauthorized}
+	}
+	var scheme, accessToken, found = strings.Cut(authorization[0], " ")
+	var isDPoP = dpop != nil && strings.EqualFold(scheme, TokenTypeDPoP)
+	if len(authorization) > 1 || !found || !(strings.EqualFold(scheme, "Bearer") || isDPoP) || accessToken == "" {
+		return nil, &BearerAuthError{Status: http.StatusBadRequest, Code: BearerErrInvalidRequest, Description: "Bearer token expected"}
+	}
+	scheme = "Bearer"
+	if isDPoP {
+		scheme = TokenTypeDPoP
+		if len(dpop.proofs) != 1 {
+			return nil, &BearerAuthError{Scheme: scheme, Status: http.StatusUnauthorized, Code: BearerErrInvalidDPoPProof, Description: "One DPoP proof expected"}
+		}
+	}
+
+	var issuer string
+	if token, _, err := jwt.NewParser().ParseUnverified(accessToken, jwt.MapClaims{}); err == nil {
+		issuer, _ = token.Claims.GetIssuer()
+	}
+	if issuer == "" {
+		return nil, &BearerAuthError{Status: http.StatusUnauthorized, Code: BearerErrInvalidToken, Description: "Malformed token"}
+	}
+	var _, realm = SplitIssuer(issuer)
+	var invalidToken = func(description string) *BearerAuthError {
+		return &BearerAuthError{Scheme: scheme, Status: http.StatusUnauthorized, Code: BearerErrInvalidToken, Description: description, Realm: realm}
+	}
+
+	var verifierProvider, err = issuerManager.GetOidcVerifierProvider(issuer)
+	if err != nil {
+		return nil, invalidToken("Unknown issuer")
+	}
+	var verifier OidcVerifier
+	if verifier, err = verifierProvider.GetOidcVerifier(realm); err != nil {
+		// Keycloak can't be reached: the token may be valid
+		return nil, &BearerAuthError{Scheme: scheme, Status: http.StatusServiceUnavailable, Realm: realm}
+	}
+
+	var claims *TokenClaims
+	if isDPoP {
+		claims, err = verifier.VerifyDPoP(accessToken, dpop.proofs[0], dpop.method, dpop.url)
+	} else {
+		claims, err = verifier.VerifyAndExtract(accessToken)
+	}
+	if err == nil {
+		err = VerifierOptions{
+			RequiredScopes:      requirements.Scopes,
+			RequiredRealmRoles:  requirements.RealmRoles,
+			RequiredClientRoles: requirements.ClientRoles,
+		}.checkClaims(claims)
+	}
+	var detailedErr keycloak.ClientDetailedError
+	var expiredErr *oidc.TokenExpiredError
+	switch {
+	case err == nil:
+	case errors.As(err, &detailedErr) && detailedErr.Status() == http.StatusForbidden:
+		return nil, &BearerAuthError{Scheme: scheme, Status: http.StatusForbidden, Code: BearerErrInsufficientScope, Description: detailedErr.Message,
+			Realm: realm, Scope: strings.Join(requirements.Scopes, " ")}
+	case errors.As(err, &detailedErr) && strings.HasPrefix(detailedErr.Message, keycloak.MsgErrInvalidToken+".dpop"):
+		return nil, &BearerAuthError{Scheme: scheme, Status: http.StatusUnauthorized, Code: BearerErrInvalidDPoPProof, Description: "Invalid DPoP proof", Realm: realm}
+	case errors.As(err, &detailedErr) && detailedErr.Message == keycloak.MsgErrInvalidToken+".cnf":
+		return nil, invalidToken("Token not bound to the DPoP proof")
+	case errors.As(err, &expiredErr):
+		return nil, invalidToken("Token expired")
+	default:
+		return nil, invalidToken("Token verification failed")
+	}
+
+	ctx = ContextWithTokenClaims(ctx, claims)
+	return context.WithValue(ctx, contextKeyAccessToken, accessToken), nil
+}
@@ -0,0 +1,221 @@
+package toolbox
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/cloudtrust/keycloak-client/v2"
+	http_transport "github.com/go-kit/kit/transport/http"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBearerAuthError(t *testing.T) {
+	assert.Equal(t, "401:Unauthorized", BearerAuthError{Status: http.StatusUnauthorized}.Error())
+	assert.Equal(t, "Bearer", BearerAuthError{Status: http.StatusUnauthorized}.Headers().Get("WWW-Authenticate"))
+
+	var err = BearerAuthError{Status: http.StatusForbidden, Code: BearerErrInsufficientScope, Description: `missing "write"`, Realm: "my-realm", Scope: "read write"}
+	assert.Equal(t, "403:insufficient_scope", err.Error())
+	assert.Equal(t, http.StatusForbidden, err.StatusCode())
+	assert.Equal(t, `Bearer realm="my-realm", error="insufficient_scope", error_description="missing 'write'", scope="read write"`,
+		err.Headers().Get("WWW-Authenticate"))
+
+	assert.Empty(t, BearerAuthError{Status: http.StatusServiceUnavailable}.Headers())
+}
+
+func TestHTTPBearerAuthMiddleware(t *testing.T) {
+	var keycloakServer = newSigningKeycloak("https://public.domain.ch")
+	var ts = httptest.NewServer(keycloakServer)
+	defer ts.Close()
+
+	var uriProvider, _ = NewKeycloakURIProviderFromArray([]string{keycloakServer.externalURL})
+	var issuerManager, err = NewIssuerManager(keycloak.Config{URIProvider: uriProvider, AddrInternalAPI: ts.URL})
+	assert.Nil(t, err)
+
+	var handlerClaims *TokenClaims
+	var handlerToken string
+	var handler = MakeHTTPBearerAuthMiddleware(issuerManager, AuthRequirements{Scopes: []string{"read"}, RealmRoles: []string{"user"}})(
+		http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			handlerClaims, _ = TokenClaimsFromContext(req.Context())
+			handlerToken, _ = AccessTokenFromContext(req.Context())
+		}))
+	var serve = func(authorization ...string) *httptest.ResponseRecorder {
+		var req = httptest.NewRequest(http.MethodGet, "/resource", nil)
+		for _, value := range authorization {
+			req.Header.Add("Authorization", value)
+		}
+		var rec = httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+	var validClaims = jwt.MapClaims{"scope": "openid read", "realm_access": map[string]any{"roles": []string{"user"}}}
+
+	t.Run("Valid token", func(t *testing.T) {
+		var token = keycloakServer.sign("my-realm", validClaims)
+		var rec = serve("Bearer " + token)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "my-realm", handlerClaims.Realm)
+		assert.Equal(t, token, handlerToken)
+	})
+	t.Run("Missing token", func(t *testing.T) {
+		var rec = serve()
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+		assert.Equal(t, "Bearer", rec.Header().Get("WWW-Authenticate"))
+	})
+	t.Run("Invalid requests", func(t *testing.T) {
+		for _, authorization := range [][]string{{"Basic dXNlcjpwYXNzd2Q="}, {"Bearer"}, {"Bearer a", "Bearer b"}} {
+			var rec = serve(authorization...)
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+			assert.Contains(t, rec.Header().Get("WWW-Authenticate"), `error="invalid_request"`)
+		}
+	})
+	t.Run("Invalid tokens", func(t *testing.T) {
+		var other = newSigningKeycloak("https://other.domain.ch")
+		for name, token := range map[string]string{
+			"Malformed":       "not.a.token",
+			"Unknown issuer":  other.sign("my-realm", validClaims),
+			"Other signature": generateJWT(keycloakServer.externalURL + "/auth/realms/my-realm"),
+		} {
+			t.Run(name, func(t *testing.T) {
+				var rec = serve("Bearer " + token)
+				assert.Equal(t, http.StatusUnauthorized, rec.Code)
+				assert.Contains(t, rec.Header().Get("WWW-Authenticate"), `error="invalid_token"`)
+			})
+		}
+	})
+	t.Run("Expired token", func(t *testing.T) {
+		var rec = serve("Bearer " + keycloakServer.sign("my-realm", jwt.MapClaims{"exp": time.Now().Add(-time.Minute).Unix()}))
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+		assert.Equal(t, `Bearer realm="my-realm", error="invalid_token", error_description="Token expired"`, rec.Header().Get("WWW-Authenticate"))
+	})
+	t.Run("Insufficient scope", func(t *testing.T) {
+		var rec = serve("Bearer " + keycloakServer.sign("my-realm", jwt.MapClaims{"scope": "openid"}))
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+		assert.Contains(t, rec.Header().Get("WWW-Authenticate"), `error="insufficient_scope"`)
+		assert.Contains(t, rec.Header().Get("WWW-Authenticate"), `scope="read"`)
+	})
+	t.Run("DPoP-bound token", func(t *testing.T) {
+		var signer, _ = GenerateDPoPSigner()
+		var claims = jwt.MapClaims{"cnf": map[string]any{"jkt": signer.Thumbprint()}}
+		for name, value := range validClaims {
+			claims[name] = value
+		}
+		var token = keycloakServer.sign("my-realm", claims)
+		var proxiedHandler = MakeHTTPBearerAuthMiddlewareWithOptions(issuerManager, AuthRequirements{}, HTTPBearerAuthOptions{TrustForwarded: true})(
+			http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				handlerClaims, _ = TokenClaimsFromContext(req.Context())
+			}))
+		var serveDPoP = func(method string, authorization string, proofs ...string) *httptest.ResponseRecorder {
+			var req = httptest.NewRequest(method, "/resource?page=2", nil)
+			req.Header.Set("Authorization", authorization)
+			req.Header.Set("Forwarded", "host=api.domain.ch;proto=https")
+			for _, proof := range proofs {
+				req.Header.Add(DPoPHeader, proof)
+			}
+			var rec = httptest.NewRecorder()
+			proxiedHandler.ServeHTTP(rec, req)
+			return rec
+		}
+		var proof, _ = signer.CreateProof(http.MethodGet, "https://api.domain.ch/resource", token, "")
+
+		// The Forwarded header is ignored by default: the proof is checked against the Host header
+		var req = httptest.NewRequest(http.MethodGet, "/resource", nil)
+		req.Header.Set("Authorization", "DPoP "+token)
+		req.Header.Set("Forwarded", "host=api.domain.ch;proto=https")
+		req.Header.Set(DPoPHeader, proof)
+		var rec = httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+		assert.Contains(t, rec.Header().Get("WWW-Authenticate"), `error="invalid_dpop_proof"`)
+
+		// The token can't be used without its proof
+		rec = serve("Bearer " + token)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+		assert.Contains(t, rec.Header().Get("WWW-Authenticate"), `error="invalid_token"`)
+
+		rec = serveDPoP(http.MethodGet, "DPoP "+token)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+		assert.Equal(t, `DPoP error="invalid_dpop_proof", error_description="One DPoP proof expected"`, rec.Header().Get("WWW-Authenticate"))
+
+		rec = serveDPoP(http.MethodPost, "DPoP "+token, proof)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+		assert.Contains(t, rec.Header().Get("WWW-Authenticate"), `error="invalid_dpop_proof"`)
+
+		handlerClaims = nil
+		rec = serveDPoP(http.MethodGet, "DPoP "+token, proof)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, signer.Thumbprint(), handlerClaims.ConfirmationThumbprint)
+
+		// Proofs can't be replayed
+		rec = serveDPoP(http.MethodGet, "DPoP "+token, proof)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	})
+	t.Run("DPoP proof of a public URL", func(t *testing.T) {
+		var signer, _ = GenerateDPoPSigner()
+		var token = keycloakServer.sign("my-realm", jwt.MapClaims{"cnf": map[string]any{"jkt": signer.Thumbprint()}})
+		var publicURL, _ = url.Parse("https://api.domain.ch")
+		var publicHandler = MakeHTTPBearerAuthMiddlewareWithOptions(issuerManager, AuthRequirements{}, HTTPBearerAuthOptions{PublicURL: publicURL})(
+			http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {}))
+		var serveProof = func(forwarded string, proofURL string) int {
+			var proof, _ = signer.CreateProof(http.MethodGet, proofURL, token, "")
+			var req = httptest.NewRequest(http.MethodGet, "/resource", nil)
+			req.Header.Set("Authorization", "DPoP "+token)
+			req.Header.Set("Forwarded", forwarded)
+			req.Header.Set(DPoPHeader, proof)
+			var rec = httptest.NewRecorder()
+			publicHandler.ServeHTTP(rec, req)
+			return rec.Code
+		}
+		assert.Equal(t, http.StatusOK, serveProof("", "https://api.domain.ch/resource"))
+		// A client can't make the proof of another service valid with a Forwarded header
+		assert.Equal(t, http.StatusUnauthorized, serveProof("host=other.domain.ch;proto=https", "https://other.domain.ch/resource"))
+	})
+	t.Run("Keycloak unavailable", func(t *testing.T) {
+		keycloakServer.unavailable.Store(true)
+		defer keycloakServer.unavailable.Store(false)
+		var rec = serve("Bearer " + keycloakServer.sign("new-realm", validClaims))
+		assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+		assert.Empty(t, rec.Header().Get("WWW-Authenticate"))
+	})
+}
+
+func TestEndpointBearerAuthMiddleware(t *testing.T) {
+	var keycloakServer = newSigningKeycloak("https://public.domain.ch")
+	var ts = httptest.NewServer(keycloakServer)
+	defer ts.Close()
+
+	var uriProvider, _ = NewKeycloakURIProviderFromArray([]string{keycloakServer.externalURL})
+	var issuerManager, _ = NewIssuerManager(keycloak.Config{URIProvider: uriProvider, AddrInternalAPI: ts.URL})
+	var authEndpoint = MakeEndpointBearerAuthMiddleware(issuerManager, AuthRequirements{ClientRoles: map[string][]string{"backend": {"reader"}}})(
+		func(ctx context.Context, request any) (any, error) {
+			var claims, _ = TokenClaimsFromContext(ctx)
+			return claims.Subject, nil
+		})
+
+	t.Run("Valid token", func(t *testing.T) {
+		var token = keycloakServer.sign("my-realm", jwt.MapClaims{"resource_access": map[string]any{"backend": map[string]any{"roles": []string{"reader"}}}})
+		var ctx = context.WithValue(context.TODO(), http_transport.ContextKeyRequestAuthorization, "Bearer "+token)
+		var res, err = authEndpoint(ctx, nil)
+		assert.Nil(t, err)
+		assert.Equal(t, "1234", res)
+	})
+	t.Run("Missing role", func(t *testing.T) {
+		var ctx = context.WithValue(context.TODO(), http_transport.ContextKeyRequestAuthorization, "Bearer "+keycloakServer.sign("my-realm", nil))
+		var _, err = authEndpoint(ctx, nil)
+		assert.IsType(t, BearerAuthError{}, err)
+		assert.Equal(t, http.StatusForbidden, err.(BearerAuthError).StatusCode())
+	})
+	t.Run("DPoP scheme", func(t *testing.T) {
+		var ctx = context.WithValue(context.TODO(), http_transport.ContextKeyRequestAuthorization, "DPoP "+keycloakServer.sign("my-realm", nil))
+		var _, err = authEndpoint(ctx, nil)
+		assert.Equal(t, http.StatusBadRequest, err.(BearerAuthError).StatusCode())
+	})
+	t.Run("Missing token", func(t *testing.T) {
+		var _, err = authEndpoint(context.TODO(), nil)
+		assert.Equal(t, BearerAuthError{Status: http.StatusUnauthorized}, err)
+	})
+}
@@ -0,0 +1,119 @@
+package toolbox
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/cloudtrust/keycloak-client/v2"
+)
+
+const (
+	defaultBruteForcePageSize = 100
+)
+
+// BruteForceClient is the part of the Keycloak client used to inspect brute force detection
+type BruteForceClient interface {
+	GetRealm(accessToken string, realmName string) (keycloak.RealmRepresentation, error)
+	GetUsers(accessToken string, reqRealmName, targetRealmName string, paramKV ...string) (keycloak.UsersPageRepresentation, error)
+	GetBruteForceStatus(accessToken string, realmName, userID string) (keycloak.BruteForceStatusRepresentation, error)
+	ClearUserLoginFailures(accessToken string, realmName, userID string) error
+}
+
+// LockedUser is a user temporarily locked by the brute force detection
+type LockedUser struct {
+	User   keycloak.UserRepresentation
+	Status keycloak.BruteForceStatusRepresentation
+	// UnlockIn is the remaining time before the user is unlocked. Nil when it can't be computed or when the lockout is permanent
+	UnlockIn *time.Duration
+	// Cleared is true when the login failures of the user have been cleared
+	Cleared bool
+}
+
+// BruteForceScanOptions configures ScanLockedUsers
+type BruteForceScanOptions struct {
+	PageSize      int  // Number of users requested per page. Defaults to 100
+	ClearFailures bool // Clear the login failures of the locked users
+}
+
+// BruteForceScanner looks for users locked by the brute force detection
+type BruteForceScanner struct {
+	client BruteForceClient
+	now    func() time.Time
+}
+
+// NewBruteForceScanner creates a BruteForceScanner
+func NewBruteForceScanner(client BruteForceClient) *BruteForceScanner {
+	return &BruteForceScanner{
+		client: client,
+		now:    time.Now,
+	}
+}
+
+// ScanLockedUsers browses all the users of the target realm page by page and returns those which are currently locked
+// by the brute force detection. When requested, login failures of the locked users are cleared.
+func (s *BruteForceScanner) ScanLockedUsers(accessToken string, reqRealmName string, targetRealmName string, options BruteForceScanOptions) ([]LockedUser, error) {
+	var realm, err = s.client.GetRealm(accessToken, targetRealmName)
+	if err != nil {
+		return nil, err
+	}
+	if realm.BruteForceProtected == nil || !*realm.BruteForceProtected {
+		return nil, nil
+	}
+
+	var pageSize = options.PageSize
+	if pageSize <= 0 {
+		pageSize = defaultBruteForcePageSize
+	}
+
+	var res []LockedUser
+	for first := 0; ; first += pageSize {
+		var page keycloak.UsersPageRepresentation
+		page, err = s.client.GetUsers(accessToken, reqRealmName, targetRealmName, "first", strconv.Itoa(first), "max", strconv.Itoa(pageSize))
+		if err != nil {
+			return res, err
+		}
+		for _, user := range page.Users {
+			if user.ID == nil {
+				continue
+			}
+			var status keycloak.BruteForceStatusRepresentation
+			if status, err = s.client.GetBruteForceStatus(accessToken, targetRealmName, *user.ID); err != nil {
+				return res, err
+			}
+			if status.Disabled == nil || !*status.Disabled {
+				continue
+			}
+			var locked = LockedUser{
+				User:     user,
+				Status:   status,
+				UnlockIn: s.getUnlockDelay(realm, status),
+			}
+			if options.ClearFailures {
+				if err = s.client.ClearUserLoginFailures(accessToken, targetRealmName, *user.ID); err != nil {
+					return res, err
+				}
+				locked.Cleared = true
+			}
+			res = append(res, locked)
+		}
+		if len(page.Users) < pageSize || (page.Count != nil && first+pageSize >= *page.Count) {
+			return res, nil
+		}
+	}
+}
+
+// getUnlockDelay computes the remaining lock time the same way Keycloak does: the wait time grows by
+// waitIncrementSeconds every failureFactor failures, up to maxFailureWaitSeconds. Users permanently locked out are never
+// unlocked automatically
+func (s *BruteForceScanner) getUnlockDelay(realm keycloak.RealmRepresentation, status keycloak.BruteForceStatusRepresentation) *time.Duration {
+	if (realm.PermanentLockout != nil && *realm.PermanentLockout) || realm.FailureFactor == nil || *realm.FailureFactor <= 0 || realm.WaitIncrementSeconds == nil || status.NumFailures == nil || status.LastFailure == nil {
+		return nil
+	}
+	var waitSeconds = int64(*realm.WaitIncrementSeconds) * int64(*status.NumFailures/(*realm.FailureFactor))
+	if realm.MaxFailureWaitSeconds != nil && waitSeconds > int64(*realm.MaxFailureWaitSeconds) {
+		waitSeconds = int64(*realm.MaxFailureWaitSeconds)
+	}
+	var unlockAt = time.UnixMilli(*status.LastFailure).Add(time.Duration(waitSeconds) * time.Second)
+	var delay = max(unlockAt.Sub(s.now()), 0)
+	return &delay
+}
@@ -0,0 +1,118 @@
+package toolbox
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cloudtrust/keycloak-client/v2"
+	"github.com/cloudtrust/keycloak-client/v2/toolbox/mock"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+func createBruteForceStatus(disabled bool, numFailures int32, lastFailure time.Time) keycloak.BruteForceStatusRepresentation {
+	var lastFailureMillis = lastFailure.UnixMilli()
+	return keycloak.BruteForceStatusRepresentation{
+		Disabled:    &disabled,
+		NumFailures: &numFailures,
+		LastFailure: &lastFailureMillis,
+	}
+}
+
+func createUsers(ids ...string) []keycloak.UserRepresentation {
+	var res []keycloak.UserRepresentation
+	for _, id := range ids {
+		res = append(res, keycloak.UserRepresentation{ID: ptr(id), Username: ptr("user-" + id)})
+	}
+	return res
+}
+
+func TestScanLockedUsers(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	var mockClient = mock.NewBruteForceClient(mockCtrl)
+	var scanner = NewBruteForceScanner(mockClient)
+	var now = time.UnixMilli(time.Now().UnixMilli())
+	scanner.now = func() time.Time { return now }
+
+	var protected = true
+	var failureFactor, waitIncrement, maxWait int32 = 3, 60, 300
+	var realmRep = keycloak.RealmRepresentation{
+		BruteForceProtected:   &protected,
+		FailureFactor:         &failureFactor,
+		WaitIncrementSeconds:  &waitIncrement,
+		MaxFailureWaitSeconds: &maxWait,
+	}
+	var count = 3
+	var options = BruteForceScanOptions{PageSize: 2}
+	var notLocked = createBruteForceStatus(false, 1, now)
+
+	t.Run("Can't get realm", func(t *testing.T) {
+		mockClient.EXPECT().GetRealm(token, realm).Return(keycloak.RealmRepresentation{}, errAny)
+		var _, err = scanner.ScanLockedUsers(token, "master", realm, options)
+		assert.Equal(t, errAny, err)
+	})
+	t.Run("Brute force protection is disabled", func(t *testing.T) {
+		mockClient.EXPECT().GetRealm(token, realm).Return(keycloak.RealmRepresentation{}, nil)
+		var res, err = scanner.ScanLockedUsers(token, "master", realm, options)
+		assert.Nil(t, err)
+		assert.Nil(t, res)
+	})
+	t.Run("Can't get users", func(t *testing.T) {
+		mockClient.EXPECT().GetRealm(token, realm).Return(realmRep, nil)
+		mockClient.EXPECT().GetUsers(token, "master", realm, "first", "0", "max", "2").Return(keycloak.UsersPageRepresentation{}, errAny)
+		var _, err = scanner.ScanLockedUsers(token, "master", realm, options)
+		assert.Equal(t, errAny, err)
+	})
+	t.Run("Can't get status", func(t *testing.T) {
+		mockClient.EXPECT().GetRealm(token, realm).Return(realmRep, nil)
+		mockClient.EXPECT().GetUsers(token, "master", realm, "first", "0", "max", "2").Return(keycloak.UsersPageRepresentation{Count: &count, Users: createUsers("1", "2")}, nil)
+		mockClient.EXPECT().GetBruteForceStatus(token, realm, "1").Return(keycloak.BruteForceStatusRepresentation{}, errAny)
+		var _, err = scanner.ScanLockedUsers(token, "master", realm, options)
+		assert.Equal(t, errAny, err)
+	})
+	t.Run("Scan all pages", func(t *testing.T) {
+		mockClient.EXPECT().GetRealm(token, realm).Return(realmRep, nil)
+		mockClient.EXPECT().GetUsers(token, "master", realm, "first", "0", "max", "2").Return(keycloak.UsersPageRepresentation{Count: &count, Users: createUsers("1", "2")}, nil)
+		mockClient.EXPECT().GetUsers(token, "master", realm, "first", "2", "max", "2").Return(keycloak.UsersPageRepresentation{Count: &count, Users: createUsers("3")}, nil)
+		mockClient.EXPECT().GetBruteForceStatus(token, realm, "1").Return(createBruteForceStatus(true, 4, now.Add(-10*time.Second)), nil)
+		mockClient.EXPECT().GetBruteForceStatus(token, realm, "2").Return(notLocked, nil)
+		mockClient.EXPECT().GetBruteForceStatus(token, realm, "3").Return(createBruteForceStatus(true, 30, now.Add(-time.Minute)), nil)
+
+		var res, err = scanner.ScanLockedUsers(token, "master", realm, options)
+		assert.Nil(t, err)
+		assert.Len(t, res, 2)
+		assert.Equal(t, "1", *res[0].User.ID)
+		assert.Equal(t, 50*time.Second, *res[0].UnlockIn)
+		assert.False(t, res[0].Cleared)
+		assert.Equal(t, "3", *res[1].User.ID)
+		assert.Equal(t, 4*time.Minute, *res[1].UnlockIn)
+	})
+	t.Run("Clear failures", func(t *testing.T) {
+		var clearOptions = BruteForceScanOptions{ClearFailures: true}
+		mockClient.EXPECT().GetRealm(token, realm).Return(realmRep, nil)
+		mockClient.EXPECT().GetUsers(token, "master", realm, "first", "0", "max", "100").Return(keycloak.UsersPageRepresentation{Users: createUsers("1", "2")}, nil)
+		mockClient.EXPECT().GetBruteForceStatus(token, realm, "1").Return(createBruteForceStatus(true, 3, now.Add(-time.Hour)), nil)
+		mockClient.EXPECT().GetBruteForceStatus(token, realm, "2").Return(createBruteForceStatus(true, 3, now), nil)
+		mockClient.EXPECT().ClearUserLoginFailures(token, realm, "1").Return(nil)
+		mockClient.EXPECT().ClearUserLoginFailures(token, realm, "2").Return(errAny)
+
+		var res, err = scanner.ScanLockedUsers(token, "master", realm, clearOptions)
+		assert.Equal(t, errAny, err)
+		assert.Len(t, res, 1)
+		assert.True(t, res[0].Cleared)
+		assert.Equal(t, time.Duration(0), *res[0].UnlockIn)
+	})
+	t.Run("Unlock delay can't be computed", func(t *testing.T) {
+		var res = scanner.getUnlockDelay(keycloak.RealmRepresentation{}, createBruteForceStatus(true, 3, now))
+		assert.Nil(t, res)
+	})
+	t.Run("Permanent lockout", func(t *testing.T) {
+		var permanentRealm = realmRep
+		permanentRealm.PermanentLockout = boolPtr(true)
+		var res = scanner.getUnlockDelay(permanentRealm, createBruteForceStatus(true, 3, now))
+		assert.Nil(t, res)
+		assert.NotNil(t, scanner.getUnlockDelay(realmRep, createBruteForceStatus(true, 3, now)))
+	})
+}
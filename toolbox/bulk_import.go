@@ -0,0 +1,304 @@
+package toolbox
+
+import (
+	"errors"
+	"hash/fnv"
+	"strings"
+	"sync"
+
+	errorhandler "github.com/cloudtrust/common-service/v2/errors"
+	"github.com/cloudtrust/keycloak-client/v2"
+)
+
+// Conflict policies used when a user already exists
+const (
+	ConflictFail   = "fail"
+	ConflictSkip   = "skip"
+	ConflictUpdate = "update"
+)
+
+// Status of an imported user
+const (
+	ImportStatusCreated = "created"
+	ImportStatusUpdated = "updated"
+	ImportStatusSkipped = "skipped"
+	ImportStatusFailed  = "failed"
+)
+
+const (
+	defaultImportConcurrency = 4
+	credentialTypePassword   = "password"
+)
+
+// BulkImportClient is the part of the Keycloak client used to import users
+type BulkImportClient interface {
+	CreateUser(accessToken string, reqRealmName, targetRealmName string, user keycloak.UserRepresentation, paramKV ...string) (string, error)
+	GetUsers(accessToken string, reqRealmName, targetRealmName string, paramKV ...string) (keycloak.UsersPageRepresentation, error)
+	UpdateUser(accessToken string, realmName, userID string, user keycloak.UserRepresentation) error
+	AddGroupToUser(accessToken string, realmName, userID, groupID string) error
+	AddRealmLevelRoleMappings(accessToken string, realmName, userID string, roles []keycloak.RoleRepresentation) error
+	AddClientRolesToUserRoleMapping(accessToken string, realmName, userID, clientID string, roles []keycloak.RoleRepresentation) error
+	ResetPassword(accessToken string, realmName, userID string, cred keycloak.CredentialRepresentation) error
+}
+
+// UserImportItem is a user to import with its group memberships, role mappings and credentials
+type UserImportItem struct {
+	User        keycloak.UserRepresentation
+	GroupIDs    []string
+	RealmRoles  []keycloak.RoleRepresentation
+	ClientRoles map[string][]keycloak.RoleRepresentation // Roles by client ID (technical identifier of the client)
+	Credentials []keycloak.CredentialRepresentation
+}
+
+// UserImportResult is the result of the import of a single user. A failed result with a UserID means that the user
+// exists but that its group memberships, role mappings or credentials could not all be assigned.
+type UserImportResult struct {
+	Username string `json:"username"`
+	UserID   string `json:"userId,omitempty"`
+	Status   string `json:"status"`
+	Error    string `json:"error,omitempty"`
+}
+
+// UserImportReport is the result of a bulk import. It can be serialized and given back to ImportUsers to resume an
+// interrupted import: users which have already been successfully processed are not imported again.
+type UserImportReport struct {
+	Results map[string]UserImportResult `json:"results"`
+	mutex   sync.Mutex
+}
+
+// NewUserImportReport creates an empty report
+func NewUserImportReport() *UserImportReport {
+	return &UserImportReport{
+		Results: map[string]UserImportResult{},
+	}
+}
+
+// Count returns the number of users with the given status
+func (r *UserImportReport) Count(status string) int {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	var count = 0
+	for _, result := range r.Results {
+		if result.Status == status {
+			count++
+		}
+	}
+	return count
+}
+
+// Failed returns the results of the users which could not be imported
+func (r *UserImportReport) Failed() []UserImportResult {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	var res []UserImportResult
+	for _, result := range r.Results {
+		if result.Status == ImportStatusFailed {
+			res = append(res, result)
+		}
+	}
+	return res
+}
+
+func (r *UserImportReport) previousResult(username string) (UserImportResult, bool) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	var result, ok = r.Results[username]
+	return result, ok
+}
+
+// BulkImportOptions configures a bulk import
+type BulkImportOptions struct {
+	Concurrency    int                           // Number of users imported in parallel. Defaults to 4
+	ConflictPolicy string                        // ConflictFail (default), ConflictSkip or ConflictUpdate
+	OnResult       func(result UserImportResult) // Called once per processed user, never concurrently. Can be used to persist the progress
+}
+
+// UserImporter creates users in bulk
+type UserImporter struct {
+	client      BulkImportClient
+	accessToken string
+	reqRealm    string
+	targetRealm string
+	options     BulkImportOptions
+}
+
+// NewUserImporter creates a UserImporter
+func NewUserImporter(client BulkImportClient, accessToken string, reqRealmName string, targetRealmName string, options BulkImportOptions) *UserImporter {
+	if options.Concurrency <= 0 {
+		options.Concurrency = defaultImportConcurrency
+	}
+	if options.ConflictPolicy == "" {
+		options.ConflictPolicy = ConflictFail
+	}
+	return &UserImporter{
+		client:      client,
+		accessToken: accessToken,
+		reqRealm:    reqRealmName,
+		targetRealm: targetRealmName,
+		options:     options,
+	}
+}
+
+// ImportUsers imports all the users read from the items channel until it is closed. When a report is given, users
+// already successfully processed are ignored and the report is completed. Otherwise a new report is created. Items
+// with the same username are processed one after the other: only the first one is imported.
+func (ui *UserImporter) ImportUsers(items <-chan UserImportItem, report *UserImportReport) *UserImportReport {
+	if report == nil {
+		report = NewUserImportReport()
+	}
+	if report.Results == nil {
+		report.Results = map[string]UserImportResult{}
+	}
+
+	// Each worker imports the users of a shard of the usernames
+	var shards = make([]chan UserImportItem, ui.options.Concurrency)
+	var wg sync.WaitGroup
+	for idx := range shards {
+		var shard = make(chan UserImportItem)
+		shards[idx] = shard
+		wg.Go(func() {
+			for item := range shard {
+				var username = importUsername(item)
+				var previous, ok = report.previousResult(username)
+				if ok && previous.Status != ImportStatusFailed {
+					continue
+				}
+				var result = ui.importUser(username, item, previous)
+				report.mutex.Lock()
+				report.Results[username] = result
+				if ui.options.OnResult != nil {
+					ui.options.OnResult(result)
+				}
+				report.mutex.Unlock()
+			}
+		})
+	}
+	for item := range items {
+		var hash = fnv.New32a()
+		_, _ = hash.Write([]byte(strings.ToLower(importUsername(item))))
+		shards[hash.Sum32()%uint32(len(shards))] <- item
+	}
+	for _, shard := range shards {
+		close(shard)
+	}
+	wg.Wait()
+	return report
+}
+
+func importUsername(item UserImportItem) string {
+	if item.User.Username == nil {
+		return ""
+	}
+	return *item.User.Username
+}
+
+// importUser creates the user of item and assigns its groups, roles and credentials. When a previous attempt failed
+// after the user was created, only the assignments are done again.
+func (ui *UserImporter) importUser(username string, item UserImportItem, previous UserImportResult) UserImportResult {
+	var result = UserImportResult{Username: username, Status: ImportStatusCreated}
+	var fail = func(err error) UserImportResult {
+		result.Status = ImportStatusFailed
+		result.Error = err.Error()
+		return result
+	}
+	if username == "" {
+		return fail(errors.New(keycloak.MsgErrMissingParam + "." + keycloak.Username))
+	}
+	for _, credential := range item.Credentials {
+		// Only passwords can be set through the reset password endpoint
+		if credential.Type != nil && *credential.Type != credentialTypePassword {
+			return fail(errors.New(keycloak.MsgErrInvalidParam + ".credentialType"))
+		}
+	}
+	if previous.Status == ImportStatusFailed && previous.UserID != "" {
+		result.UserID = previous.UserID
+		if err := ui.assign(result.UserID, item); err != nil {
+			return fail(err)
+		}
+		return result
+	}
+
+	var location, err = ui.client.CreateUser(ui.accessToken, ui.reqRealm, ui.targetRealm, item.User)
+	if err != nil {
+		if !isExistingUserError(err) || ui.options.ConflictPolicy == ConflictFail {
+			return fail(err)
+		}
+		if result.UserID, err = ui.findUserID(item.User); err != nil {
+			return fail(err)
+		}
+		if ui.options.ConflictPolicy == ConflictSkip {
+			result.Status = ImportStatusSkipped
+			return result
+		}
+		if err = ui.client.UpdateUser(ui.accessToken, ui.targetRealm, result.UserID, item.User); err != nil {
+			return fail(err)
+		}
+		result.Status = ImportStatusUpdated
+	} else {
+		result.UserID = location[strings.LastIndex(location, "/")+1:]
+	}
+
+	if err = ui.assign(result.UserID, item); err != nil {
+		return fail(err)
+	}
+	return result
+}
+
+func (ui *UserImporter) assign(userID string, item UserImportItem) error {
+	for _, groupID := range item.GroupIDs {
+		if err := ui.client.AddGroupToUser(ui.accessToken, ui.targetRealm, userID, groupID); err != nil {
+			return err
+		}
+	}
+	if len(item.RealmRoles) > 0 {
+		if err := ui.client.AddRealmLevelRoleMappings(ui.accessToken, ui.targetRealm, userID, item.RealmRoles); err != nil {
+			return err
+		}
+	}
+	for clientID, roles := range item.ClientRoles {
+		if err := ui.client.AddClientRolesToUserRoleMapping(ui.accessToken, ui.targetRealm, userID, clientID, roles); err != nil {
+			return err
+		}
+	}
+	for _, credential := range item.Credentials {
+		if err := ui.client.ResetPassword(ui.accessToken, ui.targetRealm, userID, credential); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// findUserID returns the ID of the existing user conflicting with user, which has the same username or email
+func (ui *UserImporter) findUserID(user keycloak.UserRepresentation) (string, error) {
+	for _, search := range []struct {
+		param string
+		value *string
+		field func(keycloak.UserRepresentation) *string
+	}{
+		{"username", user.Username, func(u keycloak.UserRepresentation) *string { return u.Username }},
+		{"email", user.Email, func(u keycloak.UserRepresentation) *string { return u.Email }},
+	} {
+		if search.value == nil || *search.value == "" {
+			continue
+		}
+		var page, err = ui.client.GetUsers(ui.accessToken, ui.reqRealm, ui.targetRealm, search.param, *search.value)
+		if err != nil {
+			return "", err
+		}
+		for _, existing := range page.Users {
+			var value = search.field(existing)
+			if existing.ID != nil && value != nil && strings.EqualFold(*value, *search.value) {
+				return *existing.ID, nil
+			}
+		}
+	}
+	return "", errors.New(keycloak.MsgErrCannotObtain + "." + keycloak.UserOrEmail)
+}
+
+func isExistingUserError(err error) bool {
+	var httpErr errorhandler.Error
+	return errors.As(err, &httpErr) && strings.Contains(httpErr.Message, keycloak.MsgErrExistingValue+".")
+}
@@ -0,0 +1,163 @@
+package toolbox
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	errorhandler "github.com/cloudtrust/common-service/v2/errors"
+	"github.com/cloudtrust/keycloak-client/v2"
+	"github.com/cloudtrust/keycloak-client/v2/toolbox/mock"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+func toImportChannel(items ...UserImportItem) <-chan UserImportItem {
+	var res = make(chan UserImportItem, len(items))
+	for _, item := range items {
+		res <- item
+	}
+	close(res)
+	return res
+}
+
+func TestImportUsers(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	var mockClient = mock.NewBulkImportClient(mockCtrl)
+	var reqRealm = "master"
+	var errExists = errorhandler.Error{Status: http.StatusConflict, Message: "keycloak." + keycloak.MsgErrExistingValue + "." + keycloak.UserOrEmail}
+	var john = keycloak.UserRepresentation{Username: ptr("john")}
+	var location = "https://idp.domain.ch/auth/admin/realms/my-realm/users/john-id"
+	var existingPage = keycloak.UsersPageRepresentation{Users: []keycloak.UserRepresentation{
+		{ID: ptr("johnny-id"), Username: ptr("johnny")},
+		{ID: ptr("john-id"), Username: ptr("John")},
+	}}
+
+	t.Run("Missing username", func(t *testing.T) {
+		var importer = NewUserImporter(mockClient, token, reqRealm, realm, BulkImportOptions{})
+		var report = importer.ImportUsers(toImportChannel(UserImportItem{}), nil)
+		assert.Equal(t, 1, report.Count(ImportStatusFailed))
+	})
+	t.Run("Create user with groups, roles and credentials", func(t *testing.T) {
+		var realmRoles = []keycloak.RoleRepresentation{{Name: ptr("admin")}}
+		var clientRoles = []keycloak.RoleRepresentation{{Name: ptr("reader")}}
+		var credential = keycloak.CredentialRepresentation{Type: ptr("password"), Value: ptr("P@ssw0rd")}
+		mockClient.EXPECT().CreateUser(token, reqRealm, realm, john).Return(location, nil)
+		mockClient.EXPECT().AddGroupToUser(token, realm, "john-id", "group-id").Return(nil)
+		mockClient.EXPECT().AddRealmLevelRoleMappings(token, realm, "john-id", realmRoles).Return(nil)
+		mockClient.EXPECT().AddClientRolesToUserRoleMapping(token, realm, "john-id", "client-id", clientRoles).Return(nil)
+		mockClient.EXPECT().ResetPassword(token, realm, "john-id", credential).Return(nil)
+
+		var results []UserImportResult
+		var importer = NewUserImporter(mockClient, token, reqRealm, realm, BulkImportOptions{
+			OnResult: func(result UserImportResult) { results = append(results, result) },
+		})
+		var report = importer.ImportUsers(toImportChannel(UserImportItem{
+			User:        john,
+			GroupIDs:    []string{"group-id"},
+			RealmRoles:  realmRoles,
+			ClientRoles: map[string][]keycloak.RoleRepresentation{"client-id": clientRoles},
+			Credentials: []keycloak.CredentialRepresentation{credential},
+		}), nil)
+		var expected = UserImportResult{Username: "john", UserID: "john-id", Status: ImportStatusCreated}
+		assert.Equal(t, expected, report.Results["john"])
+		assert.Equal(t, []UserImportResult{expected}, results)
+	})
+	t.Run("Assignment fails", func(t *testing.T) {
+		mockClient.EXPECT().CreateUser(token, reqRealm, realm, john).Return(location, nil)
+		mockClient.EXPECT().AddGroupToUser(token, realm, "john-id", "group-id").Return(errAny)
+		var importer = NewUserImporter(mockClient, token, reqRealm, realm, BulkImportOptions{})
+		var report = importer.ImportUsers(toImportChannel(UserImportItem{User: john, GroupIDs: []string{"group-id"}}), nil)
+		assert.Equal(t, ImportStatusFailed, report.Results["john"].Status)
+		assert.Equal(t, errAny.Error(), report.Results["john"].Error)
+		assert.Len(t, report.Failed(), 1)
+	})
+	t.Run("Resume after a failed assignment", func(t *testing.T) {
+		var item = UserImportItem{User: john, GroupIDs: []string{"group-id"}}
+		mockClient.EXPECT().CreateUser(token, reqRealm, realm, john).Return(location, nil)
+		mockClient.EXPECT().AddGroupToUser(token, realm, "john-id", "group-id").Return(errAny)
+		var importer = NewUserImporter(mockClient, token, reqRealm, realm, BulkImportOptions{})
+		var report = importer.ImportUsers(toImportChannel(item), nil)
+		assert.Equal(t, UserImportResult{Username: "john", UserID: "john-id", Status: ImportStatusFailed, Error: errAny.Error()}, report.Results["john"])
+
+		// The user is not created again, only the assignments are done
+		mockClient.EXPECT().AddGroupToUser(token, realm, "john-id", "group-id").Return(nil)
+		report = importer.ImportUsers(toImportChannel(item), report)
+		assert.Equal(t, UserImportResult{Username: "john", UserID: "john-id", Status: ImportStatusCreated}, report.Results["john"])
+	})
+	t.Run("Unsupported credential type", func(t *testing.T) {
+		var importer = NewUserImporter(mockClient, token, reqRealm, realm, BulkImportOptions{})
+		var report = importer.ImportUsers(toImportChannel(UserImportItem{User: john, Credentials: []keycloak.CredentialRepresentation{{Type: ptr("otp")}}}), nil)
+		assert.Equal(t, ImportStatusFailed, report.Results["john"].Status)
+		assert.Equal(t, keycloak.MsgErrInvalidParam+".credentialType", report.Results["john"].Error)
+	})
+	t.Run("Conflict with fail policy", func(t *testing.T) {
+		mockClient.EXPECT().CreateUser(token, reqRealm, realm, john).Return("", errExists)
+		var importer = NewUserImporter(mockClient, token, reqRealm, realm, BulkImportOptions{})
+		var report = importer.ImportUsers(toImportChannel(UserImportItem{User: john}), nil)
+		assert.Equal(t, ImportStatusFailed, report.Results["john"].Status)
+	})
+	t.Run("Conflict with skip policy", func(t *testing.T) {
+		mockClient.EXPECT().CreateUser(token, reqRealm, realm, john).Return("", errExists)
+		mockClient.EXPECT().GetUsers(token, reqRealm, realm, "username", "john").Return(existingPage, nil)
+		var importer = NewUserImporter(mockClient, token, reqRealm, realm, BulkImportOptions{ConflictPolicy: ConflictSkip})
+		var report = importer.ImportUsers(toImportChannel(UserImportItem{User: john, GroupIDs: []string{"group-id"}}), nil)
+		assert.Equal(t, UserImportResult{Username: "john", UserID: "john-id", Status: ImportStatusSkipped}, report.Results["john"])
+	})
+	t.Run("Conflict with update policy", func(t *testing.T) {
+		mockClient.EXPECT().CreateUser(token, reqRealm, realm, john).Return("", errExists)
+		mockClient.EXPECT().GetUsers(token, reqRealm, realm, "username", "john").Return(existingPage, nil)
+		mockClient.EXPECT().UpdateUser(token, realm, "john-id", john).Return(nil)
+		mockClient.EXPECT().AddGroupToUser(token, realm, "john-id", "group-id").Return(nil)
+		var importer = NewUserImporter(mockClient, token, reqRealm, realm, BulkImportOptions{ConflictPolicy: ConflictUpdate})
+		var report = importer.ImportUsers(toImportChannel(UserImportItem{User: john, GroupIDs: []string{"group-id"}}), nil)
+		assert.Equal(t, UserImportResult{Username: "john", UserID: "john-id", Status: ImportStatusUpdated}, report.Results["john"])
+	})
+	t.Run("Conflict on the email", func(t *testing.T) {
+		var johnWithEmail = keycloak.UserRepresentation{Username: ptr("john"), Email: ptr("john@domain.ch")}
+		mockClient.EXPECT().CreateUser(token, reqRealm, realm, johnWithEmail).Return("", errExists)
+		mockClient.EXPECT().GetUsers(token, reqRealm, realm, "username", "john").Return(keycloak.UsersPageRepresentation{}, nil)
+		mockClient.EXPECT().GetUsers(token, reqRealm, realm, "email", "john@domain.ch").Return(keycloak.UsersPageRepresentation{Users: []keycloak.UserRepresentation{
+			{ID: ptr("jdoe-id"), Username: ptr("jdoe"), Email: ptr("John@domain.ch")},
+		}}, nil)
+		var importer = NewUserImporter(mockClient, token, reqRealm, realm, BulkImportOptions{ConflictPolicy: ConflictSkip})
+		var report = importer.ImportUsers(toImportChannel(UserImportItem{User: johnWithEmail}), nil)
+		assert.Equal(t, UserImportResult{Username: "john", UserID: "jdoe-id", Status: ImportStatusSkipped}, report.Results["john"])
+	})
+	t.Run("Duplicate usernames", func(t *testing.T) {
+		mockClient.EXPECT().CreateUser(token, reqRealm, realm, john).Return(location, nil)
+		var results []UserImportResult
+		var importer = NewUserImporter(mockClient, token, reqRealm, realm, BulkImportOptions{
+			Concurrency: 4,
+			OnResult:    func(result UserImportResult) { results = append(results, result) },
+		})
+		var report = importer.ImportUsers(toImportChannel(UserImportItem{User: john}, UserImportItem{User: john}, UserImportItem{User: john}), nil)
+		assert.Equal(t, UserImportResult{Username: "john", UserID: "john-id", Status: ImportStatusCreated}, report.Results["john"])
+		assert.Len(t, results, 1)
+	})
+	t.Run("Conflict but existing user not found", func(t *testing.T) {
+		mockClient.EXPECT().CreateUser(token, reqRealm, realm, john).Return("", errExists)
+		mockClient.EXPECT().GetUsers(token, reqRealm, realm, "username", "john").Return(keycloak.UsersPageRepresentation{}, nil)
+		var importer = NewUserImporter(mockClient, token, reqRealm, realm, BulkImportOptions{ConflictPolicy: ConflictUpdate})
+		var report = importer.ImportUsers(toImportChannel(UserImportItem{User: john}), nil)
+		assert.Equal(t, ImportStatusFailed, report.Results["john"].Status)
+	})
+	t.Run("Resume from a serialized report", func(t *testing.T) {
+		var previous = NewUserImportReport()
+		previous.Results["alice"] = UserImportResult{Username: "alice", UserID: "alice-id", Status: ImportStatusCreated}
+		previous.Results["john"] = UserImportResult{Username: "john", Status: ImportStatusFailed, Error: "error"}
+		var bytes, err = json.Marshal(previous)
+		assert.Nil(t, err)
+
+		var report = NewUserImportReport()
+		assert.Nil(t, json.Unmarshal(bytes, report))
+
+		mockClient.EXPECT().CreateUser(token, reqRealm, realm, john).Return(location, nil)
+		var importer = NewUserImporter(mockClient, token, reqRealm, realm, BulkImportOptions{Concurrency: 2})
+		report = importer.ImportUsers(toImportChannel(UserImportItem{User: keycloak.UserRepresentation{Username: ptr("alice")}}, UserImportItem{User: john}), report)
+		assert.Equal(t, 2, report.Count(ImportStatusCreated))
+		assert.Len(t, report.Failed(), 0)
+	})
+}
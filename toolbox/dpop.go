@@ -0,0 +1,286 @@
+package toolbox
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/cloudtrust/keycloak-client/v2"
+	"github.com/go-jose/go-jose/v4"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// DPoP (RFC 9449) headers and token type
+const (
+	DPoPHeader      = "DPoP"
+	DPoPNonceHeader = "DPoP-Nonce"
+	TokenTypeDPoP   = "DPoP"
+)
+
+const (
+	dpopProofType = "dpop+jwt"
+	// Maximum difference between the issue time of a proof and the time it is verified
+	dpopProofMaxAge = time.Minute
+)
+
+// DPoPSigner creates the DPoP proofs which bind the access tokens to its key
+type DPoPSigner interface {
+	// Thumbprint returns the JWK SHA-256 thumbprint of the public key, which is the cnf.jkt claim of the bound tokens
+	Thumbprint() string
+	// CreateProof creates a proof for a request. accessToken is empty for the token requests and nonce is empty until
+	// the server requires one.
+	CreateProof(httpMethod string, targetURL string, accessToken string, nonce string) (string, error)
+}
+
+type dpopSigner struct {
+	key        crypto.Signer
+	method     jwt.SigningMethod
+	jwk        map[string]any
+	thumbprint string
+}
+
+// NewDPoPSigner creates a DPoPSigner with a RSA or EC private key
+func NewDPoPSigner(key crypto.Signer) (DPoPSigner, error) {
+	var method = jwt.GetSigningMethod(defaultSigningAlg(key))
+	if !signingMethodMatchesKey(method, key) {
+		return nil, errors.New(keycloak.MsgErrInvalidParam + ".dpopKey")
+	}
+	var publicKey = jose.JSONWebKey{Key: key.Public()}
+	var thumbprint, err = publicKey.Thumbprint(crypto.SHA256)
+	if err != nil {
+		return nil, errors.New(keycloak.MsgErrInvalidParam + ".dpopKey")
+	}
+	var data []byte
+	if data, err = publicKey.MarshalJSON(); err != nil {
+		return nil, err
+	}
+	var jwk map[string]any
+	if err = json.Unmarshal(data, &jwk); err != nil {
+		return nil, err
+	}
+	return &dpopSigner{
+		key:        key,
+		method:     method,
+		jwk:        jwk,
+		thumbprint: base64.RawURLEncoding.EncodeToString(thumbprint),
+	}, nil
+}
+
+// GenerateDPoPSigner creates a DPoPSigner with a new P-256 key
+func GenerateDPoPSigner() (DPoPSigner, error) {
+	var key, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	return NewDPoPSigner(key)
+}
+
+func (s *dpopSigner) Thumbprint() string {
+	return s.thumbprint
+}
+
+func (s *dpopSigner) CreateProof(httpMethod string, targetURL string, accessToken string, nonce string) (string, error) {
+	var claims = jwt.MapClaims{
+		"jti": rand.Text(),
+		"htm": httpMethod,
+		"htu": dpopTargetURI(targetURL),
+		"iat": time.Now().Unix(),
+	}
+	if accessToken != "" {
+		claims["ath"] = accessTokenHash(accessToken)
+	}
+	if nonce != "" {
+		claims["nonce"] = nonce
+	}
+	var token = jwt.NewWithClaims(s.method, claims)
+	token.Header["typ"] = dpopProofType
+	token.Header["jwk"] = s.jwk
+	return token.SignedString(s.key)
+}
+
+// dpopTargetURI returns the htu of a URL: the query and fragment are removed, the scheme and host are lower case
+func dpopTargetURI(targetURL string) string {
+	var u, err = url.Parse(targetURL)
+	if err != nil {
+		return targetURL
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	u.Host = strings.ToLower(u.Host)
+	u.RawQuery = ""
+	u.ForceQuery = false
+	u.Fragment = ""
+	u.RawFragment = ""
+	return u.String()
+}
+
+func accessTokenHash(accessToken string) string {
+	var hash = sha256.Sum256([]byte(accessToken))
+	return base64.RawURLEncoding.EncodeToString(hash[:])
+}
+
+// AddDPoPProof sets the DPoP header of req with a new proof. When req has a Forwarded header, such as the requests
+// sent to the internal URL of Keycloak, the proof is created for the forwarded host and protocol.
+func AddDPoPProof(req *http.Request, signer DPoPSigner, accessToken string, nonce string) error {
+	var proof, err = signer.CreateProof(req.Method, publicRequestURL(req), accessToken, nonce)
+	if err != nil {
+		return err
+	}
+	req.Header.Set(DPoPHeader, proof)
+	return nil
+}
+
+// publicRequestURL returns the URL of a request sent by a client, as seen by the server through the Forwarded header
+func publicRequestURL(req *http.Request) string {
+	return forwardedURL(*req.URL, req.Header.Get("Forwarded"))
+}
+
+// incomingRequestURL returns the URL of a request received by a server, as seen by the client. The Forwarded header is
+// set by the clients as well: it is only used when options trust it.
+func incomingRequestURL(req *http.Request, options HTTPBearerAuthOptions) string {
+	var u = *req.URL
+	u.Host = req.Host
+	u.Scheme = "http"
+	if req.TLS != nil {
+		u.Scheme = "https"
+	}
+	switch {
+	case options.PublicURL != nil:
+		u.Scheme = options.PublicURL.Scheme
+		u.Host = options.PublicURL.Host
+	case options.TrustForwarded:
+		return forwardedURL(u, req.Header.Get("Forwarded"))
+	}
+	return u.String()
+}
+
+// forwardedURL replaces the host and protocol of u by the ones of the first element of a Forwarded header
+func forwardedURL(u url.URL, forwarded string) string {
+	forwarded, _, _ = strings.Cut(forwarded, ",")
+	for pair := range strings.SplitSeq(forwarded, ";") {
+		var name, value, _ = strings.Cut(strings.TrimSpace(pair), "=")
+		value = strings.Trim(value, `"`)
+		switch strings.ToLower(name) {
+		case "host":
+			u.Host = value
+		case "proto":
+			u.Scheme = value
+		}
+	}
+	return u.String()
+}
+
+// dpopTransport adds a DPoP proof to the token requests. When Keycloak requires a nonce, the request is sent again
+// once with a proof containing it.
+type dpopTransport struct {
+	base   http.RoundTripper
+	signer DPoPSigner
+}
+
+func (t *dpopTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// RoundTrippers must not modify the request
+	var body []byte
+	if req.Body != nil && req.Body != http.NoBody {
+		var err error
+		if body, err = io.ReadAll(req.Body); err != nil {
+			return nil, err
+		}
+		_ = req.Body.Close()
+	}
+	var send = func(nonce string) (*http.Response, error) {
+		var clone = req.Clone(req.Context())
+		if body != nil {
+			clone.Body = io.NopCloser(bytes.NewReader(body))
+		}
+		if err := AddDPoPProof(clone, t.signer, "", nonce); err != nil {
+			return nil, err
+		}
+		return t.base.RoundTrip(clone)
+	}
+
+	var resp, err = send("")
+	if err != nil {
+		return nil, err
+	}
+	var nonce = resp.Header.Get(DPoPNonceHeader)
+	if nonce == "" || (resp.StatusCode != http.StatusBadRequest && resp.StatusCode != http.StatusUnauthorized) {
+		return resp, nil
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	_ = resp.Body.Close()
+	return send(nonce)
+}
+
+// dpopSignerHolder is implemented by the token providers which request DPoP-bound tokens
+type dpopSignerHolder interface {
+	dpopSigner() DPoPSigner
+}
+
+// DPoPSignerOf returns the signer of a token provider created by NewOAuth2TokenProvider with DPoP enabled, or nil. The
+// proofs of the requests using the tokens of the provider must be signed by it.
+func DPoPSignerOf(provider OidcTokenProvider) DPoPSigner {
+	if holder, ok := provider.(dpopSignerHolder); ok {
+		return holder.dpopSigner()
+	}
+	return nil
+}
+
+type dpopProofClaims struct {
+	JTI             string `json:"jti"`
+	HTTPMethod      string `json:"htm"`
+	HTTPURI         string `json:"htu"`
+	IssuedAt        int64  `json:"iat"`
+	AccessTokenHash string `json:"ath"`
+}
+
+// verifyDPoPProof verifies that proof is a DPoP proof of the request signed with its embedded key and created for
+// accessToken. It returns the thumbprint of the key and the jti of the proof.
+func verifyDPoPProof(proof string, httpMethod string, targetURL string, accessToken string, leeway time.Duration) (string, string, error) {
+	var jws, err = jose.ParseSigned(proof, supportedSigningAlgs)
+	if err != nil {
+		return "", "", invalidTokenError("dpop")
+	}
+	var header = jws.Signatures[0].Header
+	if typ, _ := header.ExtraHeaders[jose.HeaderType].(string); typ != dpopProofType {
+		return "", "", invalidTokenError("dpop.typ")
+	}
+	if header.JSONWebKey == nil || !header.JSONWebKey.IsPublic() || !header.JSONWebKey.Valid() {
+		return "", "", invalidTokenError("dpop.jwk")
+	}
+	var payload []byte
+	if payload, err = jws.Verify(header.JSONWebKey); err != nil {
+		return "", "", invalidTokenError("dpop.signature")
+	}
+	var claims dpopProofClaims
+	if err = json.Unmarshal(payload, &claims); err != nil || claims.JTI == "" {
+		return "", "", invalidTokenError("dpop")
+	}
+	if claims.HTTPMethod != httpMethod {
+		return "", "", invalidTokenError("dpop.htm")
+	}
+	if dpopTargetURI(claims.HTTPURI) != dpopTargetURI(targetURL) {
+		return "", "", invalidTokenError("dpop.htu")
+	}
+	if math.Abs(time.Since(time.Unix(claims.IssuedAt, 0)).Seconds()) > (dpopProofMaxAge + leeway).Seconds() {
+		return "", "", invalidTokenError("dpop.iat")
+	}
+	if claims.AccessTokenHash != accessTokenHash(accessToken) {
+		return "", "", invalidTokenError("dpop.ath")
+	}
+	var thumbprint []byte
+	if thumbprint, err = header.JSONWebKey.Thumbprint(crypto.SHA256); err != nil {
+		return "", "", invalidTokenError("dpop.jwk")
+	}
+	return base64.RawURLEncoding.EncodeToString(thumbprint), claims.JTI, nil
+}
@@ -0,0 +1,217 @@
+package toolbox
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/cloudtrust/keycloak-client/v2"
+	"github.com/cloudtrust/keycloak-client/v2/toolbox/mock"
+	"github.com/go-jose/go-jose/v4"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+func TestDPoPSigner(t *testing.T) {
+	t.Run("Unsupported key", func(t *testing.T) {
+		var _, key, _ = ed25519.GenerateKey(rand.Reader)
+		var _, err = NewDPoPSigner(key)
+		assert.NotNil(t, err)
+	})
+
+	var signer, err = GenerateDPoPSigner()
+	assert.Nil(t, err)
+	var targetURL = "https://public.domain.ch/auth/admin/realms/my-realm/users?first=0#top"
+
+	t.Run("Proof of a request", func(t *testing.T) {
+		var proof, err = signer.CreateProof(http.MethodGet, targetURL, "access-token", "")
+		assert.Nil(t, err)
+		var thumbprint, jti, errVerify = verifyDPoPProof(proof, http.MethodGet, "https://PUBLIC.domain.ch/auth/admin/realms/my-realm/users", "access-token", 0)
+		assert.Nil(t, errVerify)
+		assert.Equal(t, signer.Thumbprint(), thumbprint)
+		assert.NotEmpty(t, jti)
+	})
+	t.Run("Proof of a token request with a nonce", func(t *testing.T) {
+		var proof, _ = signer.CreateProof(http.MethodPost, targetURL, "", "server-nonce")
+		var token, _, err = jwt.NewParser().ParseUnverified(proof, jwt.MapClaims{})
+		assert.Nil(t, err)
+		assert.Equal(t, dpopProofType, token.Header["typ"])
+		assert.Equal(t, "ES256", token.Header["alg"])
+		var claims = token.Claims.(jwt.MapClaims)
+		assert.Equal(t, "server-nonce", claims["nonce"])
+		assert.Equal(t, "https://public.domain.ch/auth/admin/realms/my-realm/users", claims["htu"])
+		assert.NotContains(t, claims, "ath")
+	})
+	t.Run("Invalid proofs", func(t *testing.T) {
+		var proof, _ = signer.CreateProof(http.MethodGet, targetURL, "access-token", "")
+		var _, _, err = verifyDPoPProof(proof, http.MethodPost, targetURL, "access-token", 0)
+		assert.Equal(t, keycloak.MsgErrInvalidToken+".dpop.htm", err.(keycloak.ClientDetailedError).Message)
+		_, _, err = verifyDPoPProof(proof, http.MethodGet, "https://public.domain.ch/auth/admin/realms/other", "access-token", 0)
+		assert.Equal(t, keycloak.MsgErrInvalidToken+".dpop.htu", err.(keycloak.ClientDetailedError).Message)
+		_, _, err = verifyDPoPProof(proof, http.MethodGet, targetURL, "other-token", 0)
+		assert.Equal(t, keycloak.MsgErrInvalidToken+".dpop.ath", err.(keycloak.ClientDetailedError).Message)
+		_, _, err = verifyDPoPProof(newSigningKeycloak("https://public.domain.ch").sign("my-realm", nil), http.MethodGet, targetURL, "access-token", 0)
+		assert.Equal(t, keycloak.MsgErrInvalidToken+".dpop.typ", err.(keycloak.ClientDetailedError).Message)
+		_, _, err = verifyDPoPProof("not-a-proof", http.MethodGet, targetURL, "access-token", 0)
+		assert.NotNil(t, err)
+	})
+}
+
+func TestAddDPoPProof(t *testing.T) {
+	var signer, _ = GenerateDPoPSigner()
+	var req, _ = http.NewRequest(http.MethodDelete, "http://keycloak:8080/auth/admin/realms/my-realm?force=true", nil)
+	req.Header.Set("Forwarded", "host=public.domain.ch;proto=https")
+
+	assert.Nil(t, AddDPoPProof(req, signer, "access-token", ""))
+	var _, _, err = verifyDPoPProof(req.Header.Get(DPoPHeader), http.MethodDelete, "https://public.domain.ch/auth/admin/realms/my-realm", "access-token", 0)
+	assert.Nil(t, err)
+}
+
+func TestVerifyDPoP(t *testing.T) {
+	var keycloakServer = newSigningKeycloak("https://public.domain.ch")
+	var keys = jose.JSONWebKeySet{Keys: []jose.JSONWebKey{{Key: &keycloakServer.key.PublicKey, KeyID: keycloakServer.keyID, Algorithm: "RS256", Use: "sig"}}}
+	var provider = NewStaticVerifierProvider(keycloakServer.externalURL, keys, VerifierConfig{})
+	var verifier, _ = provider.GetOidcVerifier("my-realm")
+
+	var signer, _ = GenerateDPoPSigner()
+	var accessToken = keycloakServer.sign("my-realm", jwt.MapClaims{"typ": "DPoP", "cnf": map[string]any{"jkt": signer.Thumbprint()}})
+	var targetURL = "https://api.domain.ch/accounts"
+
+	t.Run("Valid proof", func(t *testing.T) {
+		var proof, _ = signer.CreateProof(http.MethodGet, targetURL, accessToken, "")
+		var claims, err = verifier.VerifyDPoP(accessToken, proof, http.MethodGet, targetURL)
+		assert.Nil(t, err)
+		assert.Equal(t, signer.Thumbprint(), claims.ConfirmationThumbprint)
+		assert.NotContains(t, claims.Attributes, "cnf")
+
+		// Proofs can't be replayed, even with another instance of the verifier of the realm
+		var realmVerifier, _ = provider.GetOidcVerifier("my-realm")
+		_, err = realmVerifier.VerifyDPoP(accessToken, proof, http.MethodGet, targetURL)
+		assert.Equal(t, keycloak.MsgErrInvalidToken+".dpop.jti", err.(keycloak.ClientDetailedError).Message)
+	})
+	t.Run("Bound token without proof", func(t *testing.T) {
+		var _, err = verifier.VerifyAndExtract(accessToken)
+		assert.Equal(t, keycloak.MsgErrInvalidToken+".cnf", err.(keycloak.ClientDetailedError).Message)
+		assert.NotNil(t, verifier.Verify(accessToken))
+	})
+	t.Run("Proof signed with another key", func(t *testing.T) {
+		var otherSigner, _ = GenerateDPoPSigner()
+		var proof, _ = otherSigner.CreateProof(http.MethodGet, targetURL, accessToken, "")
+		var _, err = verifier.VerifyDPoP(accessToken, proof, http.MethodGet, targetURL)
+		assert.Equal(t, keycloak.MsgErrInvalidToken+".cnf", err.(keycloak.ClientDetailedError).Message)
+	})
+	t.Run("Token not bound to a key", func(t *testing.T) {
+		var bearerToken = keycloakServer.sign("my-realm", nil)
+		var proof, _ = signer.CreateProof(http.MethodGet, targetURL, bearerToken, "")
+		var _, err = verifier.VerifyDPoP(bearerToken, proof, http.MethodGet, targetURL)
+		assert.Equal(t, keycloak.MsgErrInvalidToken+".cnf", err.(keycloak.ClientDetailedError).Message)
+	})
+	t.Run("Proof of another request", func(t *testing.T) {
+		var proof, _ = signer.CreateProof(http.MethodPost, targetURL, accessToken, "")
+		var _, err = verifier.VerifyDPoP(accessToken, proof, http.MethodGet, targetURL)
+		assert.NotNil(t, err)
+	})
+	t.Run("Invalid token", func(t *testing.T) {
+		var token = generateJWT(keycloakServer.externalURL + "/auth/realms/my-realm")
+		var proof, _ = signer.CreateProof(http.MethodGet, targetURL, token, "")
+		var _, err = verifier.VerifyDPoP(token, proof, http.MethodGet, targetURL)
+		assert.NotNil(t, err)
+	})
+}
+
+// dpopTokenEndpoint requires a nonce in the DPoP proofs of the token requests
+type dpopTokenEndpoint struct {
+	proofs []string
+}
+
+func (de *dpopTokenEndpoint) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var proof = r.Header.Get(DPoPHeader)
+	de.proofs = append(de.proofs, proof)
+	var token, _, err = jwt.NewParser().ParseUnverified(proof, jwt.MapClaims{})
+	w.Header().Set("Content-Type", "application/json")
+	if err != nil || token.Claims.(jwt.MapClaims)["nonce"] != "server-nonce" {
+		w.Header().Set(DPoPNonceHeader, "server-nonce")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error": "use_dpop_nonce"}`))
+		return
+	}
+	w.Write([]byte(`{"access_token": "dpop-token", "token_type": "DPoP", "expires_in": 300}`))
+}
+
+func TestDPoPTokenProvider(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	var mockLogger = mock.NewLogger(mockCtrl)
+	var endpoint = &dpopTokenEndpoint{}
+	var ts = httptest.NewServer(endpoint)
+	defer ts.Close()
+
+	var uriProvider, _ = NewKeycloakURIProviderFromArray([]string{"http://public.domain.ch"})
+	var kcConfig = keycloak.Config{URIProvider: uriProvider, AddrInternalAPI: ts.URL}
+
+	t.Run("Bearer tokens", func(t *testing.T) {
+		var provider = NewOAuth2TokenProvider(kcConfig, createServiceAccount("master", "gateway", "secret"), mockLogger)
+		assert.Nil(t, DPoPSignerOf(provider))
+	})
+	t.Run("DPoP-bound tokens", func(t *testing.T) {
+		var config = createServiceAccount("master", "gateway", "secret")
+		var dpop = true
+		config.DPoP = &dpop
+		var provider = NewOAuth2TokenProvider(kcConfig, config, mockLogger)
+		var signer = DPoPSignerOf(provider)
+		assert.NotNil(t, signer)
+
+		var token, err = provider.ProvideToken(context.TODO())
+		assert.Nil(t, err)
+		assert.Equal(t, "dpop-token", token)
+		// The first request is sent again with the nonce required by Keycloak
+		assert.Len(t, endpoint.proofs, 2)
+		var proof, _, _ = jwt.NewParser().ParseUnverified(endpoint.proofs[1], jwt.MapClaims{})
+		var claims = proof.Claims.(jwt.MapClaims)
+		assert.Equal(t, http.MethodPost, claims["htm"])
+		assert.Equal(t, "https://public.domain.ch/auth/realms/master/protocol/openid-connect/token", claims["htu"])
+		assert.Equal(t, "server-nonce", claims["nonce"])
+	})
+	t.Run("DPoP on a technical user", func(t *testing.T) {
+		var config = createTechnicalUser("master", "user", "password", "admin-cli")
+		var dpop = true
+		config.DPoP = &dpop
+		mockLogger.EXPECT().Warn(gomock.Any(), "msg", gomock.Any())
+		var provider = NewOAuth2TokenProvider(kcConfig, config, mockLogger)
+		assert.Nil(t, DPoPSignerOf(provider))
+	})
+}
+
+func TestDPoPReplayCacheOutlivesVerifiers(t *testing.T) {
+	var keycloakServer = newSigningKeycloak("https://public.domain.ch")
+	var ts = httptest.NewServer(keycloakServer)
+	defer ts.Close()
+
+	var internalURL, _ = url.Parse(ts.URL)
+	var externalURL, _ = url.Parse(keycloakServer.externalURL)
+	var verifierCache = NewVerifierCacheWithConfig(internalURL, externalURL, VerifierConfig{CacheTTL: time.Millisecond})
+
+	var signer, _ = GenerateDPoPSigner()
+	var accessToken = keycloakServer.sign("my-realm", jwt.MapClaims{"cnf": map[string]any{"jkt": signer.Thumbprint()}})
+	var targetURL = "https://api.domain.ch/accounts"
+	var proof, _ = signer.CreateProof(http.MethodGet, targetURL, accessToken, "")
+
+	var verifier, err = verifierCache.GetOidcVerifier("my-realm")
+	assert.Nil(t, err)
+	_, err = verifier.VerifyDPoP(accessToken, proof, http.MethodGet, targetURL)
+	assert.Nil(t, err)
+
+	time.Sleep(5 * time.Millisecond)
+	verifier, err = verifierCache.GetOidcVerifier("my-realm")
+	assert.Nil(t, err)
+	assert.Equal(t, int32(2), keycloakServer.discoveries.Load())
+	_, err = verifier.VerifyDPoP(accessToken, proof, http.MethodGet, targetURL)
+	assert.Equal(t, keycloak.MsgErrInvalidToken+".dpop.jti", err.(keycloak.ClientDetailedError).Message)
+}
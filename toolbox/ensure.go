@@ -0,0 +1,400 @@
+package toolbox
+
+import (
+	"encoding/json"
+	"errors"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/cloudtrust/keycloak-client/v2"
+)
+
+// Status of an ensured resource
+const (
+	EnsureCreated   = "created"
+	EnsureUpdated   = "updated"
+	EnsureUnchanged = "unchanged"
+)
+
+// ResourcesClient is the part of the Keycloak client used by ResourceEnsurer
+type ResourcesClient interface {
+	GetUsers(accessToken string, reqRealmName, targetRealmName string, paramKV ...string) (keycloak.UsersPageRepresentation, error)
+	CreateUser(accessToken string, reqRealmName, targetRealmName string, user keycloak.UserRepresentation, paramKV ...string) (string, error)
+	UpdateUser(accessToken string, realmName, userID string, user keycloak.UserRepresentation) error
+	GetGroups(accessToken string, realmName string) ([]keycloak.GroupRepresentation, error)
+	CreateGroup(accessToken string, reqRealmName string, group keycloak.GroupRepresentation) (string, error)
+	CreateChildGroup(accessToken string, realmName string, parentGroupID string, group keycloak.GroupRepresentation) (string, error)
+	UpdateGroup(accessToken string, realmName string, groupID string, group keycloak.GroupRepresentation) error
+	GetRoles(accessToken string, realmName string) ([]keycloak.RoleRepresentation, error)
+	CreateRole(accessToken string, realmName string, role keycloak.RoleRepresentation) (string, error)
+	UpdateRole(accessToken string, realmName string, roleID string, role keycloak.RoleRepresentation) error
+	GetClients(accessToken string, realmName string, paramKV ...string) ([]keycloak.ClientRepresentation, error)
+	CreateClient(accessToken string, realmName string, clientRep keycloak.ClientRepresentation) (string, error)
+	UpdateClient(accessToken string, realmName, idClient string, clientRep keycloak.ClientRepresentation) error
+	GetClientRoles(accessToken string, realmName, idClient string) ([]keycloak.RoleRepresentation, error)
+	CreateClientRole(accessToken string, realmName, clientID string, role keycloak.RoleRepresentation) (string, error)
+	GetIdps(accessToken string, realmName string) ([]keycloak.IdentityProviderRepresentation, error)
+	CreateIdp(accessToken string, realmName string, idpRep keycloak.IdentityProviderRepresentation) error
+	UpdateIdp(accessToken string, realmName, idpAlias string, idpRep keycloak.IdentityProviderRepresentation) error
+	GetComponents(accessToken string, realmName string, paramKV ...string) ([]keycloak.ComponentRepresentation, error)
+	CreateComponent(accessToken string, realmName string, compRep keycloak.ComponentRepresentation) error
+	UpdateComponent(accessToken string, realmName, componentID string, componentRep keycloak.ComponentRepresentation) error
+}
+
+// EnsureResult describes what has been done to ensure a resource
+type EnsureResult struct {
+	ID      string   // Identifier of the resource. The alias for an identity provider
+	Status  string   // EnsureCreated, EnsureUpdated or EnsureUnchanged
+	Changed []string // JSON names of the updated fields
+}
+
+// ResourceEnsurer creates resources when they are missing and otherwise only updates the fields which differ. Resources
+// are looked up by their natural key: username, group path, role name, clientId, identity provider alias, or
+// component name, type and parent.
+type ResourceEnsurer struct {
+	client ResourcesClient
+}
+
+// NewResourceEnsurer creates a ResourceEnsurer
+func NewResourceEnsurer(client ResourcesClient) *ResourceEnsurer {
+	return &ResourceEnsurer{
+		client: client,
+	}
+}
+
+// EnsureUser ensures that a user with the given username exists with the given attributes
+func (re *ResourceEnsurer) EnsureUser(accessToken string, reqRealmName string, targetRealmName string, user keycloak.UserRepresentation) (EnsureResult, error) {
+	if user.Username == nil || *user.Username == "" {
+		return EnsureResult{}, errors.New(keycloak.MsgErrMissingParam + "." + keycloak.Username)
+	}
+	var page, err = re.client.GetUsers(accessToken, reqRealmName, targetRealmName, "username", *user.Username)
+	if err != nil {
+		return EnsureResult{}, err
+	}
+	var idx = slices.IndexFunc(page.Users, func(u keycloak.UserRepresentation) bool {
+		return u.Username != nil && strings.EqualFold(*u.Username, *user.Username)
+	})
+	if idx < 0 {
+		var location string
+		if location, err = re.client.CreateUser(accessToken, reqRealmName, targetRealmName, user); err != nil {
+			return EnsureResult{}, err
+		}
+		return createdResult(location), nil
+	}
+	// Keycloak stores the usernames in lower case
+	var username = strings.ToLower(*user.Username)
+	user.Username = &username
+	var current = page.Users[idx]
+	return patchResource(*current.ID, current, user, func(merged keycloak.UserRepresentation) error {
+		return re.client.UpdateUser(accessToken, targetRealmName, *current.ID, merged)
+	})
+}
+
+// EnsureGroup ensures that a group with the given path exists with the given attributes. When the path is empty, the
+// group is a top level one named after group.Name. Parent groups must exist.
+func (re *ResourceEnsurer) EnsureGroup(accessToken string, realmName string, group keycloak.GroupRepresentation) (EnsureResult, error) {
+	var path = ""
+	if group.Path != nil {
+		path = *group.Path
+	}
+	if path == "" && group.Name != nil {
+		path = "/" + *group.Name
+	}
+	var parts = strings.Split(strings.Trim(path, "/"), "/")
+	if parts[len(parts)-1] == "" {
+		return EnsureResult{}, errors.New(keycloak.MsgErrMissingParam + ".group")
+	}
+	var name = parts[len(parts)-1]
+	group.Name = &name
+	group.SubGroups = nil
+
+	var groups, err = re.client.GetGroups(accessToken, realmName)
+	if err != nil {
+		return EnsureResult{}, err
+	}
+	var parent *keycloak.GroupRepresentation
+	for _, part := range parts[:len(parts)-1] {
+		if parent = findGroup(groups, part); parent == nil {
+			return EnsureResult{}, errors.New(keycloak.MsgErrCannotObtain + ".group." + part)
+		}
+		groups = nil
+		if parent.SubGroups != nil {
+			groups = *parent.SubGroups
+		}
+	}
+
+	var current = findGroup(groups, name)
+	if current == nil {
+		var location string
+		if parent == nil {
+			location, err = re.client.CreateGroup(accessToken, realmName, group)
+		} else {
+			location, err = re.client.CreateChildGroup(accessToken, realmName, *parent.ID, group)
+		}
+		if err != nil {
+			return EnsureResult{}, err
+		}
+		return createdResult(location), nil
+	}
+	var existing = *current
+	existing.SubGroups = nil
+	group.Path = existing.Path
+	return patchResource(*existing.ID, existing, group, func(merged keycloak.GroupRepresentation) error {
+		return re.client.UpdateGroup(accessToken, realmName, *existing.ID, merged)
+	})
+}
+
+// EnsureRole ensures that a realm role with the given name exists with the given attributes
+func (re *ResourceEnsurer) EnsureRole(accessToken string, realmName string, role keycloak.RoleRepresentation) (EnsureResult, error) {
+	if role.Name == nil || *role.Name == "" {
+		return EnsureResult{}, errors.New(keycloak.MsgErrMissingParam + ".role")
+	}
+	var roles, err = re.client.GetRoles(accessToken, realmName)
+	if err != nil {
+		return EnsureResult{}, err
+	}
+	return re.ensureRole(accessToken, realmName, roles, role, func() (string, error) {
+		return re.client.CreateRole(accessToken, realmName, role)
+	})
+}
+
+// EnsureClientRole ensures that a role with the given name exists for the client identified by clientID (not the
+// technical identifier of the client)
+func (re *ResourceEnsurer) EnsureClientRole(accessToken string, realmName string, clientID string, role keycloak.RoleRepresentation) (EnsureResult, error) {
+	if role.Name == nil || *role.Name == "" {
+		return EnsureResult{}, errors.New(keycloak.MsgErrMissingParam + ".role")
+	}
+	var client, err = re.findClient(accessToken, realmName, clientID)
+	if err != nil {
+		return EnsureResult{}, err
+	}
+	if client == nil {
+		return EnsureResult{}, errors.New(keycloak.MsgErrCannotObtain + ".client." + clientID)
+	}
+	var roles []keycloak.RoleRepresentation
+	if roles, err = re.client.GetClientRoles(accessToken, realmName, *client.ID); err != nil {
+		return EnsureResult{}, err
+	}
+	return re.ensureRole(accessToken, realmName, roles, role, func() (string, error) {
+		return re.client.CreateClientRole(accessToken, realmName, *client.ID, role)
+	})
+}
+
+func (re *ResourceEnsurer) ensureRole(accessToken string, realmName string, roles []keycloak.RoleRepresentation, role keycloak.RoleRepresentation, create func() (string, error)) (EnsureResult, error) {
+	var idx = slices.IndexFunc(roles, func(r keycloak.RoleRepresentation) bool {
+		return r.Name != nil && *r.Name == *role.Name
+	})
+	if idx < 0 {
+		var location, err = create()
+		if err != nil {
+			return EnsureResult{}, err
+		}
+		return createdResult(location), nil
+	}
+	var current = roles[idx]
+	return patchResource(*current.ID, current, role, func(merged keycloak.RoleRepresentation) error {
+		return re.client.UpdateRole(accessToken, realmName, *current.ID, merged)
+	})
+}
+
+// EnsureClient ensures that a client with the given clientId exists with the given attributes
+func (re *ResourceEnsurer) EnsureClient(accessToken string, realmName string, clientRep keycloak.ClientRepresentation) (EnsureResult, error) {
+	if clientRep.ClientID == nil || *clientRep.ClientID == "" {
+		return EnsureResult{}, errors.New(keycloak.MsgErrMissingParam + ".clientId")
+	}
+	var current, err = re.findClient(accessToken, realmName, *clientRep.ClientID)
+	if err != nil {
+		return EnsureResult{}, err
+	}
+	if current == nil {
+		var location string
+		if location, err = re.client.CreateClient(accessToken, realmName, clientRep); err != nil {
+			return EnsureResult{}, err
+		}
+		return createdResult(location), nil
+	}
+	return patchResource(*current.ID, *current, clientRep, func(merged keycloak.ClientRepresentation) error {
+		return re.client.UpdateClient(accessToken, realmName, *current.ID, merged)
+	})
+}
+
+func (re *ResourceEnsurer) findClient(accessToken string, realmName string, clientID string) (*keycloak.ClientRepresentation, error) {
+	var clients, err = re.client.GetClients(accessToken, realmName, "clientId", clientID)
+	if err != nil {
+		return nil, err
+	}
+	for _, client := range clients {
+		if client.ClientID != nil && *client.ClientID == clientID {
+			return &client, nil
+		}
+	}
+	return nil, nil
+}
+
+// EnsureIdp ensures that an identity provider with the given alias exists with the given attributes
+func (re *ResourceEnsurer) EnsureIdp(accessToken string, realmName string, idp keycloak.IdentityProviderRepresentation) (EnsureResult, error) {
+	if idp.Alias == nil || *idp.Alias == "" {
+		return EnsureResult{}, errors.New(keycloak.MsgErrMissingParam + ".alias")
+	}
+	var idps, err = re.client.GetIdps(accessToken, realmName)
+	if err != nil {
+		return EnsureResult{}, err
+	}
+	var idx = slices.IndexFunc(idps, func(i keycloak.IdentityProviderRepresentation) bool {
+		return i.Alias != nil && *i.Alias == *idp.Alias
+	})
+	if idx < 0 {
+		if err = re.client.CreateIdp(accessToken, realmName, idp); err != nil {
+			return EnsureResult{}, err
+		}
+		return EnsureResult{ID: *idp.Alias, Status: EnsureCreated}, nil
+	}
+	return patchResource(*idp.Alias, idps[idx], idp, func(merged keycloak.IdentityProviderRepresentation) error {
+		return re.client.UpdateIdp(accessToken, realmName, *idp.Alias, merged)
+	})
+}
+
+// EnsureComponent ensures that a component with the given name, provider type and parent exists with the given attributes
+func (re *ResourceEnsurer) EnsureComponent(accessToken string, realmName string, component keycloak.ComponentRepresentation) (EnsureResult, error) {
+	if component.Name == nil || *component.Name == "" || component.ProviderType == nil || *component.ProviderType == "" {
+		return EnsureResult{}, errors.New(keycloak.MsgErrMissingParam + ".component")
+	}
+	var params = []string{"name", *component.Name, "type", *component.ProviderType}
+	if component.ParentID != nil {
+		params = append(params, "parent", *component.ParentID)
+	}
+	var current, err = re.findComponent(accessToken, realmName, component, params)
+	if err != nil {
+		return EnsureResult{}, err
+	}
+	if current == nil {
+		if err = re.client.CreateComponent(accessToken, realmName, component); err != nil {
+			return EnsureResult{}, err
+		}
+		// The identifier of the component is not returned on creation
+		if current, err = re.findComponent(accessToken, realmName, component, params); err != nil {
+			return EnsureResult{}, err
+		}
+		var res = EnsureResult{Status: EnsureCreated}
+		if current != nil && current.ID != nil {
+			res.ID = *current.ID
+		}
+		return res, nil
+	}
+	return patchResource(*current.ID, *current, component, func(merged keycloak.ComponentRepresentation) error {
+		return re.client.UpdateComponent(accessToken, realmName, *current.ID, merged)
+	})
+}
+
+func (re *ResourceEnsurer) findComponent(accessToken string, realmName string, component keycloak.ComponentRepresentation, params []string) (*keycloak.ComponentRepresentation, error) {
+	var components, err = re.client.GetComponents(accessToken, realmName, params...)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range components {
+		if c.Name != nil && *c.Name == *component.Name && c.ProviderType != nil && *c.ProviderType == *component.ProviderType &&
+			(component.ParentID == nil || (c.ParentID != nil && *c.ParentID == *component.ParentID)) {
+			return &c, nil
+		}
+	}
+	return nil, nil
+}
+
+func findGroup(groups []keycloak.GroupRepresentation, name string) *keycloak.GroupRepresentation {
+	for i := range groups {
+		if groups[i].Name != nil && *groups[i].Name == name {
+			return &groups[i]
+		}
+	}
+	return nil
+}
+
+func createdResult(location string) EnsureResult {
+	return EnsureResult{
+		ID:     location[strings.LastIndex(location, "/")+1:],
+		Status: EnsureCreated,
+	}
+}
+
+// patchResource overlays the fields set in desired over the current representation and calls update only when at
+// least one of these fields differs
+func patchResource[T any](id string, current T, desired T, update func(merged T) error) (EnsureResult, error) {
+	var merged, changed, err = mergeRepresentation(current, desired)
+	if err != nil {
+		return EnsureResult{}, err
+	}
+	if len(changed) == 0 {
+		return EnsureResult{ID: id, Status: EnsureUnchanged}, nil
+	}
+	if err = update(merged); err != nil {
+		return EnsureResult{}, err
+	}
+	return EnsureResult{ID: id, Status: EnsureUpdated, Changed: changed}, nil
+}
+
+// FieldDiff is a field of a representation whose current value differs from the desired one
+type FieldDiff struct {
+	Field   string `json:"field"`
+	Current any    `json:"current"`
+	Desired any    `json:"desired"`
+}
+
+// diffRepresentation compares the fields set in desired with the current representation
+func diffRepresentation[T any](current T, desired T) ([]FieldDiff, error) {
+	var currentFields, desiredFields map[string]any
+	if err := toJSONFields(current, &currentFields); err != nil {
+		return nil, err
+	}
+	if err := toJSONFields(desired, &desiredFields); err != nil {
+		return nil, err
+	}
+	var res []FieldDiff
+	for key, value := range desiredFields {
+		if !reflect.DeepEqual(currentFields[key], value) {
+			res = append(res, FieldDiff{Field: key, Current: currentFields[key], Desired: value})
+		}
+	}
+	slices.SortFunc(res, func(a, b FieldDiff) int {
+		return strings.Compare(a.Field, b.Field)
+	})
+	return res, nil
+}
+
+// mergeRepresentation returns current with the fields set in desired replaced wholesale. Maps and lists of desired,
+// such as attributes, are not merged with the current ones and current is not modified.
+func mergeRepresentation[T any](current T, desired T) (T, []string, error) {
+	var diffs, err = diffRepresentation(current, desired)
+	if err != nil || len(diffs) == 0 {
+		return current, nil, err
+	}
+	var changed []string
+	for _, diff := range diffs {
+		changed = append(changed, diff.Field)
+	}
+
+	var currentFields, desiredFields map[string]any
+	if err = toJSONFields(current, &currentFields); err != nil {
+		return current, nil, err
+	}
+	if err = toJSONFields(desired, &desiredFields); err != nil {
+		return current, nil, err
+	}
+	maps.Copy(currentFields, desiredFields)
+
+	var merged T
+	var bytes []byte
+	if bytes, err = json.Marshal(currentFields); err == nil {
+		err = json.Unmarshal(bytes, &merged)
+	}
+	return merged, changed, err
+}
+
+func toJSONFields(value any, fields *map[string]any) error {
+	var bytes, err = json.Marshal(value)
+	if err != nil {
+		return errors.New(keycloak.MsgErrCannotMarshal)
+	}
+	return json.Unmarshal(bytes, fields)
+}
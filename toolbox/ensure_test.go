@@ -0,0 +1,330 @@
+package toolbox
+
+import (
+	"testing"
+
+	"github.com/cloudtrust/keycloak-client/v2"
+	"github.com/cloudtrust/keycloak-client/v2/toolbox/mock"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+func boolPtr(value bool) *bool {
+	return &value
+}
+
+func TestEnsureUser(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	var mockClient = mock.NewResourcesClient(mockCtrl)
+	var ensurer = NewResourceEnsurer(mockClient)
+	var reqRealm = "master"
+	var existing = keycloak.UserRepresentation{ID: ptr("john-id"), Username: ptr("john"), Email: ptr("john@domain.ch"), Enabled: boolPtr(true)}
+
+	t.Run("Missing username", func(t *testing.T) {
+		var _, err = ensurer.EnsureUser(token, reqRealm, realm, keycloak.UserRepresentation{})
+		assert.NotNil(t, err)
+	})
+	t.Run("Can't get users", func(t *testing.T) {
+		mockClient.EXPECT().GetUsers(token, reqRealm, realm, "username", "john").Return(keycloak.UsersPageRepresentation{}, errAny)
+		var _, err = ensurer.EnsureUser(token, reqRealm, realm, keycloak.UserRepresentation{Username: ptr("john")})
+		assert.Equal(t, errAny, err)
+	})
+	t.Run("Create user", func(t *testing.T) {
+		var user = keycloak.UserRepresentation{Username: ptr("john")}
+		mockClient.EXPECT().GetUsers(token, reqRealm, realm, "username", "john").Return(keycloak.UsersPageRepresentation{
+			Users: []keycloak.UserRepresentation{{ID: ptr("johnny-id"), Username: ptr("johnny")}},
+		}, nil)
+		mockClient.EXPECT().CreateUser(token, reqRealm, realm, user).Return("http://localhost/users/new-id", nil)
+		var res, err = ensurer.EnsureUser(token, reqRealm, realm, user)
+		assert.Nil(t, err)
+		assert.Equal(t, EnsureResult{ID: "new-id", Status: EnsureCreated}, res)
+	})
+	t.Run("User is unchanged", func(t *testing.T) {
+		mockClient.EXPECT().GetUsers(token, reqRealm, realm, "username", "john").Return(keycloak.UsersPageRepresentation{Users: []keycloak.UserRepresentation{existing}}, nil)
+		var res, err = ensurer.EnsureUser(token, reqRealm, realm, keycloak.UserRepresentation{Username: ptr("john"), Enabled: boolPtr(true)})
+		assert.Nil(t, err)
+		assert.Equal(t, EnsureResult{ID: "john-id", Status: EnsureUnchanged}, res)
+	})
+	t.Run("Username in mixed case is unchanged", func(t *testing.T) {
+		mockClient.EXPECT().GetUsers(token, reqRealm, realm, "username", "John").Return(keycloak.UsersPageRepresentation{Users: []keycloak.UserRepresentation{existing}}, nil)
+		var res, err = ensurer.EnsureUser(token, reqRealm, realm, keycloak.UserRepresentation{Username: ptr("John"), Enabled: boolPtr(true)})
+		assert.Nil(t, err)
+		assert.Equal(t, EnsureResult{ID: "john-id", Status: EnsureUnchanged}, res)
+	})
+	t.Run("User is updated", func(t *testing.T) {
+		mockClient.EXPECT().GetUsers(token, reqRealm, realm, "username", "john").Return(keycloak.UsersPageRepresentation{Users: []keycloak.UserRepresentation{existing}}, nil)
+		mockClient.EXPECT().UpdateUser(token, realm, "john-id", gomock.Any()).DoAndReturn(func(_, _, _ string, user keycloak.UserRepresentation) error {
+			assert.Equal(t, "john@domain.ch", *user.Email)
+			assert.Equal(t, "John", *user.FirstName)
+			assert.False(t, *user.Enabled)
+			return nil
+		})
+		var res, err = ensurer.EnsureUser(token, reqRealm, realm, keycloak.UserRepresentation{Username: ptr("john"), FirstName: ptr("John"), Enabled: boolPtr(false)})
+		assert.Nil(t, err)
+		assert.Equal(t, EnsureResult{ID: "john-id", Status: EnsureUpdated, Changed: []string{"enabled", "firstName"}}, res)
+	})
+	t.Run("Attributes are replaced and converge", func(t *testing.T) {
+		var current = existing
+		current.Attributes = &keycloak.Attributes{"a": {"1"}, "b": {"2"}}
+		var desired = keycloak.UserRepresentation{Username: ptr("john"), Attributes: &keycloak.Attributes{"a": {"1"}}}
+
+		var updated keycloak.UserRepresentation
+		mockClient.EXPECT().GetUsers(token, reqRealm, realm, "username", "john").Return(keycloak.UsersPageRepresentation{Users: []keycloak.UserRepresentation{current}}, nil)
+		mockClient.EXPECT().UpdateUser(token, realm, "john-id", gomock.Any()).DoAndReturn(func(_, _, _ string, user keycloak.UserRepresentation) error {
+			updated = user
+			return nil
+		})
+		var res, err = ensurer.EnsureUser(token, reqRealm, realm, desired)
+		assert.Nil(t, err)
+		assert.Equal(t, EnsureResult{ID: "john-id", Status: EnsureUpdated, Changed: []string{"attributes"}}, res)
+		assert.Equal(t, keycloak.Attributes{"a": {"1"}}, *updated.Attributes)
+		assert.Equal(t, "john@domain.ch", *updated.Email)
+		// The current representation is not modified
+		assert.Len(t, *current.Attributes, 2)
+
+		// Ensuring the converged user again does not update it
+		mockClient.EXPECT().GetUsers(token, reqRealm, realm, "username", "john").Return(keycloak.UsersPageRepresentation{Users: []keycloak.UserRepresentation{updated}}, nil)
+		res, err = ensurer.EnsureUser(token, reqRealm, realm, desired)
+		assert.Nil(t, err)
+		assert.Equal(t, EnsureResult{ID: "john-id", Status: EnsureUnchanged}, res)
+	})
+	t.Run("Update fails", func(t *testing.T) {
+		mockClient.EXPECT().GetUsers(token, reqRealm, realm, "username", "john").Return(keycloak.UsersPageRepresentation{Users: []keycloak.UserRepresentation{existing}}, nil)
+		mockClient.EXPECT().UpdateUser(token, realm, "john-id", gomock.Any()).Return(errAny)
+		var _, err = ensurer.EnsureUser(token, reqRealm, realm, keycloak.UserRepresentation{Username: ptr("john"), FirstName: ptr("John")})
+		assert.Equal(t, errAny, err)
+	})
+}
+
+func TestEnsureGroup(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	var mockClient = mock.NewResourcesClient(mockCtrl)
+	var ensurer = NewResourceEnsurer(mockClient)
+	var groups = []keycloak.GroupRepresentation{
+		{ID: ptr("parent-id"), Name: ptr("parent"), Path: ptr("/parent"), SubGroups: &[]keycloak.GroupRepresentation{
+			{ID: ptr("child-id"), Name: ptr("child"), Path: ptr("/parent/child")},
+		}},
+	}
+
+	t.Run("Missing name", func(t *testing.T) {
+		var _, err = ensurer.EnsureGroup(token, realm, keycloak.GroupRepresentation{})
+		assert.NotNil(t, err)
+	})
+	t.Run("Can't get groups", func(t *testing.T) {
+		mockClient.EXPECT().GetGroups(token, realm).Return(nil, errAny)
+		var _, err = ensurer.EnsureGroup(token, realm, keycloak.GroupRepresentation{Name: ptr("parent")})
+		assert.Equal(t, errAny, err)
+	})
+	t.Run("Parent does not exist", func(t *testing.T) {
+		mockClient.EXPECT().GetGroups(token, realm).Return(groups, nil)
+		var _, err = ensurer.EnsureGroup(token, realm, keycloak.GroupRepresentation{Path: ptr("/unknown/child")})
+		assert.NotNil(t, err)
+	})
+	t.Run("Create top level group", func(t *testing.T) {
+		mockClient.EXPECT().GetGroups(token, realm).Return(groups, nil)
+		mockClient.EXPECT().CreateGroup(token, realm, keycloak.GroupRepresentation{Name: ptr("other")}).Return("http://localhost/groups/other-id", nil)
+		var res, err = ensurer.EnsureGroup(token, realm, keycloak.GroupRepresentation{Name: ptr("other")})
+		assert.Nil(t, err)
+		assert.Equal(t, EnsureResult{ID: "other-id", Status: EnsureCreated}, res)
+	})
+	t.Run("Create sub-group", func(t *testing.T) {
+		mockClient.EXPECT().GetGroups(token, realm).Return(groups, nil)
+		mockClient.EXPECT().CreateChildGroup(token, realm, "parent-id", gomock.Any()).Return("http://localhost/groups/other-id", nil)
+		var res, err = ensurer.EnsureGroup(token, realm, keycloak.GroupRepresentation{Path: ptr("/parent/other")})
+		assert.Nil(t, err)
+		assert.Equal(t, EnsureResult{ID: "other-id", Status: EnsureCreated}, res)
+	})
+	t.Run("Update sub-group", func(t *testing.T) {
+		var attributes = map[string]any{"key": []any{"value"}}
+		mockClient.EXPECT().GetGroups(token, realm).Return(groups, nil)
+		mockClient.EXPECT().UpdateGroup(token, realm, "child-id", gomock.Any()).Return(nil)
+		var res, err = ensurer.EnsureGroup(token, realm, keycloak.GroupRepresentation{Path: ptr("parent/child"), Attributes: &attributes})
+		assert.Nil(t, err)
+		assert.Equal(t, EnsureResult{ID: "child-id", Status: EnsureUpdated, Changed: []string{"attributes"}}, res)
+	})
+}
+
+func TestEnsureRoles(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	var mockClient = mock.NewResourcesClient(mockCtrl)
+	var ensurer = NewResourceEnsurer(mockClient)
+	var roles = []keycloak.RoleRepresentation{{ID: ptr("role-id"), Name: ptr("admin"), Description: ptr("Administrator")}}
+	var clients = []keycloak.ClientRepresentation{{ID: ptr("client-uuid"), ClientID: ptr("my-client")}}
+
+	t.Run("Missing role name", func(t *testing.T) {
+		var _, err = ensurer.EnsureRole(token, realm, keycloak.RoleRepresentation{})
+		assert.NotNil(t, err)
+		_, err = ensurer.EnsureClientRole(token, realm, "my-client", keycloak.RoleRepresentation{})
+		assert.NotNil(t, err)
+	})
+	t.Run("Create realm role", func(t *testing.T) {
+		var role = keycloak.RoleRepresentation{Name: ptr("reader")}
+		mockClient.EXPECT().GetRoles(token, realm).Return(roles, nil)
+		mockClient.EXPECT().CreateRole(token, realm, role).Return("http://localhost/roles/reader", nil)
+		var res, err = ensurer.EnsureRole(token, realm, role)
+		assert.Nil(t, err)
+		assert.Equal(t, EnsureCreated, res.Status)
+	})
+	t.Run("Realm role is unchanged", func(t *testing.T) {
+		mockClient.EXPECT().GetRoles(token, realm).Return(roles, nil)
+		var res, err = ensurer.EnsureRole(token, realm, keycloak.RoleRepresentation{Name: ptr("admin"), Description: ptr("Administrator")})
+		assert.Nil(t, err)
+		assert.Equal(t, EnsureResult{ID: "role-id", Status: EnsureUnchanged}, res)
+	})
+	t.Run("Client does not exist", func(t *testing.T) {
+		mockClient.EXPECT().GetClients(token, realm, "clientId", "unknown").Return(nil, nil)
+		var _, err = ensurer.EnsureClientRole(token, realm, "unknown", keycloak.RoleRepresentation{Name: ptr("admin")})
+		assert.NotNil(t, err)
+	})
+	t.Run("Can't get client roles", func(t *testing.T) {
+		mockClient.EXPECT().GetClients(token, realm, "clientId", "my-client").Return(clients, nil)
+		mockClient.EXPECT().GetClientRoles(token, realm, "client-uuid").Return(nil, errAny)
+		var _, err = ensurer.EnsureClientRole(token, realm, "my-client", keycloak.RoleRepresentation{Name: ptr("admin")})
+		assert.Equal(t, errAny, err)
+	})
+	t.Run("Create client role", func(t *testing.T) {
+		var role = keycloak.RoleRepresentation{Name: ptr("reader")}
+		mockClient.EXPECT().GetClients(token, realm, "clientId", "my-client").Return(clients, nil)
+		mockClient.EXPECT().GetClientRoles(token, realm, "client-uuid").Return(roles, nil)
+		mockClient.EXPECT().CreateClientRole(token, realm, "client-uuid", role).Return("http://localhost/roles/reader", nil)
+		var res, err = ensurer.EnsureClientRole(token, realm, "my-client", role)
+		assert.Nil(t, err)
+		assert.Equal(t, EnsureCreated, res.Status)
+	})
+	t.Run("Update client role", func(t *testing.T) {
+		mockClient.EXPECT().GetClients(token, realm, "clientId", "my-client").Return(clients, nil)
+		mockClient.EXPECT().GetClientRoles(token, realm, "client-uuid").Return(roles, nil)
+		mockClient.EXPECT().UpdateRole(token, realm, "role-id", gomock.Any()).Return(nil)
+		var res, err = ensurer.EnsureClientRole(token, realm, "my-client", keycloak.RoleRepresentation{Name: ptr("admin"), Description: ptr("Admin")})
+		assert.Nil(t, err)
+		assert.Equal(t, EnsureResult{ID: "role-id", Status: EnsureUpdated, Changed: []string{"description"}}, res)
+	})
+}
+
+func TestEnsureClient(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	var mockClient = mock.NewResourcesClient(mockCtrl)
+	var ensurer = NewResourceEnsurer(mockClient)
+	var client = keycloak.ClientRepresentation{ClientID: ptr("my-client"), Enabled: boolPtr(true)}
+
+	t.Run("Missing clientId", func(t *testing.T) {
+		var _, err = ensurer.EnsureClient(token, realm, keycloak.ClientRepresentation{})
+		assert.NotNil(t, err)
+	})
+	t.Run("Can't get clients", func(t *testing.T) {
+		mockClient.EXPECT().GetClients(token, realm, "clientId", "my-client").Return(nil, errAny)
+		var _, err = ensurer.EnsureClient(token, realm, client)
+		assert.Equal(t, errAny, err)
+	})
+	t.Run("Create client", func(t *testing.T) {
+		mockClient.EXPECT().GetClients(token, realm, "clientId", "my-client").Return(nil, nil)
+		mockClient.EXPECT().CreateClient(token, realm, client).Return("http://localhost/clients/client-uuid", nil)
+		var res, err = ensurer.EnsureClient(token, realm, client)
+		assert.Nil(t, err)
+		assert.Equal(t, EnsureResult{ID: "client-uuid", Status: EnsureCreated}, res)
+	})
+	t.Run("Update client", func(t *testing.T) {
+		mockClient.EXPECT().GetClients(token, realm, "clientId", "my-client").Return([]keycloak.ClientRepresentation{
+			{ID: ptr("client-uuid"), ClientID: ptr("my-client"), Enabled: boolPtr(false)},
+		}, nil)
+		mockClient.EXPECT().UpdateClient(token, realm, "client-uuid", gomock.Any()).Return(nil)
+		var res, err = ensurer.EnsureClient(token, realm, client)
+		assert.Nil(t, err)
+		assert.Equal(t, EnsureResult{ID: "client-uuid", Status: EnsureUpdated, Changed: []string{"enabled"}}, res)
+	})
+}
+
+func TestEnsureIdp(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	var mockClient = mock.NewResourcesClient(mockCtrl)
+	var ensurer = NewResourceEnsurer(mockClient)
+	var idp = keycloak.IdentityProviderRepresentation{Alias: ptr("my-idp"), DisplayName: ptr("My IdP")}
+
+	t.Run("Missing alias", func(t *testing.T) {
+		var _, err = ensurer.EnsureIdp(token, realm, keycloak.IdentityProviderRepresentation{})
+		assert.NotNil(t, err)
+	})
+	t.Run("Create IdP", func(t *testing.T) {
+		mockClient.EXPECT().GetIdps(token, realm).Return(nil, nil)
+		mockClient.EXPECT().CreateIdp(token, realm, idp).Return(nil)
+		var res, err = ensurer.EnsureIdp(token, realm, idp)
+		assert.Nil(t, err)
+		assert.Equal(t, EnsureResult{ID: "my-idp", Status: EnsureCreated}, res)
+	})
+	t.Run("Update IdP", func(t *testing.T) {
+		mockClient.EXPECT().GetIdps(token, realm).Return([]keycloak.IdentityProviderRepresentation{{Alias: ptr("my-idp"), InternalID: ptr("idp-id")}}, nil)
+		mockClient.EXPECT().UpdateIdp(token, realm, "my-idp", gomock.Any()).DoAndReturn(func(_, _, _ string, merged keycloak.IdentityProviderRepresentation) error {
+			assert.Equal(t, "idp-id", *merged.InternalID)
+			assert.Equal(t, "My IdP", *merged.DisplayName)
+			return nil
+		})
+		var res, err = ensurer.EnsureIdp(token, realm, idp)
+		assert.Nil(t, err)
+		assert.Equal(t, EnsureResult{ID: "my-idp", Status: EnsureUpdated, Changed: []string{"displayName"}}, res)
+	})
+}
+
+func TestEnsureComponent(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	var mockClient = mock.NewResourcesClient(mockCtrl)
+	var ensurer = NewResourceEnsurer(mockClient)
+	var component = keycloak.ComponentRepresentation{
+		Name:         ptr("my-component"),
+		ProviderType: ptr("my-type"),
+		ParentID:     ptr(realm),
+		Config:       map[string][]string{"key": {"value"}},
+	}
+	var params = []any{"name", "my-component", "type", "my-type", "parent", realm}
+
+	t.Run("Missing name", func(t *testing.T) {
+		var _, err = ensurer.EnsureComponent(token, realm, keycloak.ComponentRepresentation{})
+		assert.NotNil(t, err)
+	})
+	t.Run("Create component", func(t *testing.T) {
+		var created = component
+		created.ID = ptr("component-id")
+		gomock.InOrder(
+			mockClient.EXPECT().GetComponents(token, realm, params...).Return(nil, nil),
+			mockClient.EXPECT().CreateComponent(token, realm, component).Return(nil),
+			mockClient.EXPECT().GetComponents(token, realm, params...).Return([]keycloak.ComponentRepresentation{created}, nil),
+		)
+		var res, err = ensurer.EnsureComponent(token, realm, component)
+		assert.Nil(t, err)
+		assert.Equal(t, EnsureResult{ID: "component-id", Status: EnsureCreated}, res)
+	})
+	t.Run("Create component fails", func(t *testing.T) {
+		mockClient.EXPECT().GetComponents(token, realm, params...).Return(nil, nil)
+		mockClient.EXPECT().CreateComponent(token, realm, component).Return(errAny)
+		var _, err = ensurer.EnsureComponent(token, realm, component)
+		assert.Equal(t, errAny, err)
+	})
+	t.Run("Update component", func(t *testing.T) {
+		mockClient.EXPECT().GetComponents(token, realm, params...).Return([]keycloak.ComponentRepresentation{{
+			ID:           ptr("component-id"),
+			Name:         ptr("my-component"),
+			ProviderType: ptr("my-type"),
+			ParentID:     ptr(realm),
+			Config:       map[string][]string{"key": {"old"}, "other": {"value"}},
+		}}, nil)
+		mockClient.EXPECT().UpdateComponent(token, realm, "component-id", gomock.Any()).DoAndReturn(func(_, _, _ string, merged keycloak.ComponentRepresentation) error {
+			// The config of the component is replaced, not merged
+			assert.Equal(t, map[string][]string{"key": {"value"}}, merged.Config)
+			return nil
+		})
+		var res, err = ensurer.EnsureComponent(token, realm, component)
+		assert.Nil(t, err)
+		assert.Equal(t, EnsureResult{ID: "component-id", Status: EnsureUpdated, Changed: []string{"config"}}, res)
+	})
+}
@@ -31,12 +31,20 @@ func ContextWithForwarded(ctx context.Context, internalURL *url.URL, externalURL
 	if internalURL == nil || externalURL == nil {
 		return ctx
 	}
-	return oidc.ClientContext(ctx, &http.Client{
+	return oidc.ClientContext(ctx, newForwardedClient(internalURL, externalURL))
+}
+
+// newForwardedClient returns a http client which sends the requests for externalURL to internalURL with a Forwarded
+// header
+func newForwardedClient(internalURL *url.URL, externalURL *url.URL) *http.Client {
+	if internalURL == nil || externalURL == nil {
+		return http.DefaultClient
+	}
+	return &http.Client{
 		Transport: &forwardedTransport{
 			base:        http.DefaultTransport,
 			internalURL: internalURL,
 			externalURL: externalURL,
 		},
-	})
-
+	}
 }
@@ -28,8 +28,19 @@ func getProtocolAndDomain(URL string) string {
 	return URL
 }
 
+// SplitIssuer returns the base URL and the realm of a Keycloak issuer
+func SplitIssuer(issuer string) (string, string) {
+	var splitIssuer = strings.Split(issuer, "/realms/")
+	return splitIssuer[0], splitIssuer[len(splitIssuer)-1]
+}
+
 // NewIssuerManager creates a new URLProvider
 func NewIssuerManager(config keycloak.Config) (IssuerManager, error) {
+	return NewIssuerManagerWithVerifierConfig(config, VerifierConfig{})
+}
+
+// NewIssuerManagerWithVerifierConfig creates a new URLProvider whose verifiers check the options of verifierConfig
+func NewIssuerManagerWithVerifierConfig(config keycloak.Config, verifierConfig VerifierConfig) (IssuerManager, error) {
 	urlInternal, err := url.Parse(config.AddrInternalAPI)
 	if err != nil {
 		return nil, err
@@ -40,7 +51,7 @@ func NewIssuerManager(config keycloak.Config) (IssuerManager, error) {
 		if err != nil {
 			return nil, err
 		}
-		verifier := NewVerifierCache(urlInternal, uToken)
+		verifier := NewVerifierCacheWithConfig(urlInternal, uToken, verifierConfig)
 		domainToVerifier[getProtocolAndDomain(value)] = verifier
 	}
 	return &issuerManager{
@@ -0,0 +1,229 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/cloudtrust/keycloak-client/v2/toolbox (interfaces: AuthenticationFlowsClient)
+//
+// Generated by this command:
+//
+//	mockgen --build_flags=--mod=mod -destination=./mock/authentication_flows.go -package=mock -mock_names=AuthenticationFlowsClient=AuthenticationFlowsClient github.com/cloudtrust/keycloak-client/v2/toolbox AuthenticationFlowsClient
+//
+
+// Package mock is a generated GoMock package.
+package mock
+
+import (
+	reflect "reflect"
+
+	keycloak "github.com/cloudtrust/keycloak-client/v2"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// AuthenticationFlowsClient is a mock of AuthenticationFlowsClient interface.
+type AuthenticationFlowsClient struct {
+	ctrl     *gomock.Controller
+	recorder *AuthenticationFlowsClientMockRecorder
+	isgomock struct{}
+}
+
+// AuthenticationFlowsClientMockRecorder is the mock recorder for AuthenticationFlowsClient.
+type AuthenticationFlowsClientMockRecorder struct {
+	mock *AuthenticationFlowsClient
+}
+
+// NewAuthenticationFlowsClient creates a new mock instance.
+func NewAuthenticationFlowsClient(ctrl *gomock.Controller) *AuthenticationFlowsClient {
+	mock := &AuthenticationFlowsClient{ctrl: ctrl}
+	mock.recorder = &AuthenticationFlowsClientMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *AuthenticationFlowsClient) EXPECT() *AuthenticationFlowsClientMockRecorder {
+	return m.recorder
+}
+
+// CreateAuthenticationExecutionForFlow mocks base method.
+func (m *AuthenticationFlowsClient) CreateAuthenticationExecutionForFlow(accessToken, realmName, flowAlias, provider string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateAuthenticationExecutionForFlow", accessToken, realmName, flowAlias, provider)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateAuthenticationExecutionForFlow indicates an expected call of CreateAuthenticationExecutionForFlow.
+func (mr *AuthenticationFlowsClientMockRecorder) CreateAuthenticationExecutionForFlow(accessToken, realmName, flowAlias, provider any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateAuthenticationExecutionForFlow", reflect.TypeOf((*AuthenticationFlowsClient)(nil).CreateAuthenticationExecutionForFlow), accessToken, realmName, flowAlias, provider)
+}
+
+// CreateAuthenticationFlow mocks base method.
+func (m *AuthenticationFlowsClient) CreateAuthenticationFlow(accessToken, realmName string, authFlow keycloak.AuthenticationFlowRepresentation) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateAuthenticationFlow", accessToken, realmName, authFlow)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateAuthenticationFlow indicates an expected call of CreateAuthenticationFlow.
+func (mr *AuthenticationFlowsClientMockRecorder) CreateAuthenticationFlow(accessToken, realmName, authFlow any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateAuthenticationFlow", reflect.TypeOf((*AuthenticationFlowsClient)(nil).CreateAuthenticationFlow), accessToken, realmName, authFlow)
+}
+
+// CreateFlowWithExecutionForExistingFlow mocks base method.
+func (m *AuthenticationFlowsClient) CreateFlowWithExecutionForExistingFlow(accessToken, realmName, flowAlias, alias, flowType, provider, description string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateFlowWithExecutionForExistingFlow", accessToken, realmName, flowAlias, alias, flowType, provider, description)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateFlowWithExecutionForExistingFlow indicates an expected call of CreateFlowWithExecutionForExistingFlow.
+func (mr *AuthenticationFlowsClientMockRecorder) CreateFlowWithExecutionForExistingFlow(accessToken, realmName, flowAlias, alias, flowType, provider, description any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateFlowWithExecutionForExistingFlow", reflect.TypeOf((*AuthenticationFlowsClient)(nil).CreateFlowWithExecutionForExistingFlow), accessToken, realmName, flowAlias, alias, flowType, provider, description)
+}
+
+// DeleteAuthenticationExecution mocks base method.
+func (m *AuthenticationFlowsClient) DeleteAuthenticationExecution(accessToken, realmName, executionID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteAuthenticationExecution", accessToken, realmName, executionID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteAuthenticationExecution indicates an expected call of DeleteAuthenticationExecution.
+func (mr *AuthenticationFlowsClientMockRecorder) DeleteAuthenticationExecution(accessToken, realmName, executionID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteAuthenticationExecution", reflect.TypeOf((*AuthenticationFlowsClient)(nil).DeleteAuthenticationExecution), accessToken, realmName, executionID)
+}
+
+// GetAuthenticationExecutionsForFlow mocks base method.
+func (m *AuthenticationFlowsClient) GetAuthenticationExecutionsForFlow(accessToken, realmName, flowAlias string) ([]keycloak.AuthenticationExecutionInfoRepresentation, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAuthenticationExecutionsForFlow", accessToken, realmName, flowAlias)
+	ret0, _ := ret[0].([]keycloak.AuthenticationExecutionInfoRepresentation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAuthenticationExecutionsForFlow indicates an expected call of GetAuthenticationExecutionsForFlow.
+func (mr *AuthenticationFlowsClientMockRecorder) GetAuthenticationExecutionsForFlow(accessToken, realmName, flowAlias any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAuthenticationExecutionsForFlow", reflect.TypeOf((*AuthenticationFlowsClient)(nil).GetAuthenticationExecutionsForFlow), accessToken, realmName, flowAlias)
+}
+
+// GetAuthenticationFlow mocks base method.
+func (m *AuthenticationFlowsClient) GetAuthenticationFlow(accessToken, realmName, flowID string) (keycloak.AuthenticationFlowRepresentation, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAuthenticationFlow", accessToken, realmName, flowID)
+	ret0, _ := ret[0].(keycloak.AuthenticationFlowRepresentation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAuthenticationFlow indicates an expected call of GetAuthenticationFlow.
+func (mr *AuthenticationFlowsClientMockRecorder) GetAuthenticationFlow(accessToken, realmName, flowID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAuthenticationFlow", reflect.TypeOf((*AuthenticationFlowsClient)(nil).GetAuthenticationFlow), accessToken, realmName, flowID)
+}
+
+// GetAuthenticationFlows mocks base method.
+func (m *AuthenticationFlowsClient) GetAuthenticationFlows(accessToken, realmName string) ([]keycloak.AuthenticationFlowRepresentation, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAuthenticationFlows", accessToken, realmName)
+	ret0, _ := ret[0].([]keycloak.AuthenticationFlowRepresentation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAuthenticationFlows indicates an expected call of GetAuthenticationFlows.
+func (mr *AuthenticationFlowsClientMockRecorder) GetAuthenticationFlows(accessToken, realmName any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAuthenticationFlows", reflect.TypeOf((*AuthenticationFlowsClient)(nil).GetAuthenticationFlows), accessToken, realmName)
+}
+
+// GetAuthenticatorConfig mocks base method.
+func (m *AuthenticationFlowsClient) GetAuthenticatorConfig(accessToken, realmName, configID string) (keycloak.AuthenticatorConfigRepresentation, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAuthenticatorConfig", accessToken, realmName, configID)
+	ret0, _ := ret[0].(keycloak.AuthenticatorConfigRepresentation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAuthenticatorConfig indicates an expected call of GetAuthenticatorConfig.
+func (mr *AuthenticationFlowsClientMockRecorder) GetAuthenticatorConfig(accessToken, realmName, configID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAuthenticatorConfig", reflect.TypeOf((*AuthenticationFlowsClient)(nil).GetAuthenticatorConfig), accessToken, realmName, configID)
+}
+
+// RaiseExecutionPriority mocks base method.
+func (m *AuthenticationFlowsClient) RaiseExecutionPriority(accessToken, realmName, executionID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RaiseExecutionPriority", accessToken, realmName, executionID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RaiseExecutionPriority indicates an expected call of RaiseExecutionPriority.
+func (mr *AuthenticationFlowsClientMockRecorder) RaiseExecutionPriority(accessToken, realmName, executionID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RaiseExecutionPriority", reflect.TypeOf((*AuthenticationFlowsClient)(nil).RaiseExecutionPriority), accessToken, realmName, executionID)
+}
+
+// UpdateAuthenticationExecution mocks base method.
+func (m *AuthenticationFlowsClient) UpdateAuthenticationExecution(accessToken, realmName, executionID string, authConfig keycloak.AuthenticatorConfigRepresentation) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateAuthenticationExecution", accessToken, realmName, executionID, authConfig)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateAuthenticationExecution indicates an expected call of UpdateAuthenticationExecution.
+func (mr *AuthenticationFlowsClientMockRecorder) UpdateAuthenticationExecution(accessToken, realmName, executionID, authConfig any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateAuthenticationExecution", reflect.TypeOf((*AuthenticationFlowsClient)(nil).UpdateAuthenticationExecution), accessToken, realmName, executionID, authConfig)
+}
+
+// UpdateAuthenticationExecutionForFlow mocks base method.
+func (m *AuthenticationFlowsClient) UpdateAuthenticationExecutionForFlow(accessToken, realmName, flowAlias string, authExecInfo keycloak.AuthenticationExecutionInfoRepresentation) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateAuthenticationExecutionForFlow", accessToken, realmName, flowAlias, authExecInfo)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateAuthenticationExecutionForFlow indicates an expected call of UpdateAuthenticationExecutionForFlow.
+func (mr *AuthenticationFlowsClientMockRecorder) UpdateAuthenticationExecutionForFlow(accessToken, realmName, flowAlias, authExecInfo any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateAuthenticationExecutionForFlow", reflect.TypeOf((*AuthenticationFlowsClient)(nil).UpdateAuthenticationExecutionForFlow), accessToken, realmName, flowAlias, authExecInfo)
+}
+
+// UpdateAuthenticationFlow mocks base method.
+func (m *AuthenticationFlowsClient) UpdateAuthenticationFlow(accessToken, realmName, flowID string, authFlow keycloak.AuthenticationFlowRepresentation) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateAuthenticationFlow", accessToken, realmName, flowID, authFlow)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateAuthenticationFlow indicates an expected call of UpdateAuthenticationFlow.
+func (mr *AuthenticationFlowsClientMockRecorder) UpdateAuthenticationFlow(accessToken, realmName, flowID, authFlow any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateAuthenticationFlow", reflect.TypeOf((*AuthenticationFlowsClient)(nil).UpdateAuthenticationFlow), accessToken, realmName, flowID, authFlow)
+}
+
+// UpdateAuthenticatorConfig mocks base method.
+func (m *AuthenticationFlowsClient) UpdateAuthenticatorConfig(accessToken, realmName, configID string, config keycloak.AuthenticatorConfigRepresentation) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateAuthenticatorConfig", accessToken, realmName, configID, config)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateAuthenticatorConfig indicates an expected call of UpdateAuthenticatorConfig.
+func (mr *AuthenticationFlowsClientMockRecorder) UpdateAuthenticatorConfig(accessToken, realmName, configID, config any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateAuthenticatorConfig", reflect.TypeOf((*AuthenticationFlowsClient)(nil).UpdateAuthenticatorConfig), accessToken, realmName, configID, config)
+}
@@ -0,0 +1,105 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/cloudtrust/keycloak-client/v2/toolbox (interfaces: BruteForceClient)
+//
+// Generated by this command:
+//
+//	mockgen --build_flags=--mod=mod -destination=./mock/brute_force.go -package=mock -mock_names=BruteForceClient=BruteForceClient github.com/cloudtrust/keycloak-client/v2/toolbox BruteForceClient
+//
+
+// Package mock is a generated GoMock package.
+package mock
+
+import (
+	reflect "reflect"
+
+	keycloak "github.com/cloudtrust/keycloak-client/v2"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// BruteForceClient is a mock of BruteForceClient interface.
+type BruteForceClient struct {
+	ctrl     *gomock.Controller
+	recorder *BruteForceClientMockRecorder
+	isgomock struct{}
+}
+
+// BruteForceClientMockRecorder is the mock recorder for BruteForceClient.
+type BruteForceClientMockRecorder struct {
+	mock *BruteForceClient
+}
+
+// NewBruteForceClient creates a new mock instance.
+func NewBruteForceClient(ctrl *gomock.Controller) *BruteForceClient {
+	mock := &BruteForceClient{ctrl: ctrl}
+	mock.recorder = &BruteForceClientMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *BruteForceClient) EXPECT() *BruteForceClientMockRecorder {
+	return m.recorder
+}
+
+// ClearUserLoginFailures mocks base method.
+func (m *BruteForceClient) ClearUserLoginFailures(accessToken, realmName, userID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ClearUserLoginFailures", accessToken, realmName, userID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ClearUserLoginFailures indicates an expected call of ClearUserLoginFailures.
+func (mr *BruteForceClientMockRecorder) ClearUserLoginFailures(accessToken, realmName, userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClearUserLoginFailures", reflect.TypeOf((*BruteForceClient)(nil).ClearUserLoginFailures), accessToken, realmName, userID)
+}
+
+// GetBruteForceStatus mocks base method.
+func (m *BruteForceClient) GetBruteForceStatus(accessToken, realmName, userID string) (keycloak.BruteForceStatusRepresentation, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetBruteForceStatus", accessToken, realmName, userID)
+	ret0, _ := ret[0].(keycloak.BruteForceStatusRepresentation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetBruteForceStatus indicates an expected call of GetBruteForceStatus.
+func (mr *BruteForceClientMockRecorder) GetBruteForceStatus(accessToken, realmName, userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBruteForceStatus", reflect.TypeOf((*BruteForceClient)(nil).GetBruteForceStatus), accessToken, realmName, userID)
+}
+
+// GetRealm mocks base method.
+func (m *BruteForceClient) GetRealm(accessToken, realmName string) (keycloak.RealmRepresentation, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetRealm", accessToken, realmName)
+	ret0, _ := ret[0].(keycloak.RealmRepresentation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetRealm indicates an expected call of GetRealm.
+func (mr *BruteForceClientMockRecorder) GetRealm(accessToken, realmName any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRealm", reflect.TypeOf((*BruteForceClient)(nil).GetRealm), accessToken, realmName)
+}
+
+// GetUsers mocks base method.
+func (m *BruteForceClient) GetUsers(accessToken, reqRealmName, targetRealmName string, paramKV ...string) (keycloak.UsersPageRepresentation, error) {
+	m.ctrl.T.Helper()
+	varargs := []any{accessToken, reqRealmName, targetRealmName}
+	for _, a := range paramKV {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "GetUsers", varargs...)
+	ret0, _ := ret[0].(keycloak.UsersPageRepresentation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUsers indicates an expected call of GetUsers.
+func (mr *BruteForceClientMockRecorder) GetUsers(accessToken, reqRealmName, targetRealmName any, paramKV ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]any{accessToken, reqRealmName, targetRealmName}, paramKV...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUsers", reflect.TypeOf((*BruteForceClient)(nil).GetUsers), varargs...)
+}
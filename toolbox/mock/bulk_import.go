@@ -0,0 +1,151 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/cloudtrust/keycloak-client/v2/toolbox (interfaces: BulkImportClient)
+//
+// Generated by this command:
+//
+//	mockgen --build_flags=--mod=mod -destination=./mock/bulk_import.go -package=mock -mock_names=BulkImportClient=BulkImportClient github.com/cloudtrust/keycloak-client/v2/toolbox BulkImportClient
+//
+
+// Package mock is a generated GoMock package.
+package mock
+
+import (
+	reflect "reflect"
+
+	keycloak "github.com/cloudtrust/keycloak-client/v2"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// BulkImportClient is a mock of BulkImportClient interface.
+type BulkImportClient struct {
+	ctrl     *gomock.Controller
+	recorder *BulkImportClientMockRecorder
+	isgomock struct{}
+}
+
+// BulkImportClientMockRecorder is the mock recorder for BulkImportClient.
+type BulkImportClientMockRecorder struct {
+	mock *BulkImportClient
+}
+
+// NewBulkImportClient creates a new mock instance.
+func NewBulkImportClient(ctrl *gomock.Controller) *BulkImportClient {
+	mock := &BulkImportClient{ctrl: ctrl}
+	mock.recorder = &BulkImportClientMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *BulkImportClient) EXPECT() *BulkImportClientMockRecorder {
+	return m.recorder
+}
+
+// AddClientRolesToUserRoleMapping mocks base method.
+func (m *BulkImportClient) AddClientRolesToUserRoleMapping(accessToken, realmName, userID, clientID string, roles []keycloak.RoleRepresentation) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddClientRolesToUserRoleMapping", accessToken, realmName, userID, clientID, roles)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddClientRolesToUserRoleMapping indicates an expected call of AddClientRolesToUserRoleMapping.
+func (mr *BulkImportClientMockRecorder) AddClientRolesToUserRoleMapping(accessToken, realmName, userID, clientID, roles any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddClientRolesToUserRoleMapping", reflect.TypeOf((*BulkImportClient)(nil).AddClientRolesToUserRoleMapping), accessToken, realmName, userID, clientID, roles)
+}
+
+// AddGroupToUser mocks base method.
+func (m *BulkImportClient) AddGroupToUser(accessToken, realmName, userID, groupID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddGroupToUser", accessToken, realmName, userID, groupID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddGroupToUser indicates an expected call of AddGroupToUser.
+func (mr *BulkImportClientMockRecorder) AddGroupToUser(accessToken, realmName, userID, groupID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddGroupToUser", reflect.TypeOf((*BulkImportClient)(nil).AddGroupToUser), accessToken, realmName, userID, groupID)
+}
+
+// AddRealmLevelRoleMappings mocks base method.
+func (m *BulkImportClient) AddRealmLevelRoleMappings(accessToken, realmName, userID string, roles []keycloak.RoleRepresentation) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddRealmLevelRoleMappings", accessToken, realmName, userID, roles)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddRealmLevelRoleMappings indicates an expected call of AddRealmLevelRoleMappings.
+func (mr *BulkImportClientMockRecorder) AddRealmLevelRoleMappings(accessToken, realmName, userID, roles any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddRealmLevelRoleMappings", reflect.TypeOf((*BulkImportClient)(nil).AddRealmLevelRoleMappings), accessToken, realmName, userID, roles)
+}
+
+// CreateUser mocks base method.
+func (m *BulkImportClient) CreateUser(accessToken, reqRealmName, targetRealmName string, user keycloak.UserRepresentation, paramKV ...string) (string, error) {
+	m.ctrl.T.Helper()
+	varargs := []any{accessToken, reqRealmName, targetRealmName, user}
+	for _, a := range paramKV {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "CreateUser", varargs...)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateUser indicates an expected call of CreateUser.
+func (mr *BulkImportClientMockRecorder) CreateUser(accessToken, reqRealmName, targetRealmName, user any, paramKV ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]any{accessToken, reqRealmName, targetRealmName, user}, paramKV...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateUser", reflect.TypeOf((*BulkImportClient)(nil).CreateUser), varargs...)
+}
+
+// GetUsers mocks base method.
+func (m *BulkImportClient) GetUsers(accessToken, reqRealmName, targetRealmName string, paramKV ...string) (keycloak.UsersPageRepresentation, error) {
+	m.ctrl.T.Helper()
+	varargs := []any{accessToken, reqRealmName, targetRealmName}
+	for _, a := range paramKV {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "GetUsers", varargs...)
+	ret0, _ := ret[0].(keycloak.UsersPageRepresentation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUsers indicates an expected call of GetUsers.
+func (mr *BulkImportClientMockRecorder) GetUsers(accessToken, reqRealmName, targetRealmName any, paramKV ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]any{accessToken, reqRealmName, targetRealmName}, paramKV...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUsers", reflect.TypeOf((*BulkImportClient)(nil).GetUsers), varargs...)
+}
+
+// ResetPassword mocks base method.
+func (m *BulkImportClient) ResetPassword(accessToken, realmName, userID string, cred keycloak.CredentialRepresentation) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ResetPassword", accessToken, realmName, userID, cred)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ResetPassword indicates an expected call of ResetPassword.
+func (mr *BulkImportClientMockRecorder) ResetPassword(accessToken, realmName, userID, cred any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResetPassword", reflect.TypeOf((*BulkImportClient)(nil).ResetPassword), accessToken, realmName, userID, cred)
+}
+
+// UpdateUser mocks base method.
+func (m *BulkImportClient) UpdateUser(accessToken, realmName, userID string, user keycloak.UserRepresentation) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateUser", accessToken, realmName, userID, user)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateUser indicates an expected call of UpdateUser.
+func (mr *BulkImportClientMockRecorder) UpdateUser(accessToken, realmName, userID, user any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateUser", reflect.TypeOf((*BulkImportClient)(nil).UpdateUser), accessToken, realmName, userID, user)
+}
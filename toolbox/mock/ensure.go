@@ -0,0 +1,368 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/cloudtrust/keycloak-client/v2/toolbox (interfaces: ResourcesClient)
+//
+// Generated by this command:
+//
+//	mockgen --build_flags=--mod=mod -destination=./mock/ensure.go -package=mock -mock_names=ResourcesClient=ResourcesClient github.com/cloudtrust/keycloak-client/v2/toolbox ResourcesClient
+//
+
+// Package mock is a generated GoMock package.
+package mock
+
+import (
+	reflect "reflect"
+
+	keycloak "github.com/cloudtrust/keycloak-client/v2"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// ResourcesClient is a mock of ResourcesClient interface.
+type ResourcesClient struct {
+	ctrl     *gomock.Controller
+	recorder *ResourcesClientMockRecorder
+	isgomock struct{}
+}
+
+// ResourcesClientMockRecorder is the mock recorder for ResourcesClient.
+type ResourcesClientMockRecorder struct {
+	mock *ResourcesClient
+}
+
+// NewResourcesClient creates a new mock instance.
+func NewResourcesClient(ctrl *gomock.Controller) *ResourcesClient {
+	mock := &ResourcesClient{ctrl: ctrl}
+	mock.recorder = &ResourcesClientMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *ResourcesClient) EXPECT() *ResourcesClientMockRecorder {
+	return m.recorder
+}
+
+// CreateChildGroup mocks base method.
+func (m *ResourcesClient) CreateChildGroup(accessToken, realmName, parentGroupID string, group keycloak.GroupRepresentation) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateChildGroup", accessToken, realmName, parentGroupID, group)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateChildGroup indicates an expected call of CreateChildGroup.
+func (mr *ResourcesClientMockRecorder) CreateChildGroup(accessToken, realmName, parentGroupID, group any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateChildGroup", reflect.TypeOf((*ResourcesClient)(nil).CreateChildGroup), accessToken, realmName, parentGroupID, group)
+}
+
+// CreateClient mocks base method.
+func (m *ResourcesClient) CreateClient(accessToken, realmName string, clientRep keycloak.ClientRepresentation) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateClient", accessToken, realmName, clientRep)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateClient indicates an expected call of CreateClient.
+func (mr *ResourcesClientMockRecorder) CreateClient(accessToken, realmName, clientRep any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateClient", reflect.TypeOf((*ResourcesClient)(nil).CreateClient), accessToken, realmName, clientRep)
+}
+
+// CreateClientRole mocks base method.
+func (m *ResourcesClient) CreateClientRole(accessToken, realmName, clientID string, role keycloak.RoleRepresentation) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateClientRole", accessToken, realmName, clientID, role)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateClientRole indicates an expected call of CreateClientRole.
+func (mr *ResourcesClientMockRecorder) CreateClientRole(accessToken, realmName, clientID, role any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateClientRole", reflect.TypeOf((*ResourcesClient)(nil).CreateClientRole), accessToken, realmName, clientID, role)
+}
+
+// CreateComponent mocks base method.
+func (m *ResourcesClient) CreateComponent(accessToken, realmName string, compRep keycloak.ComponentRepresentation) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateComponent", accessToken, realmName, compRep)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateComponent indicates an expected call of CreateComponent.
+func (mr *ResourcesClientMockRecorder) CreateComponent(accessToken, realmName, compRep any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateComponent", reflect.TypeOf((*ResourcesClient)(nil).CreateComponent), accessToken, realmName, compRep)
+}
+
+// CreateGroup mocks base method.
+func (m *ResourcesClient) CreateGroup(accessToken, reqRealmName string, group keycloak.GroupRepresentation) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateGroup", accessToken, reqRealmName, group)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateGroup indicates an expected call of CreateGroup.
+func (mr *ResourcesClientMockRecorder) CreateGroup(accessToken, reqRealmName, group any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateGroup", reflect.TypeOf((*ResourcesClient)(nil).CreateGroup), accessToken, reqRealmName, group)
+}
+
+// CreateIdp mocks base method.
+func (m *ResourcesClient) CreateIdp(accessToken, realmName string, idpRep keycloak.IdentityProviderRepresentation) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateIdp", accessToken, realmName, idpRep)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateIdp indicates an expected call of CreateIdp.
+func (mr *ResourcesClientMockRecorder) CreateIdp(accessToken, realmName, idpRep any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateIdp", reflect.TypeOf((*ResourcesClient)(nil).CreateIdp), accessToken, realmName, idpRep)
+}
+
+// CreateRole mocks base method.
+func (m *ResourcesClient) CreateRole(accessToken, realmName string, role keycloak.RoleRepresentation) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateRole", accessToken, realmName, role)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateRole indicates an expected call of CreateRole.
+func (mr *ResourcesClientMockRecorder) CreateRole(accessToken, realmName, role any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateRole", reflect.TypeOf((*ResourcesClient)(nil).CreateRole), accessToken, realmName, role)
+}
+
+// CreateUser mocks base method.
+func (m *ResourcesClient) CreateUser(accessToken, reqRealmName, targetRealmName string, user keycloak.UserRepresentation, paramKV ...string) (string, error) {
+	m.ctrl.T.Helper()
+	varargs := []any{accessToken, reqRealmName, targetRealmName, user}
+	for _, a := range paramKV {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "CreateUser", varargs...)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateUser indicates an expected call of CreateUser.
+func (mr *ResourcesClientMockRecorder) CreateUser(accessToken, reqRealmName, targetRealmName, user any, paramKV ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]any{accessToken, reqRealmName, targetRealmName, user}, paramKV...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateUser", reflect.TypeOf((*ResourcesClient)(nil).CreateUser), varargs...)
+}
+
+// GetClientRoles mocks base method.
+func (m *ResourcesClient) GetClientRoles(accessToken, realmName, idClient string) ([]keycloak.RoleRepresentation, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetClientRoles", accessToken, realmName, idClient)
+	ret0, _ := ret[0].([]keycloak.RoleRepresentation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetClientRoles indicates an expected call of GetClientRoles.
+func (mr *ResourcesClientMockRecorder) GetClientRoles(accessToken, realmName, idClient any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetClientRoles", reflect.TypeOf((*ResourcesClient)(nil).GetClientRoles), accessToken, realmName, idClient)
+}
+
+// GetClients mocks base method.
+func (m *ResourcesClient) GetClients(accessToken, realmName string, paramKV ...string) ([]keycloak.ClientRepresentation, error) {
+	m.ctrl.T.Helper()
+	varargs := []any{accessToken, realmName}
+	for _, a := range paramKV {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "GetClients", varargs...)
+	ret0, _ := ret[0].([]keycloak.ClientRepresentation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetClients indicates an expected call of GetClients.
+func (mr *ResourcesClientMockRecorder) GetClients(accessToken, realmName any, paramKV ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]any{accessToken, realmName}, paramKV...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetClients", reflect.TypeOf((*ResourcesClient)(nil).GetClients), varargs...)
+}
+
+// GetComponents mocks base method.
+func (m *ResourcesClient) GetComponents(accessToken, realmName string, paramKV ...string) ([]keycloak.ComponentRepresentation, error) {
+	m.ctrl.T.Helper()
+	varargs := []any{accessToken, realmName}
+	for _, a := range paramKV {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "GetComponents", varargs...)
+	ret0, _ := ret[0].([]keycloak.ComponentRepresentation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetComponents indicates an expected call of GetComponents.
+func (mr *ResourcesClientMockRecorder) GetComponents(accessToken, realmName any, paramKV ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]any{accessToken, realmName}, paramKV...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetComponents", reflect.TypeOf((*ResourcesClient)(nil).GetComponents), varargs...)
+}
+
+// GetGroups mocks base method.
+func (m *ResourcesClient) GetGroups(accessToken, realmName string) ([]keycloak.GroupRepresentation, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetGroups", accessToken, realmName)
+	ret0, _ := ret[0].([]keycloak.GroupRepresentation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetGroups indicates an expected call of GetGroups.
+func (mr *ResourcesClientMockRecorder) GetGroups(accessToken, realmName any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetGroups", reflect.TypeOf((*ResourcesClient)(nil).GetGroups), accessToken, realmName)
+}
+
+// GetIdps mocks base method.
+func (m *ResourcesClient) GetIdps(accessToken, realmName string) ([]keycloak.IdentityProviderRepresentation, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetIdps", accessToken, realmName)
+	ret0, _ := ret[0].([]keycloak.IdentityProviderRepresentation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetIdps indicates an expected call of GetIdps.
+func (mr *ResourcesClientMockRecorder) GetIdps(accessToken, realmName any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetIdps", reflect.TypeOf((*ResourcesClient)(nil).GetIdps), accessToken, realmName)
+}
+
+// GetRoles mocks base method.
+func (m *ResourcesClient) GetRoles(accessToken, realmName string) ([]keycloak.RoleRepresentation, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetRoles", accessToken, realmName)
+	ret0, _ := ret[0].([]keycloak.RoleRepresentation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetRoles indicates an expected call of GetRoles.
+func (mr *ResourcesClientMockRecorder) GetRoles(accessToken, realmName any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRoles", reflect.TypeOf((*ResourcesClient)(nil).GetRoles), accessToken, realmName)
+}
+
+// GetUsers mocks base method.
+func (m *ResourcesClient) GetUsers(accessToken, reqRealmName, targetRealmName string, paramKV ...string) (keycloak.UsersPageRepresentation, error) {
+	m.ctrl.T.Helper()
+	varargs := []any{accessToken, reqRealmName, targetRealmName}
+	for _, a := range paramKV {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "GetUsers", varargs...)
+	ret0, _ := ret[0].(keycloak.UsersPageRepresentation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUsers indicates an expected call of GetUsers.
+func (mr *ResourcesClientMockRecorder) GetUsers(accessToken, reqRealmName, targetRealmName any, paramKV ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]any{accessToken, reqRealmName, targetRealmName}, paramKV...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUsers", reflect.TypeOf((*ResourcesClient)(nil).GetUsers), varargs...)
+}
+
+// UpdateClient mocks base method.
+func (m *ResourcesClient) UpdateClient(accessToken, realmName, idClient string, clientRep keycloak.ClientRepresentation) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateClient", accessToken, realmName, idClient, clientRep)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateClient indicates an expected call of UpdateClient.
+func (mr *ResourcesClientMockRecorder) UpdateClient(accessToken, realmName, idClient, clientRep any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateClient", reflect.TypeOf((*ResourcesClient)(nil).UpdateClient), accessToken, realmName, idClient, clientRep)
+}
+
+// UpdateComponent mocks base method.
+func (m *ResourcesClient) UpdateComponent(accessToken, realmName, componentID string, componentRep keycloak.ComponentRepresentation) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateComponent", accessToken, realmName, componentID, componentRep)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateComponent indicates an expected call of UpdateComponent.
+func (mr *ResourcesClientMockRecorder) UpdateComponent(accessToken, realmName, componentID, componentRep any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateComponent", reflect.TypeOf((*ResourcesClient)(nil).UpdateComponent), accessToken, realmName, componentID, componentRep)
+}
+
+// UpdateGroup mocks base method.
+func (m *ResourcesClient) UpdateGroup(accessToken, realmName, groupID string, group keycloak.GroupRepresentation) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateGroup", accessToken, realmName, groupID, group)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateGroup indicates an expected call of UpdateGroup.
+func (mr *ResourcesClientMockRecorder) UpdateGroup(accessToken, realmName, groupID, group any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateGroup", reflect.TypeOf((*ResourcesClient)(nil).UpdateGroup), accessToken, realmName, groupID, group)
+}
+
+// UpdateIdp mocks base method.
+func (m *ResourcesClient) UpdateIdp(accessToken, realmName, idpAlias string, idpRep keycloak.IdentityProviderRepresentation) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateIdp", accessToken, realmName, idpAlias, idpRep)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateIdp indicates an expected call of UpdateIdp.
+func (mr *ResourcesClientMockRecorder) UpdateIdp(accessToken, realmName, idpAlias, idpRep any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateIdp", reflect.TypeOf((*ResourcesClient)(nil).UpdateIdp), accessToken, realmName, idpAlias, idpRep)
+}
+
+// UpdateRole mocks base method.
+func (m *ResourcesClient) UpdateRole(accessToken, realmName, roleID string, role keycloak.RoleRepresentation) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateRole", accessToken, realmName, roleID, role)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateRole indicates an expected call of UpdateRole.
+func (mr *ResourcesClientMockRecorder) UpdateRole(accessToken, realmName, roleID, role any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateRole", reflect.TypeOf((*ResourcesClient)(nil).UpdateRole), accessToken, realmName, roleID, role)
+}
+
+// UpdateUser mocks base method.
+func (m *ResourcesClient) UpdateUser(accessToken, realmName, userID string, user keycloak.UserRepresentation) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateUser", accessToken, realmName, userID, user)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateUser indicates an expected call of UpdateUser.
+func (mr *ResourcesClientMockRecorder) UpdateUser(accessToken, realmName, userID, user any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateUser", reflect.TypeOf((*ResourcesClient)(nil).UpdateUser), accessToken, realmName, userID, user)
+}
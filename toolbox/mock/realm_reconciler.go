@@ -0,0 +1,740 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/cloudtrust/keycloak-client/v2/toolbox (interfaces: RealmConfigClient)
+//
+// Generated by this command:
+//
+//	mockgen --build_flags=--mod=mod -destination=./mock/realm_reconciler.go -package=mock -mock_names=RealmConfigClient=RealmConfigClient github.com/cloudtrust/keycloak-client/v2/toolbox RealmConfigClient
+//
+
+// Package mock is a generated GoMock package.
+package mock
+
+import (
+	reflect "reflect"
+
+	keycloak "github.com/cloudtrust/keycloak-client/v2"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// RealmConfigClient is a mock of RealmConfigClient interface.
+type RealmConfigClient struct {
+	ctrl     *gomock.Controller
+	recorder *RealmConfigClientMockRecorder
+	isgomock struct{}
+}
+
+// RealmConfigClientMockRecorder is the mock recorder for RealmConfigClient.
+type RealmConfigClientMockRecorder struct {
+	mock *RealmConfigClient
+}
+
+// NewRealmConfigClient creates a new mock instance.
+func NewRealmConfigClient(ctrl *gomock.Controller) *RealmConfigClient {
+	mock := &RealmConfigClient{ctrl: ctrl}
+	mock.recorder = &RealmConfigClientMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *RealmConfigClient) EXPECT() *RealmConfigClientMockRecorder {
+	return m.recorder
+}
+
+// CreateAuthenticationExecutionForFlow mocks base method.
+func (m *RealmConfigClient) CreateAuthenticationExecutionForFlow(accessToken, realmName, flowAlias, provider string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateAuthenticationExecutionForFlow", accessToken, realmName, flowAlias, provider)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateAuthenticationExecutionForFlow indicates an expected call of CreateAuthenticationExecutionForFlow.
+func (mr *RealmConfigClientMockRecorder) CreateAuthenticationExecutionForFlow(accessToken, realmName, flowAlias, provider any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateAuthenticationExecutionForFlow", reflect.TypeOf((*RealmConfigClient)(nil).CreateAuthenticationExecutionForFlow), accessToken, realmName, flowAlias, provider)
+}
+
+// CreateAuthenticationFlow mocks base method.
+func (m *RealmConfigClient) CreateAuthenticationFlow(accessToken, realmName string, authFlow keycloak.AuthenticationFlowRepresentation) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateAuthenticationFlow", accessToken, realmName, authFlow)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateAuthenticationFlow indicates an expected call of CreateAuthenticationFlow.
+func (mr *RealmConfigClientMockRecorder) CreateAuthenticationFlow(accessToken, realmName, authFlow any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateAuthenticationFlow", reflect.TypeOf((*RealmConfigClient)(nil).CreateAuthenticationFlow), accessToken, realmName, authFlow)
+}
+
+// CreateChildGroup mocks base method.
+func (m *RealmConfigClient) CreateChildGroup(accessToken, realmName, parentGroupID string, group keycloak.GroupRepresentation) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateChildGroup", accessToken, realmName, parentGroupID, group)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateChildGroup indicates an expected call of CreateChildGroup.
+func (mr *RealmConfigClientMockRecorder) CreateChildGroup(accessToken, realmName, parentGroupID, group any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateChildGroup", reflect.TypeOf((*RealmConfigClient)(nil).CreateChildGroup), accessToken, realmName, parentGroupID, group)
+}
+
+// CreateClient mocks base method.
+func (m *RealmConfigClient) CreateClient(accessToken, realmName string, clientRep keycloak.ClientRepresentation) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateClient", accessToken, realmName, clientRep)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateClient indicates an expected call of CreateClient.
+func (mr *RealmConfigClientMockRecorder) CreateClient(accessToken, realmName, clientRep any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateClient", reflect.TypeOf((*RealmConfigClient)(nil).CreateClient), accessToken, realmName, clientRep)
+}
+
+// CreateClientRole mocks base method.
+func (m *RealmConfigClient) CreateClientRole(accessToken, realmName, clientID string, role keycloak.RoleRepresentation) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateClientRole", accessToken, realmName, clientID, role)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateClientRole indicates an expected call of CreateClientRole.
+func (mr *RealmConfigClientMockRecorder) CreateClientRole(accessToken, realmName, clientID, role any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateClientRole", reflect.TypeOf((*RealmConfigClient)(nil).CreateClientRole), accessToken, realmName, clientID, role)
+}
+
+// CreateComponent mocks base method.
+func (m *RealmConfigClient) CreateComponent(accessToken, realmName string, compRep keycloak.ComponentRepresentation) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateComponent", accessToken, realmName, compRep)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateComponent indicates an expected call of CreateComponent.
+func (mr *RealmConfigClientMockRecorder) CreateComponent(accessToken, realmName, compRep any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateComponent", reflect.TypeOf((*RealmConfigClient)(nil).CreateComponent), accessToken, realmName, compRep)
+}
+
+// CreateFlowWithExecutionForExistingFlow mocks base method.
+func (m *RealmConfigClient) CreateFlowWithExecutionForExistingFlow(accessToken, realmName, flowAlias, alias, flowType, provider, description string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateFlowWithExecutionForExistingFlow", accessToken, realmName, flowAlias, alias, flowType, provider, description)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateFlowWithExecutionForExistingFlow indicates an expected call of CreateFlowWithExecutionForExistingFlow.
+func (mr *RealmConfigClientMockRecorder) CreateFlowWithExecutionForExistingFlow(accessToken, realmName, flowAlias, alias, flowType, provider, description any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateFlowWithExecutionForExistingFlow", reflect.TypeOf((*RealmConfigClient)(nil).CreateFlowWithExecutionForExistingFlow), accessToken, realmName, flowAlias, alias, flowType, provider, description)
+}
+
+// CreateGroup mocks base method.
+func (m *RealmConfigClient) CreateGroup(accessToken, reqRealmName string, group keycloak.GroupRepresentation) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateGroup", accessToken, reqRealmName, group)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateGroup indicates an expected call of CreateGroup.
+func (mr *RealmConfigClientMockRecorder) CreateGroup(accessToken, reqRealmName, group any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateGroup", reflect.TypeOf((*RealmConfigClient)(nil).CreateGroup), accessToken, reqRealmName, group)
+}
+
+// CreateIdp mocks base method.
+func (m *RealmConfigClient) CreateIdp(accessToken, realmName string, idpRep keycloak.IdentityProviderRepresentation) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateIdp", accessToken, realmName, idpRep)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateIdp indicates an expected call of CreateIdp.
+func (mr *RealmConfigClientMockRecorder) CreateIdp(accessToken, realmName, idpRep any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateIdp", reflect.TypeOf((*RealmConfigClient)(nil).CreateIdp), accessToken, realmName, idpRep)
+}
+
+// CreateRole mocks base method.
+func (m *RealmConfigClient) CreateRole(accessToken, realmName string, role keycloak.RoleRepresentation) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateRole", accessToken, realmName, role)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateRole indicates an expected call of CreateRole.
+func (mr *RealmConfigClientMockRecorder) CreateRole(accessToken, realmName, role any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateRole", reflect.TypeOf((*RealmConfigClient)(nil).CreateRole), accessToken, realmName, role)
+}
+
+// CreateUser mocks base method.
+func (m *RealmConfigClient) CreateUser(accessToken, reqRealmName, targetRealmName string, user keycloak.UserRepresentation, paramKV ...string) (string, error) {
+	m.ctrl.T.Helper()
+	varargs := []any{accessToken, reqRealmName, targetRealmName, user}
+	for _, a := range paramKV {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "CreateUser", varargs...)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateUser indicates an expected call of CreateUser.
+func (mr *RealmConfigClientMockRecorder) CreateUser(accessToken, reqRealmName, targetRealmName, user any, paramKV ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]any{accessToken, reqRealmName, targetRealmName, user}, paramKV...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateUser", reflect.TypeOf((*RealmConfigClient)(nil).CreateUser), varargs...)
+}
+
+// DeleteAuthenticationExecution mocks base method.
+func (m *RealmConfigClient) DeleteAuthenticationExecution(accessToken, realmName, executionID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteAuthenticationExecution", accessToken, realmName, executionID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteAuthenticationExecution indicates an expected call of DeleteAuthenticationExecution.
+func (mr *RealmConfigClientMockRecorder) DeleteAuthenticationExecution(accessToken, realmName, executionID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteAuthenticationExecution", reflect.TypeOf((*RealmConfigClient)(nil).DeleteAuthenticationExecution), accessToken, realmName, executionID)
+}
+
+// DeleteAuthenticationFlow mocks base method.
+func (m *RealmConfigClient) DeleteAuthenticationFlow(accessToken, realmName, flowID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteAuthenticationFlow", accessToken, realmName, flowID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteAuthenticationFlow indicates an expected call of DeleteAuthenticationFlow.
+func (mr *RealmConfigClientMockRecorder) DeleteAuthenticationFlow(accessToken, realmName, flowID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteAuthenticationFlow", reflect.TypeOf((*RealmConfigClient)(nil).DeleteAuthenticationFlow), accessToken, realmName, flowID)
+}
+
+// DeleteClient mocks base method.
+func (m *RealmConfigClient) DeleteClient(accessToken, realmName, idClient string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteClient", accessToken, realmName, idClient)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteClient indicates an expected call of DeleteClient.
+func (mr *RealmConfigClientMockRecorder) DeleteClient(accessToken, realmName, idClient any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteClient", reflect.TypeOf((*RealmConfigClient)(nil).DeleteClient), accessToken, realmName, idClient)
+}
+
+// DeleteComponent mocks base method.
+func (m *RealmConfigClient) DeleteComponent(accessToken, realmName, componentID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteComponent", accessToken, realmName, componentID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteComponent indicates an expected call of DeleteComponent.
+func (mr *RealmConfigClientMockRecorder) DeleteComponent(accessToken, realmName, componentID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteComponent", reflect.TypeOf((*RealmConfigClient)(nil).DeleteComponent), accessToken, realmName, componentID)
+}
+
+// DeleteGroup mocks base method.
+func (m *RealmConfigClient) DeleteGroup(accessToken, realmName, groupID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteGroup", accessToken, realmName, groupID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteGroup indicates an expected call of DeleteGroup.
+func (mr *RealmConfigClientMockRecorder) DeleteGroup(accessToken, realmName, groupID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteGroup", reflect.TypeOf((*RealmConfigClient)(nil).DeleteGroup), accessToken, realmName, groupID)
+}
+
+// DeleteIdp mocks base method.
+func (m *RealmConfigClient) DeleteIdp(accessToken, realmName, idpAlias string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteIdp", accessToken, realmName, idpAlias)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteIdp indicates an expected call of DeleteIdp.
+func (mr *RealmConfigClientMockRecorder) DeleteIdp(accessToken, realmName, idpAlias any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteIdp", reflect.TypeOf((*RealmConfigClient)(nil).DeleteIdp), accessToken, realmName, idpAlias)
+}
+
+// DeleteRole mocks base method.
+func (m *RealmConfigClient) DeleteRole(accessToken, realmName, roleID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteRole", accessToken, realmName, roleID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteRole indicates an expected call of DeleteRole.
+func (mr *RealmConfigClientMockRecorder) DeleteRole(accessToken, realmName, roleID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteRole", reflect.TypeOf((*RealmConfigClient)(nil).DeleteRole), accessToken, realmName, roleID)
+}
+
+// GetAuthenticationExecutionsForFlow mocks base method.
+func (m *RealmConfigClient) GetAuthenticationExecutionsForFlow(accessToken, realmName, flowAlias string) ([]keycloak.AuthenticationExecutionInfoRepresentation, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAuthenticationExecutionsForFlow", accessToken, realmName, flowAlias)
+	ret0, _ := ret[0].([]keycloak.AuthenticationExecutionInfoRepresentation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAuthenticationExecutionsForFlow indicates an expected call of GetAuthenticationExecutionsForFlow.
+func (mr *RealmConfigClientMockRecorder) GetAuthenticationExecutionsForFlow(accessToken, realmName, flowAlias any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAuthenticationExecutionsForFlow", reflect.TypeOf((*RealmConfigClient)(nil).GetAuthenticationExecutionsForFlow), accessToken, realmName, flowAlias)
+}
+
+// GetAuthenticationFlow mocks base method.
+func (m *RealmConfigClient) GetAuthenticationFlow(accessToken, realmName, flowID string) (keycloak.AuthenticationFlowRepresentation, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAuthenticationFlow", accessToken, realmName, flowID)
+	ret0, _ := ret[0].(keycloak.AuthenticationFlowRepresentation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAuthenticationFlow indicates an expected call of GetAuthenticationFlow.
+func (mr *RealmConfigClientMockRecorder) GetAuthenticationFlow(accessToken, realmName, flowID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAuthenticationFlow", reflect.TypeOf((*RealmConfigClient)(nil).GetAuthenticationFlow), accessToken, realmName, flowID)
+}
+
+// GetAuthenticationFlows mocks base method.
+func (m *RealmConfigClient) GetAuthenticationFlows(accessToken, realmName string) ([]keycloak.AuthenticationFlowRepresentation, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAuthenticationFlows", accessToken, realmName)
+	ret0, _ := ret[0].([]keycloak.AuthenticationFlowRepresentation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAuthenticationFlows indicates an expected call of GetAuthenticationFlows.
+func (mr *RealmConfigClientMockRecorder) GetAuthenticationFlows(accessToken, realmName any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAuthenticationFlows", reflect.TypeOf((*RealmConfigClient)(nil).GetAuthenticationFlows), accessToken, realmName)
+}
+
+// GetAuthenticatorConfig mocks base method.
+func (m *RealmConfigClient) GetAuthenticatorConfig(accessToken, realmName, configID string) (keycloak.AuthenticatorConfigRepresentation, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAuthenticatorConfig", accessToken, realmName, configID)
+	ret0, _ := ret[0].(keycloak.AuthenticatorConfigRepresentation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAuthenticatorConfig indicates an expected call of GetAuthenticatorConfig.
+func (mr *RealmConfigClientMockRecorder) GetAuthenticatorConfig(accessToken, realmName, configID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAuthenticatorConfig", reflect.TypeOf((*RealmConfigClient)(nil).GetAuthenticatorConfig), accessToken, realmName, configID)
+}
+
+// GetClientRoles mocks base method.
+func (m *RealmConfigClient) GetClientRoles(accessToken, realmName, idClient string) ([]keycloak.RoleRepresentation, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetClientRoles", accessToken, realmName, idClient)
+	ret0, _ := ret[0].([]keycloak.RoleRepresentation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetClientRoles indicates an expected call of GetClientRoles.
+func (mr *RealmConfigClientMockRecorder) GetClientRoles(accessToken, realmName, idClient any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetClientRoles", reflect.TypeOf((*RealmConfigClient)(nil).GetClientRoles), accessToken, realmName, idClient)
+}
+
+// GetClients mocks base method.
+func (m *RealmConfigClient) GetClients(accessToken, realmName string, paramKV ...string) ([]keycloak.ClientRepresentation, error) {
+	m.ctrl.T.Helper()
+	varargs := []any{accessToken, realmName}
+	for _, a := range paramKV {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "GetClients", varargs...)
+	ret0, _ := ret[0].([]keycloak.ClientRepresentation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetClients indicates an expected call of GetClients.
+func (mr *RealmConfigClientMockRecorder) GetClients(accessToken, realmName any, paramKV ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]any{accessToken, realmName}, paramKV...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetClients", reflect.TypeOf((*RealmConfigClient)(nil).GetClients), varargs...)
+}
+
+// GetComponents mocks base method.
+func (m *RealmConfigClient) GetComponents(accessToken, realmName string, paramKV ...string) ([]keycloak.ComponentRepresentation, error) {
+	m.ctrl.T.Helper()
+	varargs := []any{accessToken, realmName}
+	for _, a := range paramKV {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "GetComponents", varargs...)
+	ret0, _ := ret[0].([]keycloak.ComponentRepresentation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetComponents indicates an expected call of GetComponents.
+func (mr *RealmConfigClientMockRecorder) GetComponents(accessToken, realmName any, paramKV ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]any{accessToken, realmName}, paramKV...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetComponents", reflect.TypeOf((*RealmConfigClient)(nil).GetComponents), varargs...)
+}
+
+// GetGroups mocks base method.
+func (m *RealmConfigClient) GetGroups(accessToken, realmName string) ([]keycloak.GroupRepresentation, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetGroups", accessToken, realmName)
+	ret0, _ := ret[0].([]keycloak.GroupRepresentation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetGroups indicates an expected call of GetGroups.
+func (mr *RealmConfigClientMockRecorder) GetGroups(accessToken, realmName any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetGroups", reflect.TypeOf((*RealmConfigClient)(nil).GetGroups), accessToken, realmName)
+}
+
+// GetIdps mocks base method.
+func (m *RealmConfigClient) GetIdps(accessToken, realmName string) ([]keycloak.IdentityProviderRepresentation, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetIdps", accessToken, realmName)
+	ret0, _ := ret[0].([]keycloak.IdentityProviderRepresentation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetIdps indicates an expected call of GetIdps.
+func (mr *RealmConfigClientMockRecorder) GetIdps(accessToken, realmName any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetIdps", reflect.TypeOf((*RealmConfigClient)(nil).GetIdps), accessToken, realmName)
+}
+
+// GetRealm mocks base method.
+func (m *RealmConfigClient) GetRealm(accessToken, realmName string) (keycloak.RealmRepresentation, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetRealm", accessToken, realmName)
+	ret0, _ := ret[0].(keycloak.RealmRepresentation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetRealm indicates an expected call of GetRealm.
+func (mr *RealmConfigClientMockRecorder) GetRealm(accessToken, realmName any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRealm", reflect.TypeOf((*RealmConfigClient)(nil).GetRealm), accessToken, realmName)
+}
+
+// GetRequiredActions mocks base method.
+func (m *RealmConfigClient) GetRequiredActions(accessToken, realmName string) ([]keycloak.RequiredActionProviderRepresentation, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetRequiredActions", accessToken, realmName)
+	ret0, _ := ret[0].([]keycloak.RequiredActionProviderRepresentation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetRequiredActions indicates an expected call of GetRequiredActions.
+func (mr *RealmConfigClientMockRecorder) GetRequiredActions(accessToken, realmName any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRequiredActions", reflect.TypeOf((*RealmConfigClient)(nil).GetRequiredActions), accessToken, realmName)
+}
+
+// GetRoles mocks base method.
+func (m *RealmConfigClient) GetRoles(accessToken, realmName string) ([]keycloak.RoleRepresentation, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetRoles", accessToken, realmName)
+	ret0, _ := ret[0].([]keycloak.RoleRepresentation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetRoles indicates an expected call of GetRoles.
+func (mr *RealmConfigClientMockRecorder) GetRoles(accessToken, realmName any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRoles", reflect.TypeOf((*RealmConfigClient)(nil).GetRoles), accessToken, realmName)
+}
+
+// GetUsers mocks base method.
+func (m *RealmConfigClient) GetUsers(accessToken, reqRealmName, targetRealmName string, paramKV ...string) (keycloak.UsersPageRepresentation, error) {
+	m.ctrl.T.Helper()
+	varargs := []any{accessToken, reqRealmName, targetRealmName}
+	for _, a := range paramKV {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "GetUsers", varargs...)
+	ret0, _ := ret[0].(keycloak.UsersPageRepresentation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUsers indicates an expected call of GetUsers.
+func (mr *RealmConfigClientMockRecorder) GetUsers(accessToken, reqRealmName, targetRealmName any, paramKV ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]any{accessToken, reqRealmName, targetRealmName}, paramKV...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUsers", reflect.TypeOf((*RealmConfigClient)(nil).GetUsers), varargs...)
+}
+
+// LowerRequiredActionPriority mocks base method.
+func (m *RealmConfigClient) LowerRequiredActionPriority(accessToken, realmName, actionAlias string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LowerRequiredActionPriority", accessToken, realmName, actionAlias)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// LowerRequiredActionPriority indicates an expected call of LowerRequiredActionPriority.
+func (mr *RealmConfigClientMockRecorder) LowerRequiredActionPriority(accessToken, realmName, actionAlias any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LowerRequiredActionPriority", reflect.TypeOf((*RealmConfigClient)(nil).LowerRequiredActionPriority), accessToken, realmName, actionAlias)
+}
+
+// RaiseExecutionPriority mocks base method.
+func (m *RealmConfigClient) RaiseExecutionPriority(accessToken, realmName, executionID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RaiseExecutionPriority", accessToken, realmName, executionID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RaiseExecutionPriority indicates an expected call of RaiseExecutionPriority.
+func (mr *RealmConfigClientMockRecorder) RaiseExecutionPriority(accessToken, realmName, executionID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RaiseExecutionPriority", reflect.TypeOf((*RealmConfigClient)(nil).RaiseExecutionPriority), accessToken, realmName, executionID)
+}
+
+// RaiseRequiredActionPriority mocks base method.
+func (m *RealmConfigClient) RaiseRequiredActionPriority(accessToken, realmName, actionAlias string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RaiseRequiredActionPriority", accessToken, realmName, actionAlias)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RaiseRequiredActionPriority indicates an expected call of RaiseRequiredActionPriority.
+func (mr *RealmConfigClientMockRecorder) RaiseRequiredActionPriority(accessToken, realmName, actionAlias any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RaiseRequiredActionPriority", reflect.TypeOf((*RealmConfigClient)(nil).RaiseRequiredActionPriority), accessToken, realmName, actionAlias)
+}
+
+// RegisterRequiredAction mocks base method.
+func (m *RealmConfigClient) RegisterRequiredAction(accessToken, realmName, providerID, name string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RegisterRequiredAction", accessToken, realmName, providerID, name)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RegisterRequiredAction indicates an expected call of RegisterRequiredAction.
+func (mr *RealmConfigClientMockRecorder) RegisterRequiredAction(accessToken, realmName, providerID, name any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RegisterRequiredAction", reflect.TypeOf((*RealmConfigClient)(nil).RegisterRequiredAction), accessToken, realmName, providerID, name)
+}
+
+// UpdateAuthenticationExecution mocks base method.
+func (m *RealmConfigClient) UpdateAuthenticationExecution(accessToken, realmName, executionID string, authConfig keycloak.AuthenticatorConfigRepresentation) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateAuthenticationExecution", accessToken, realmName, executionID, authConfig)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateAuthenticationExecution indicates an expected call of UpdateAuthenticationExecution.
+func (mr *RealmConfigClientMockRecorder) UpdateAuthenticationExecution(accessToken, realmName, executionID, authConfig any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateAuthenticationExecution", reflect.TypeOf((*RealmConfigClient)(nil).UpdateAuthenticationExecution), accessToken, realmName, executionID, authConfig)
+}
+
+// UpdateAuthenticationExecutionForFlow mocks base method.
+func (m *RealmConfigClient) UpdateAuthenticationExecutionForFlow(accessToken, realmName, flowAlias string, authExecInfo keycloak.AuthenticationExecutionInfoRepresentation) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateAuthenticationExecutionForFlow", accessToken, realmName, flowAlias, authExecInfo)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateAuthenticationExecutionForFlow indicates an expected call of UpdateAuthenticationExecutionForFlow.
+func (mr *RealmConfigClientMockRecorder) UpdateAuthenticationExecutionForFlow(accessToken, realmName, flowAlias, authExecInfo any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateAuthenticationExecutionForFlow", reflect.TypeOf((*RealmConfigClient)(nil).UpdateAuthenticationExecutionForFlow), accessToken, realmName, flowAlias, authExecInfo)
+}
+
+// UpdateAuthenticationFlow mocks base method.
+func (m *RealmConfigClient) UpdateAuthenticationFlow(accessToken, realmName, flowID string, authFlow keycloak.AuthenticationFlowRepresentation) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateAuthenticationFlow", accessToken, realmName, flowID, authFlow)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateAuthenticationFlow indicates an expected call of UpdateAuthenticationFlow.
+func (mr *RealmConfigClientMockRecorder) UpdateAuthenticationFlow(accessToken, realmName, flowID, authFlow any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateAuthenticationFlow", reflect.TypeOf((*RealmConfigClient)(nil).UpdateAuthenticationFlow), accessToken, realmName, flowID, authFlow)
+}
+
+// UpdateAuthenticatorConfig mocks base method.
+func (m *RealmConfigClient) UpdateAuthenticatorConfig(accessToken, realmName, configID string, config keycloak.AuthenticatorConfigRepresentation) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateAuthenticatorConfig", accessToken, realmName, configID, config)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateAuthenticatorConfig indicates an expected call of UpdateAuthenticatorConfig.
+func (mr *RealmConfigClientMockRecorder) UpdateAuthenticatorConfig(accessToken, realmName, configID, config any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateAuthenticatorConfig", reflect.TypeOf((*RealmConfigClient)(nil).UpdateAuthenticatorConfig), accessToken, realmName, configID, config)
+}
+
+// UpdateClient mocks base method.
+func (m *RealmConfigClient) UpdateClient(accessToken, realmName, idClient string, clientRep keycloak.ClientRepresentation) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateClient", accessToken, realmName, idClient, clientRep)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateClient indicates an expected call of UpdateClient.
+func (mr *RealmConfigClientMockRecorder) UpdateClient(accessToken, realmName, idClient, clientRep any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateClient", reflect.TypeOf((*RealmConfigClient)(nil).UpdateClient), accessToken, realmName, idClient, clientRep)
+}
+
+// UpdateComponent mocks base method.
+func (m *RealmConfigClient) UpdateComponent(accessToken, realmName, componentID string, componentRep keycloak.ComponentRepresentation) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateComponent", accessToken, realmName, componentID, componentRep)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateComponent indicates an expected call of UpdateComponent.
+func (mr *RealmConfigClientMockRecorder) UpdateComponent(accessToken, realmName, componentID, componentRep any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateComponent", reflect.TypeOf((*RealmConfigClient)(nil).UpdateComponent), accessToken, realmName, componentID, componentRep)
+}
+
+// UpdateGroup mocks base method.
+func (m *RealmConfigClient) UpdateGroup(accessToken, realmName, groupID string, group keycloak.GroupRepresentation) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateGroup", accessToken, realmName, groupID, group)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateGroup indicates an expected call of UpdateGroup.
+func (mr *RealmConfigClientMockRecorder) UpdateGroup(accessToken, realmName, groupID, group any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateGroup", reflect.TypeOf((*RealmConfigClient)(nil).UpdateGroup), accessToken, realmName, groupID, group)
+}
+
+// UpdateIdp mocks base method.
+func (m *RealmConfigClient) UpdateIdp(accessToken, realmName, idpAlias string, idpRep keycloak.IdentityProviderRepresentation) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateIdp", accessToken, realmName, idpAlias, idpRep)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateIdp indicates an expected call of UpdateIdp.
+func (mr *RealmConfigClientMockRecorder) UpdateIdp(accessToken, realmName, idpAlias, idpRep any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateIdp", reflect.TypeOf((*RealmConfigClient)(nil).UpdateIdp), accessToken, realmName, idpAlias, idpRep)
+}
+
+// UpdateRealm mocks base method.
+func (m *RealmConfigClient) UpdateRealm(accessToken, realmName string, realm keycloak.RealmRepresentation) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateRealm", accessToken, realmName, realm)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateRealm indicates an expected call of UpdateRealm.
+func (mr *RealmConfigClientMockRecorder) UpdateRealm(accessToken, realmName, realm any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateRealm", reflect.TypeOf((*RealmConfigClient)(nil).UpdateRealm), accessToken, realmName, realm)
+}
+
+// UpdateRequiredAction mocks base method.
+func (m *RealmConfigClient) UpdateRequiredAction(accessToken, realmName, actionAlias string, action keycloak.RequiredActionProviderRepresentation) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateRequiredAction", accessToken, realmName, actionAlias, action)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateRequiredAction indicates an expected call of UpdateRequiredAction.
+func (mr *RealmConfigClientMockRecorder) UpdateRequiredAction(accessToken, realmName, actionAlias, action any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateRequiredAction", reflect.TypeOf((*RealmConfigClient)(nil).UpdateRequiredAction), accessToken, realmName, actionAlias, action)
+}
+
+// UpdateRole mocks base method.
+func (m *RealmConfigClient) UpdateRole(accessToken, realmName, roleID string, role keycloak.RoleRepresentation) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateRole", accessToken, realmName, roleID, role)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateRole indicates an expected call of UpdateRole.
+func (mr *RealmConfigClientMockRecorder) UpdateRole(accessToken, realmName, roleID, role any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateRole", reflect.TypeOf((*RealmConfigClient)(nil).UpdateRole), accessToken, realmName, roleID, role)
+}
+
+// UpdateUser mocks base method.
+func (m *RealmConfigClient) UpdateUser(accessToken, realmName, userID string, user keycloak.UserRepresentation) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateUser", accessToken, realmName, userID, user)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateUser indicates an expected call of UpdateUser.
+func (mr *RealmConfigClientMockRecorder) UpdateUser(accessToken, realmName, userID, user any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateUser", reflect.TypeOf((*RealmConfigClient)(nil).UpdateUser), accessToken, realmName, userID, user)
+}
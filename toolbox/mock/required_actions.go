@@ -0,0 +1,112 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/cloudtrust/keycloak-client/v2/toolbox (interfaces: RequiredActionsClient)
+//
+// Generated by this command:
+//
+//	mockgen --build_flags=--mod=mod -destination=./mock/required_actions.go -package=mock -mock_names=RequiredActionsClient=RequiredActionsClient github.com/cloudtrust/keycloak-client/v2/toolbox RequiredActionsClient
+//
+
+// Package mock is a generated GoMock package.
+package mock
+
+import (
+	reflect "reflect"
+
+	keycloak "github.com/cloudtrust/keycloak-client/v2"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// RequiredActionsClient is a mock of RequiredActionsClient interface.
+type RequiredActionsClient struct {
+	ctrl     *gomock.Controller
+	recorder *RequiredActionsClientMockRecorder
+	isgomock struct{}
+}
+
+// RequiredActionsClientMockRecorder is the mock recorder for RequiredActionsClient.
+type RequiredActionsClientMockRecorder struct {
+	mock *RequiredActionsClient
+}
+
+// NewRequiredActionsClient creates a new mock instance.
+func NewRequiredActionsClient(ctrl *gomock.Controller) *RequiredActionsClient {
+	mock := &RequiredActionsClient{ctrl: ctrl}
+	mock.recorder = &RequiredActionsClientMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *RequiredActionsClient) EXPECT() *RequiredActionsClientMockRecorder {
+	return m.recorder
+}
+
+// GetRequiredActions mocks base method.
+func (m *RequiredActionsClient) GetRequiredActions(accessToken, realmName string) ([]keycloak.RequiredActionProviderRepresentation, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetRequiredActions", accessToken, realmName)
+	ret0, _ := ret[0].([]keycloak.RequiredActionProviderRepresentation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetRequiredActions indicates an expected call of GetRequiredActions.
+func (mr *RequiredActionsClientMockRecorder) GetRequiredActions(accessToken, realmName any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRequiredActions", reflect.TypeOf((*RequiredActionsClient)(nil).GetRequiredActions), accessToken, realmName)
+}
+
+// LowerRequiredActionPriority mocks base method.
+func (m *RequiredActionsClient) LowerRequiredActionPriority(accessToken, realmName, actionAlias string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LowerRequiredActionPriority", accessToken, realmName, actionAlias)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// LowerRequiredActionPriority indicates an expected call of LowerRequiredActionPriority.
+func (mr *RequiredActionsClientMockRecorder) LowerRequiredActionPriority(accessToken, realmName, actionAlias any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LowerRequiredActionPriority", reflect.TypeOf((*RequiredActionsClient)(nil).LowerRequiredActionPriority), accessToken, realmName, actionAlias)
+}
+
+// RaiseRequiredActionPriority mocks base method.
+func (m *RequiredActionsClient) RaiseRequiredActionPriority(accessToken, realmName, actionAlias string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RaiseRequiredActionPriority", accessToken, realmName, actionAlias)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RaiseRequiredActionPriority indicates an expected call of RaiseRequiredActionPriority.
+func (mr *RequiredActionsClientMockRecorder) RaiseRequiredActionPriority(accessToken, realmName, actionAlias any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RaiseRequiredActionPriority", reflect.TypeOf((*RequiredActionsClient)(nil).RaiseRequiredActionPriority), accessToken, realmName, actionAlias)
+}
+
+// RegisterRequiredAction mocks base method.
+func (m *RequiredActionsClient) RegisterRequiredAction(accessToken, realmName, providerID, name string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RegisterRequiredAction", accessToken, realmName, providerID, name)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RegisterRequiredAction indicates an expected call of RegisterRequiredAction.
+func (mr *RequiredActionsClientMockRecorder) RegisterRequiredAction(accessToken, realmName, providerID, name any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RegisterRequiredAction", reflect.TypeOf((*RequiredActionsClient)(nil).RegisterRequiredAction), accessToken, realmName, providerID, name)
+}
+
+// UpdateRequiredAction mocks base method.
+func (m *RequiredActionsClient) UpdateRequiredAction(accessToken, realmName, actionAlias string, action keycloak.RequiredActionProviderRepresentation) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateRequiredAction", accessToken, realmName, actionAlias, action)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateRequiredAction indicates an expected call of UpdateRequiredAction.
+func (mr *RequiredActionsClientMockRecorder) UpdateRequiredAction(accessToken, realmName, actionAlias, action any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateRequiredAction", reflect.TypeOf((*RequiredActionsClient)(nil).UpdateRequiredAction), accessToken, realmName, actionAlias, action)
+}
@@ -5,3 +5,9 @@ import _ "github.com/golang/mock/mockgen/model"
 //go:generate mockgen --build_flags=--mod=mod -destination=./mock/logger.go -package=mock -mock_names=Logger=Logger github.com/cloudtrust/keycloak-client/v2/toolbox Logger
 //go:generate mockgen --build_flags=--mod=mod -destination=./mock/profile.go -package=mock -mock_names=ProfileRetriever=ProfileRetriever,OidcTokenProvider=OidcTokenProvider github.com/cloudtrust/keycloak-client/v2/toolbox ProfileRetriever,OidcTokenProvider
 //go:generate mockgen --build_flags=--mod=mod -destination=./mock/component.go -package=mock -mock_names=ComponentTool=ComponentTool github.com/cloudtrust/keycloak-client/v2/toolbox ComponentTool
+//go:generate mockgen --build_flags=--mod=mod -destination=./mock/required_actions.go -package=mock -mock_names=RequiredActionsClient=RequiredActionsClient github.com/cloudtrust/keycloak-client/v2/toolbox RequiredActionsClient
+//go:generate mockgen --build_flags=--mod=mod -destination=./mock/authentication_flows.go -package=mock -mock_names=AuthenticationFlowsClient=AuthenticationFlowsClient github.com/cloudtrust/keycloak-client/v2/toolbox AuthenticationFlowsClient
+//go:generate mockgen --build_flags=--mod=mod -destination=./mock/brute_force.go -package=mock -mock_names=BruteForceClient=BruteForceClient github.com/cloudtrust/keycloak-client/v2/toolbox BruteForceClient
+//go:generate mockgen --build_flags=--mod=mod -destination=./mock/bulk_import.go -package=mock -mock_names=BulkImportClient=BulkImportClient github.com/cloudtrust/keycloak-client/v2/toolbox BulkImportClient
+//go:generate mockgen --build_flags=--mod=mod -destination=./mock/ensure.go -package=mock -mock_names=ResourcesClient=ResourcesClient github.com/cloudtrust/keycloak-client/v2/toolbox ResourcesClient
+//go:generate mockgen --build_flags=--mod=mod -destination=./mock/realm_reconciler.go -package=mock -mock_names=RealmConfigClient=RealmConfigClient github.com/cloudtrust/keycloak-client/v2/toolbox RealmConfigClient
@@ -0,0 +1,132 @@
+package toolbox
+
+import (
+	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/cloudtrust/keycloak-client/v2"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// TokenClaims are the claims of a verified Keycloak access token
+type TokenClaims struct {
+	Subject           string
+	Issuer            string
+	Realm             string
+	AuthorizedParty   string
+	Audience          []string
+	ExpiresAt         time.Time
+	IssuedAt          time.Time
+	NotBefore         time.Time
+	TokenType         string
+	PreferredUsername string
+	Email             string
+	RealmRoles        []string
+	ClientRoles       map[string][]string
+	Groups            []string
+	Scope             string
+	SessionState      string
+	// ConfirmationThumbprint is the JWK thumbprint of the DPoP key the token is bound to (cnf.jkt)
+	ConfirmationThumbprint string
+	// Attributes contains the claims which are not mapped to another field, such as the ones added by user attribute
+	// mappers
+	Attributes map[string]any
+}
+
+type rolesClaim struct {
+	Roles []string `json:"roles"`
+}
+
+type keycloakClaims struct {
+	Subject           string                `json:"sub"`
+	Issuer            string                `json:"iss"`
+	AuthorizedParty   string                `json:"azp"`
+	Audience          jwt.ClaimStrings      `json:"aud"`
+	ExpiresAt         *jwt.NumericDate      `json:"exp"`
+	IssuedAt          *jwt.NumericDate      `json:"iat"`
+	NotBefore         *jwt.NumericDate      `json:"nbf"`
+	TokenType         string                `json:"typ"`
+	PreferredUsername string                `json:"preferred_username"`
+	Email             string                `json:"email"`
+	RealmAccess       rolesClaim            `json:"realm_access"`
+	ResourceAccess    map[string]rolesClaim `json:"resource_access"`
+	Groups            []string              `json:"groups"`
+	Scope             string                `json:"scope"`
+	SessionState      string                `json:"session_state"`
+	SessionID         string                `json:"sid"`
+	Confirmation      struct {
+		JWKThumbprint string `json:"jkt"`
+	} `json:"cnf"`
+}
+
+// Claims which are not kept in TokenClaims.Attributes
+var mappedClaims = []string{"sub", "iss", "azp", "aud", "exp", "iat", "nbf", "jti", "typ", "preferred_username", "email",
+	"realm_access", "resource_access", "groups", "scope", "session_state", "sid", "cnf"}
+
+// parseTokenClaims creates TokenClaims from the JSON payload of a token
+func parseTokenClaims(payload []byte) (*TokenClaims, error) {
+	var claims keycloakClaims
+	var attributes map[string]any
+	if json.Unmarshal(payload, &claims) != nil || json.Unmarshal(payload, &attributes) != nil {
+		return nil, errors.New(keycloak.MsgErrCannotUnmarshal + "." + keycloak.TokenMsg)
+	}
+	for _, name := range mappedClaims {
+		delete(attributes, name)
+	}
+
+	var res = &TokenClaims{
+		Subject:           claims.Subject,
+		Issuer:            claims.Issuer,
+		AuthorizedParty:   claims.AuthorizedParty,
+		Audience:          claims.Audience,
+		TokenType:         claims.TokenType,
+		PreferredUsername: claims.PreferredUsername,
+		Email:             claims.Email,
+		RealmRoles:        claims.RealmAccess.Roles,
+		ClientRoles:       map[string][]string{},
+		Groups:            claims.Groups,
+		Scope:             claims.Scope,
+		SessionState:      claims.SessionState,
+		Attributes:        attributes,
+
+		ConfirmationThumbprint: claims.Confirmation.JWKThumbprint,
+	}
+	if idx := strings.LastIndex(claims.Issuer, "/realms/"); idx >= 0 {
+		res.Realm = claims.Issuer[idx+len("/realms/"):]
+	}
+	if claims.ExpiresAt != nil {
+		res.ExpiresAt = claims.ExpiresAt.Time
+	}
+	if claims.IssuedAt != nil {
+		res.IssuedAt = claims.IssuedAt.Time
+	}
+	if claims.NotBefore != nil {
+		res.NotBefore = claims.NotBefore.Time
+	}
+	for clientID, access := range claims.ResourceAccess {
+		res.ClientRoles[clientID] = access.Roles
+	}
+	if res.SessionState == "" {
+		// Recent Keycloak versions only provide the session ID
+		res.SessionState = claims.SessionID
+	}
+	return res, nil
+}
+
+// HasRealmRole checks if the token grants the given realm role
+func (tc *TokenClaims) HasRealmRole(role string) bool {
+	return slices.Contains(tc.RealmRoles, role)
+}
+
+// HasClientRole checks if the token grants the given role of a client
+func (tc *TokenClaims) HasClientRole(clientID string, role string) bool {
+	return slices.Contains(tc.ClientRoles[clientID], role)
+}
+
+// HasScope checks if the given scope has been granted to the token
+func (tc *TokenClaims) HasScope(scope string) bool {
+	return slices.Contains(strings.Fields(tc.Scope), scope)
+}
@@ -0,0 +1,51 @@
+package toolbox
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTokenClaims(t *testing.T) {
+	t.Run("Invalid payload", func(t *testing.T) {
+		var _, err = parseTokenClaims([]byte(`{"aud": 12}`))
+		assert.NotNil(t, err)
+	})
+	t.Run("Keycloak access token", func(t *testing.T) {
+		var claims, err = parseTokenClaims([]byte(`{
+			"exp": 1700000300, "iat": 1700000000, "jti": "abc", "typ": "Bearer",
+			"iss": "https://my.domain.test/auth/realms/my-realm", "aud": "account", "sub": "1234", "azp": "my-app",
+			"session_state": "session", "sid": "session", "scope": "openid email profile",
+			"realm_access": {"roles": ["offline_access", "admin"]},
+			"resource_access": {"account": {"roles": ["manage-account"]}, "my-app": {"roles": ["reader"]}},
+			"groups": ["/staff"], "preferred_username": "john", "email": "john@domain.test",
+			"business_id": "CH-123", "locale": "fr"
+		}`))
+		assert.Nil(t, err)
+		assert.Equal(t, "1234", claims.Subject)
+		assert.Equal(t, "my-realm", claims.Realm)
+		assert.Equal(t, "my-app", claims.AuthorizedParty)
+		assert.Equal(t, []string{"account"}, claims.Audience)
+		assert.Equal(t, time.Unix(1700000300, 0), claims.ExpiresAt)
+		assert.Equal(t, "john", claims.PreferredUsername)
+		assert.Equal(t, "session", claims.SessionState)
+		assert.Equal(t, []string{"/staff"}, claims.Groups)
+		assert.Equal(t, map[string]any{"business_id": "CH-123", "locale": "fr"}, claims.Attributes)
+
+		assert.True(t, claims.HasRealmRole("admin"))
+		assert.False(t, claims.HasRealmRole("reader"))
+		assert.True(t, claims.HasClientRole("my-app", "reader"))
+		assert.False(t, claims.HasClientRole("account", "reader"))
+		assert.False(t, claims.HasClientRole("unknown", "reader"))
+		assert.True(t, claims.HasScope("email"))
+		assert.False(t, claims.HasScope("mail"))
+	})
+	t.Run("Session ID only", func(t *testing.T) {
+		var claims, err = parseTokenClaims([]byte(`{"sid": "session"}`))
+		assert.Nil(t, err)
+		assert.Equal(t, "session", claims.SessionState)
+		assert.Empty(t, claims.Realm)
+		assert.True(t, claims.ExpiresAt.IsZero())
+	})
+}
@@ -0,0 +1,289 @@
+package toolbox
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/cloudtrust/keycloak-client/v2"
+	"github.com/go-jose/go-jose/v4"
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/clientcredentials"
+)
+
+// Client authentication methods of service accounts
+const (
+	ClientAuthSecret        = "client_secret"
+	ClientAuthPrivateKeyJWT = "private_key_jwt"
+	ClientAuthTLS           = "tls_client_auth"
+)
+
+const (
+	clientAssertionType     = "urn:ietf:params:oauth:client-assertion-type:jwt-bearer"
+	clientAssertionLifetime = time.Minute
+)
+
+// clientTokenRequester calls the token endpoint of a realm context, authenticated as the client of an OAuth2Config.
+// params are added to the client credentials grant parameters and can override the grant type.
+type clientTokenRequester func(params url.Values) (*oauth2.Token, error)
+
+// newClientTokenRequesters creates a clientTokenRequester per realm context of the Keycloak configuration. Requests
+// are sent to the internal API with the Forwarded host of the context. When dpop is not nil, DPoP-bound tokens are
+// requested. Configuration errors are logged and returned when tokens are requested.
+func newClientTokenRequesters(kcConfig keycloak.Config, oauth2Config OAuth2Config, dpop DPoPSigner, logger Logger) map[string]clientTokenRequester {
+	var baseTransport, signer, configErr = newClientAuthentication(oauth2Config)
+	if configErr != nil {
+		logger.Warn(context.Background(), "msg", "Invalid client authentication configuration", "err", configErr.Error())
+	}
+	if dpop != nil {
+		baseTransport = &dpopTransport{base: baseTransport, signer: dpop}
+	}
+
+	var res = map[string]clientTokenRequester{}
+	kcConfig.URIProvider.ForEachContextURI(func(targetRealm, host, baseURI string) {
+		var cfg = clientcredentials.Config{
+			ClientID:     *oauth2Config.ClientID,
+			ClientSecret: stringOrEmpty(oauth2Config.ClientSecret),
+			TokenURL:     fmt.Sprintf("%s/auth/realms/%s/protocol/openid-connect/token", kcConfig.AddrInternalAPI, *oauth2Config.Realm),
+		}
+		if oauth2Config.clientAuthMethod() != ClientAuthSecret {
+			// The client is identified by the client_id parameter
+			cfg.ClientSecret = ""
+			cfg.AuthStyle = oauth2.AuthStyleInParams
+		}
+		var client = &http.Client{
+			Transport: &customTransport{
+				base:          baseTransport,
+				forwardedHost: host,
+			},
+		}
+		var ctx = context.WithValue(context.Background(), oauth2.HTTPClient, client)
+		// Keycloak expects the issuer of the realm, as seen through the Forwarded host, as audience of the assertions
+		var audience = fmt.Sprintf("%s/auth/realms/%s", strings.TrimSuffix(baseURI, "/"), *oauth2Config.Realm)
+		res[targetRealm] = func(params url.Values) (*oauth2.Token, error) {
+			if configErr != nil {
+				return nil, configErr
+			}
+			var requestCfg = cfg
+			requestCfg.EndpointParams = url.Values{}
+			for key, values := range params {
+				requestCfg.EndpointParams[key] = values
+			}
+			if signer != nil {
+				// A new assertion is needed for each request as Keycloak rejects replayed ones
+				var assertion, err = signer.sign(audience)
+				if err != nil {
+					return nil, err
+				}
+				requestCfg.EndpointParams.Set("client_assertion_type", clientAssertionType)
+				requestCfg.EndpointParams.Set("client_assertion", assertion)
+			}
+			return requestCfg.Token(ctx)
+		}
+	})
+	return res
+}
+
+// newClientAuthentication returns the transport to use to call Keycloak as the client of the configuration and, for
+// private_key_jwt, the signer of the client assertions
+func newClientAuthentication(oauth2Config OAuth2Config) (http.RoundTripper, *clientAssertionSigner, error) {
+	switch oauth2Config.clientAuthMethod() {
+	case ClientAuthSecret:
+		return http.DefaultTransport, nil, nil
+	case ClientAuthPrivateKeyJWT:
+		var signer, err = newClientAssertionSigner(oauth2Config)
+		return http.DefaultTransport, signer, err
+	case ClientAuthTLS:
+		var transport, err = newTLSClientTransport(oauth2Config)
+		return transport, nil, err
+	}
+	return nil, nil, errors.New(keycloak.MsgErrInvalidParam + ".clientAuthMethod")
+}
+
+// clientAssertionSigner creates the signed JWTs used to authenticate a client (RFC 7523)
+type clientAssertionSigner struct {
+	clientID string
+	key      crypto.Signer
+	method   jwt.SigningMethod
+	keyID    string
+}
+
+func newClientAssertionSigner(config OAuth2Config) (*clientAssertionSigner, error) {
+	var key crypto.Signer
+	var keyID, alg = stringOrEmpty(config.KeyID), stringOrEmpty(config.SigningAlg)
+	var err error
+	switch {
+	case config.PrivateKey != nil:
+		key, err = parsePrivateKeyPEM([]byte(*config.PrivateKey))
+	case config.PrivateKeyFile != nil:
+		var data []byte
+		if data, err = os.ReadFile(*config.PrivateKeyFile); err == nil {
+			key, err = parsePrivateKeyPEM(data)
+		}
+	case config.JWKSFile != nil:
+		var data []byte
+		if data, err = os.ReadFile(*config.JWKSFile); err == nil {
+			var jwk jose.JSONWebKey
+			if jwk, err = selectPrivateJWK(data, keyID); err == nil {
+				key, _ = jwk.Key.(crypto.Signer)
+				keyID = jwk.KeyID
+				if alg == "" {
+					alg = jwk.Algorithm
+				}
+			}
+		}
+	default:
+		return nil, errors.New(keycloak.MsgErrMissingParam + ".privateKey")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if key == nil {
+		return nil, errors.New(keycloak.MsgErrInvalidParam + ".privateKey")
+	}
+
+	if alg == "" {
+		alg = defaultSigningAlg(key)
+	}
+	var method = jwt.GetSigningMethod(alg)
+	if method == nil || !signingMethodMatchesKey(method, key) {
+		return nil, errors.New(keycloak.MsgErrInvalidParam + ".signingAlg")
+	}
+	return &clientAssertionSigner{
+		clientID: *config.ClientID,
+		key:      key,
+		method:   method,
+		keyID:    keyID,
+	}, nil
+}
+
+// sign creates a client assertion for the given audience, which is the issuer of the realm
+func (s *clientAssertionSigner) sign(audience string) (string, error) {
+	var jti = make([]byte, 16)
+	if _, err := rand.Read(jti); err != nil {
+		return "", err
+	}
+	var now = time.Now()
+	var token = jwt.NewWithClaims(s.method, jwt.RegisteredClaims{
+		Issuer:    s.clientID,
+		Subject:   s.clientID,
+		Audience:  jwt.ClaimStrings{audience},
+		ID:        base64.RawURLEncoding.EncodeToString(jti),
+		IssuedAt:  jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(now.Add(clientAssertionLifetime)),
+	})
+	if s.keyID != "" {
+		token.Header["kid"] = s.keyID
+	}
+	return token.SignedString(s.key)
+}
+
+func parsePrivateKeyPEM(data []byte) (crypto.Signer, error) {
+	var block, _ = pem.Decode(data)
+	if block == nil {
+		return nil, errors.New(keycloak.MsgErrCannotParse + ".privateKey")
+	}
+	var key any
+	var err error
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	default:
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, errors.New(keycloak.MsgErrCannotParse + ".privateKey")
+	}
+	if signer, ok := key.(crypto.Signer); ok {
+		return signer, nil
+	}
+	return nil, errors.New(keycloak.MsgErrInvalidParam + ".privateKey")
+}
+
+// selectPrivateJWK returns the private key of the JWKS identified by keyID, or the first private signing key when
+// keyID is empty
+func selectPrivateJWK(data []byte, keyID string) (jose.JSONWebKey, error) {
+	var jwks jose.JSONWebKeySet
+	if err := json.Unmarshal(data, &jwks); err != nil {
+		return jose.JSONWebKey{}, errors.New(keycloak.MsgErrCannotParse + ".jwks")
+	}
+	for _, jwk := range jwks.Keys {
+		if (keyID == "" || jwk.KeyID == keyID) && !jwk.IsPublic() && (jwk.Use == "" || jwk.Use == "sig") {
+			return jwk, nil
+		}
+	}
+	return jose.JSONWebKey{}, errors.New(keycloak.MsgErrInvalidParam + ".jwks")
+}
+
+func defaultSigningAlg(key crypto.Signer) string {
+	if ecKey, ok := key.(*ecdsa.PrivateKey); ok {
+		switch ecKey.Curve {
+		case elliptic.P384():
+			return jwt.SigningMethodES384.Alg()
+		case elliptic.P521():
+			return jwt.SigningMethodES512.Alg()
+		}
+		return jwt.SigningMethodES256.Alg()
+	}
+	return jwt.SigningMethodRS256.Alg()
+}
+
+func signingMethodMatchesKey(method jwt.SigningMethod, key crypto.Signer) bool {
+	switch key.(type) {
+	case *rsa.PrivateKey:
+		switch method.(type) {
+		case *jwt.SigningMethodRSA, *jwt.SigningMethodRSAPSS:
+			return true
+		}
+	case *ecdsa.PrivateKey:
+		_, ok := method.(*jwt.SigningMethodECDSA)
+		return ok
+	}
+	return false
+}
+
+// newTLSClientTransport creates a transport which authenticates with the client certificate of the configuration
+func newTLSClientTransport(config OAuth2Config) (http.RoundTripper, error) {
+	if config.TLSCertFile == nil || config.TLSKeyFile == nil {
+		return nil, errors.New(keycloak.MsgErrMissingParam + ".tlsCertificate")
+	}
+	var cert, err = tls.LoadX509KeyPair(*config.TLSCertFile, *config.TLSKeyFile)
+	if err != nil {
+		return nil, err
+	}
+	var tlsConfig = &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+	if config.TLSCAFile != nil {
+		var caCert []byte
+		if caCert, err = os.ReadFile(*config.TLSCAFile); err != nil {
+			return nil, err
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(caCert) {
+			return nil, errors.New(keycloak.MsgErrCannotParse + ".tlsCA")
+		}
+	}
+	var transport = http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	return transport, nil
+}
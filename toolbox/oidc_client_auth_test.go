@@ -0,0 +1,211 @@
+package toolbox
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/cloudtrust/keycloak-client/v2"
+	"github.com/cloudtrust/keycloak-client/v2/toolbox/mock"
+	"github.com/go-jose/go-jose/v4"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+// clientAuthEndpoint is a token endpoint which records the parameters of the last request
+type clientAuthEndpoint struct {
+	form      url.Values
+	peerCerts int
+}
+
+func (ce *clientAuthEndpoint) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	_ = r.ParseForm()
+	ce.form = r.PostForm
+	if r.TLS != nil {
+		ce.peerCerts = len(r.TLS.PeerCertificates)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write([]byte(`{"access_token": "access", "token_type": "bearer", "expires_in": 300}`))
+}
+
+func createKeyConfig(method string) OAuth2Config {
+	var config = createServiceAccount("master", "my-client", "")
+	config.ClientSecret = nil
+	config.ClientAuthMethod = &method
+	return config
+}
+
+func writeTestFile(t *testing.T, name string, content []byte) *string {
+	var filename = filepath.Join(t.TempDir(), name)
+	assert.Nil(t, os.WriteFile(filename, content, 0600))
+	return &filename
+}
+
+func TestPrivateKeyJWT(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	var mockLogger = mock.NewLogger(mockCtrl)
+	var endpoint = &clientAuthEndpoint{}
+	var ts = httptest.NewServer(endpoint)
+	defer ts.Close()
+
+	var uriProvider, _ = NewKeycloakURIProviderFromArray([]string{ts.URL})
+	var kcConfig = keycloak.Config{URIProvider: uriProvider, AddrInternalAPI: ts.URL}
+	var rsaKey, _ = rsa.GenerateKey(rand.Reader, 2048)
+	var ecKey, _ = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	var rsaPEM = string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)}))
+
+	var verifyAssertion = func(t *testing.T, publicKey crypto.PublicKey, alg string, kid any) {
+		assert.Equal(t, clientAssertionType, endpoint.form.Get("client_assertion_type"))
+		assert.Equal(t, "my-client", endpoint.form.Get("client_id"))
+		assert.Empty(t, endpoint.form.Get("client_secret"))
+
+		var claims jwt.RegisteredClaims
+		var token, err = jwt.ParseWithClaims(endpoint.form.Get("client_assertion"), &claims, func(*jwt.Token) (any, error) {
+			return publicKey, nil
+		}, jwt.WithValidMethods([]string{alg}), jwt.WithAudience(ts.URL+"/auth/realms/master"), jwt.WithIssuer("my-client"), jwt.WithSubject("my-client"))
+		assert.Nil(t, err)
+		assert.NotEmpty(t, claims.ID)
+		assert.Equal(t, kid, token.Header["kid"])
+	}
+
+	t.Run("PEM key", func(t *testing.T) {
+		var config = createKeyConfig(ClientAuthPrivateKeyJWT)
+		config.PrivateKey = &rsaPEM
+		config.KeyID = ptr("my-kid")
+		var token, err = NewOAuth2TokenProvider(kcConfig, config, mockLogger).ProvideToken(context.TODO())
+		assert.Nil(t, err)
+		assert.Equal(t, "access", token)
+		verifyAssertion(t, &rsaKey.PublicKey, "RS256", "my-kid")
+	})
+	t.Run("PKCS8 PEM file with PS256", func(t *testing.T) {
+		var pkcs8, _ = x509.MarshalPKCS8PrivateKey(rsaKey)
+		var config = createKeyConfig(ClientAuthPrivateKeyJWT)
+		config.PrivateKeyFile = writeTestFile(t, "key.pem", pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8}))
+		config.SigningAlg = ptr("PS256")
+		var _, err = NewOAuth2TokenProvider(kcConfig, config, mockLogger).ProvideToken(context.TODO())
+		assert.Nil(t, err)
+		verifyAssertion(t, &rsaKey.PublicKey, "PS256", nil)
+	})
+	t.Run("JWKS file", func(t *testing.T) {
+		var jwks, _ = json.Marshal(jose.JSONWebKeySet{Keys: []jose.JSONWebKey{
+			{Key: &rsaKey.PublicKey, KeyID: "public", Use: "sig"},
+			{Key: rsaKey, KeyID: "encryption", Use: "enc"},
+			{Key: ecKey, KeyID: "ec-key", Use: "sig"},
+		}})
+		var config = createKeyConfig(ClientAuthPrivateKeyJWT)
+		config.JWKSFile = writeTestFile(t, "jwks.json", jwks)
+		var _, err = NewOAuth2TokenProvider(kcConfig, config, mockLogger).ProvideToken(context.TODO())
+		assert.Nil(t, err)
+		verifyAssertion(t, &ecKey.PublicKey, "ES256", "ec-key")
+
+		config.KeyID = ptr("unknown")
+		mockLogger.EXPECT().Warn(gomock.Any(), "msg", "Invalid client authentication configuration", "err", gomock.Any())
+		_, err = NewOAuth2TokenProvider(kcConfig, config, mockLogger).ProvideToken(context.TODO())
+		assert.NotNil(t, err)
+	})
+	t.Run("Algorithm does not match key", func(t *testing.T) {
+		var config = createKeyConfig(ClientAuthPrivateKeyJWT)
+		config.PrivateKey = &rsaPEM
+		config.SigningAlg = ptr("ES256")
+		mockLogger.EXPECT().Warn(gomock.Any(), "msg", "Invalid client authentication configuration", "err", gomock.Any())
+		var _, err = NewOAuth2TokenProvider(kcConfig, config, mockLogger).ProvideToken(context.TODO())
+		assert.NotNil(t, err)
+	})
+	t.Run("Invalid key", func(t *testing.T) {
+		var config = createKeyConfig(ClientAuthPrivateKeyJWT)
+		mockLogger.EXPECT().Warn(gomock.Any(), "msg", "Invalid client authentication configuration", "err", gomock.Any()).Times(2)
+		var _, err = NewOAuth2TokenProvider(kcConfig, config, mockLogger).ProvideToken(context.TODO())
+		assert.NotNil(t, err)
+
+		config.PrivateKey = ptr("not a PEM key")
+		_, err = NewOAuth2TokenProvider(kcConfig, config, mockLogger).ProvideToken(context.TODO())
+		assert.NotNil(t, err)
+	})
+	t.Run("Unknown method", func(t *testing.T) {
+		var config = createKeyConfig("unknown")
+		mockLogger.EXPECT().Warn(gomock.Any(), "msg", "Invalid client authentication configuration", "err", gomock.Any())
+		var _, err = NewOAuth2TokenProvider(kcConfig, config, mockLogger).ProvideToken(context.TODO())
+		assert.NotNil(t, err)
+	})
+}
+
+func TestTLSClientAuth(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	var mockLogger = mock.NewLogger(mockCtrl)
+	var endpoint = &clientAuthEndpoint{}
+	var ts = httptest.NewUnstartedServer(endpoint)
+	ts.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+	ts.StartTLS()
+	defer ts.Close()
+
+	var uriProvider, _ = NewKeycloakURIProviderFromArray([]string{ts.URL})
+	var kcConfig = keycloak.Config{URIProvider: uriProvider, AddrInternalAPI: ts.URL}
+
+	var key, _ = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	var template = x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "my-client"},
+		NotBefore:    time.Now().Add(-time.Minute),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	var certDER, _ = x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	var keyDER, _ = x509.MarshalECPrivateKey(key)
+	var certFile = writeTestFile(t, "client.crt", pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDER}))
+	var keyFile = writeTestFile(t, "client.key", pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}))
+	var caFile = writeTestFile(t, "ca.crt", pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ts.Certificate().Raw}))
+
+	t.Run("Success", func(t *testing.T) {
+		var config = createKeyConfig(ClientAuthTLS)
+		config.TLSCertFile, config.TLSKeyFile, config.TLSCAFile = certFile, keyFile, caFile
+		var token, err = NewOAuth2TokenProvider(kcConfig, config, mockLogger).ProvideToken(context.TODO())
+		assert.Nil(t, err)
+		assert.Equal(t, "access", token)
+		assert.Equal(t, 1, endpoint.peerCerts)
+		assert.Equal(t, "my-client", endpoint.form.Get("client_id"))
+		assert.Empty(t, endpoint.form.Get("client_secret"))
+	})
+	t.Run("Missing certificate", func(t *testing.T) {
+		var config = createKeyConfig(ClientAuthTLS)
+		mockLogger.EXPECT().Warn(gomock.Any(), "msg", "Invalid client authentication configuration", "err", gomock.Any())
+		var _, err = NewOAuth2TokenProvider(kcConfig, config, mockLogger).ProvideToken(context.TODO())
+		assert.NotNil(t, err)
+	})
+	t.Run("Invalid CA", func(t *testing.T) {
+		var config = createKeyConfig(ClientAuthTLS)
+		config.TLSCertFile, config.TLSKeyFile, config.TLSCAFile = certFile, keyFile, keyFile
+		mockLogger.EXPECT().Warn(gomock.Any(), "msg", "Invalid client authentication configuration", "err", gomock.Any())
+		var _, err = NewOAuth2TokenProvider(kcConfig, config, mockLogger).ProvideToken(context.TODO())
+		assert.NotNil(t, err)
+	})
+}
+
+func TestIsClientConfig(t *testing.T) {
+	assert.False(t, (*OAuth2Config)(nil).IsClientConfig())
+	var tlsConfig, secretConfig = createKeyConfig(ClientAuthTLS), createKeyConfig(ClientAuthSecret)
+	assert.True(t, tlsConfig.IsClientConfig())
+	assert.False(t, secretConfig.IsClientConfig())
+	var technicalUser = createTechnicalUser("master", "user", "passwd", "clientID")
+	assert.False(t, technicalUser.IsClientConfig())
+}
@@ -5,36 +5,69 @@ import (
 	"fmt"
 	"net/http"
 	"strings"
+	"sync"
+	"time"
 
 	errorhandler "github.com/cloudtrust/common-service/v2/errors"
 	"github.com/cloudtrust/keycloak-client/v2"
 	"golang.org/x/oauth2"
-	"golang.org/x/oauth2/clientcredentials"
 )
 
-// OAuth2Config struct
+// OAuth2Config struct. Invalid client authentication settings (keys, JWKS, signing algorithm, TLS certificate) are
+// logged when the token provider is created and make each token request fail.
 type OAuth2Config struct {
-	Realm        *string `mapstructure:"realm"`
-	Username     *string `mapstructure:"username"`
-	Password     *string `mapstructure:"password"`
-	ClientID     *string `mapstructure:"client-id"`
-	ClientSecret *string `mapstructure:"client-secret"`
+	Realm         *string `mapstructure:"realm"`
+	Username      *string `mapstructure:"username"`
+	Password      *string `mapstructure:"password"`
+	ClientID      *string `mapstructure:"client-id"`
+	ClientSecret  *string `mapstructure:"client-secret"`
+	OfflineAccess *bool   `mapstructure:"offline-access"` // Technical users only: requests an offline token
+
+	// Service accounts only: ClientAuthSecret (default), ClientAuthPrivateKeyJWT or ClientAuthTLS
+	ClientAuthMethod *string `mapstructure:"client-auth-method"`
+	// private_key_jwt: the signing key is given as PEM, as a PEM file or as a JWKS file
+	PrivateKey     *string `mapstructure:"private-key"`
+	PrivateKeyFile *string `mapstructure:"private-key-file"`
+	JWKSFile       *string `mapstructure:"jwks-file"`
+	KeyID          *string `mapstructure:"key-id"`      // Selects the key of the JWKS and is set as kid of the assertions
+	SigningAlg     *string `mapstructure:"signing-alg"` // Defaults to RS256 for RSA keys, ESxxx for EC keys
+	// tls_client_auth
+	TLSCertFile *string `mapstructure:"tls-cert-file"`
+	TLSKeyFile  *string `mapstructure:"tls-key-file"`
+	TLSCAFile   *string `mapstructure:"tls-ca-file"`
+	// Service accounts only, ignored with a warning otherwise: requests DPoP-bound tokens (RFC 9449) with proofs signed
+	// by a generated key
+	DPoP *bool `mapstructure:"dpop"`
 }
 
 // IsClientConfig checks if the config is a client config or a username/password one
 func (oac *OAuth2Config) IsClientConfig() bool {
-	return oac != nil && oac.Realm != nil && oac.ClientID != nil && oac.ClientSecret != nil
+	if oac == nil || oac.Realm == nil || oac.ClientID == nil {
+		return false
+	}
+	// Other methods than client secret are only supported by service accounts
+	return oac.ClientSecret != nil || oac.clientAuthMethod() != ClientAuthSecret
+}
+
+func (oac *OAuth2Config) clientAuthMethod() string {
+	if oac.ClientAuthMethod == nil || *oac.ClientAuthMethod == "" {
+		return ClientAuthSecret
+	}
+	return *oac.ClientAuthMethod
 }
 
 type oauth2TokenProvider struct {
 	perRealmTokenInfo map[string]*oauth2TokenInfo
 	defaultKey        string
 	logger            Logger
+	dpop              DPoPSigner
 }
 
 type oauth2TokenInfo struct {
-	tokenSource oauth2.TokenSource
-	token       *oauth2.Token
+	fetchToken func() (*oauth2.Token, error) // Always calls the token endpoint
+	token      *oauth2.Token
+	mutex      sync.RWMutex // Protects token
+	flight     singleFlight[string]
 }
 
 // customTransport used to force header Forwarded
@@ -50,35 +83,49 @@ func (t *customTransport) RoundTrip(req *http.Request) (*http.Response, error) {
 
 // NewOAuth2TokenProvider creates an OidcTokenProvider
 func NewOAuth2TokenProvider(kcConfig keycloak.Config, oauth2Config OAuth2Config, logger Logger) OidcTokenProvider {
+	var withDPoP = oauth2Config.DPoP != nil && *oauth2Config.DPoP
 	if !oauth2Config.IsClientConfig() {
+		if withDPoP {
+			logger.Warn(context.Background(), "msg", "DPoP is only supported by service accounts, bearer tokens are requested")
+		}
+		if oauth2Config.OfflineAccess != nil && *oauth2Config.OfflineAccess {
+			return NewOfflineOidcTokenProvider(kcConfig, *oauth2Config.Realm, *oauth2Config.Username, *oauth2Config.Password, *oauth2Config.ClientID, logger)
+		}
 		return NewOidcTokenProvider(kcConfig, *oauth2Config.Realm, *oauth2Config.Username, *oauth2Config.Password, *oauth2Config.ClientID, logger)
 	}
-	var perRealmTokenInfo = make(map[string]*oauth2TokenInfo)
-	kcConfig.URIProvider.ForEachContextURI(func(targetRealm, host, _ string) {
-		var cfg = clientcredentials.Config{
-			ClientID:     *oauth2Config.ClientID,
-			ClientSecret: *oauth2Config.ClientSecret,
-			TokenURL:     fmt.Sprintf("%s/auth/realms/%s/protocol/openid-connect/token", kcConfig.AddrInternalAPI, *oauth2Config.Realm),
+	var dpop DPoPSigner
+	var dpopErr error
+	if withDPoP {
+		// Key generation only fails when the random source is broken. Tokens are not requested without the
+		// expected binding
+		if dpop, dpopErr = GenerateDPoPSigner(); dpopErr != nil {
+			logger.Warn(context.Background(), "msg", "Can't generate the DPoP key", "err", dpopErr.Error())
 		}
-		var client = &http.Client{
-			Transport: &customTransport{
-				base:          http.DefaultTransport,
-				forwardedHost: host,
-			},
-		}
-		var ctx = context.WithValue(context.Background(), oauth2.HTTPClient, client)
+	}
+	var perRealmTokenInfo = make(map[string]*oauth2TokenInfo)
+	for targetRealm, requestToken := range newClientTokenRequesters(kcConfig, oauth2Config, dpop, logger) {
 		perRealmTokenInfo[targetRealm] = &oauth2TokenInfo{
-			tokenSource: cfg.TokenSource(ctx),
+			fetchToken: func() (*oauth2.Token, error) {
+				if dpopErr != nil {
+					return nil, dpopErr
+				}
+				return requestToken(nil)
+			},
 		}
-	})
+	}
 
 	return &oauth2TokenProvider{
 		perRealmTokenInfo: perRealmTokenInfo,
 		defaultKey:        kcConfig.URIProvider.GetDefaultKey(),
 		logger:            logger,
+		dpop:              dpop,
 	}
 }
 
+func (o *oauth2TokenProvider) dpopSigner() DPoPSigner {
+	return o.dpop
+}
+
 func (o *oauth2TokenProvider) ProvideToken(ctx context.Context) (string, error) {
 	return o.ProvideTokenForRealm(ctx, o.defaultKey)
 }
@@ -92,12 +139,50 @@ func (o *oauth2TokenProvider) ProvideTokenForRealm(ctx context.Context, realm st
 		}
 		return o.ProvideTokenForRealm(ctx, o.defaultKey)
 	}
-	if oti.token == nil || !oti.token.Valid() {
-		var err error
-		oti.token, err = oti.tokenSource.Token()
-		if err != nil {
-			return "", err
+	if token, ok := oti.validToken(0); ok {
+		return token, nil
+	}
+	return oti.flight.do(func() (string, error) {
+		// The token may have been renewed by another caller in the meantime
+		if token, ok := oti.validToken(0); ok {
+			return token, nil
+		}
+		return oti.renewToken()
+	})
+}
+
+func (o *oauth2TokenProvider) refreshExpiring(_ context.Context, margin time.Duration) {
+	for _, oti := range o.perRealmTokenInfo {
+		oti.mutex.RLock()
+		var requested = oti.token != nil
+		oti.mutex.RUnlock()
+		if _, ok := oti.validToken(margin); requested && !ok {
+			_, _ = oti.flight.do(oti.renewToken)
 		}
 	}
-	return oti.token.AccessToken, nil
+}
+
+// validToken returns the access token if it is still valid in margin. oauth2 already considers tokens as expired a
+// few seconds before their expiry.
+func (oti *oauth2TokenInfo) validToken(margin time.Duration) (string, bool) {
+	oti.mutex.RLock()
+	defer oti.mutex.RUnlock()
+	if oti.token == nil || !oti.token.Valid() {
+		return "", false
+	}
+	if margin > 0 && !oti.token.Expiry.IsZero() && time.Until(oti.token.Expiry) <= margin {
+		return "", false
+	}
+	return oti.token.AccessToken, true
+}
+
+func (oti *oauth2TokenInfo) renewToken() (string, error) {
+	var token, err = oti.fetchToken()
+	if err != nil {
+		return "", err
+	}
+	oti.mutex.Lock()
+	defer oti.mutex.Unlock()
+	oti.token = token
+	return token.AccessToken, nil
 }
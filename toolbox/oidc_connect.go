@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"net/url"
 	"strings"
+	"sync"
 	"time"
 
 	errorhandler "github.com/cloudtrust/common-service/v2/errors"
@@ -37,15 +38,19 @@ type oidcTokenProvider struct {
 	reqBody           string // Added for fix CLOUDTRUST-6415
 	//username          string // Commented for fix CLOUDTRUST-6415
 	//password          string // Commented for fix CLOUDTRUST-6415
+	clientID   string
 	defaultKey string
 	logger     Logger
 }
 
 type oidcTokenInfo struct {
-	url        string    // Added for fix CLOUDTRUST-6415
-	oidcToken  oidcToken // Added for fix CLOUDTRUST-6415
-	validUntil int64     // Added for fix CLOUDTRUST-6415
-	forwarded  string
+	url               string    // Added for fix CLOUDTRUST-6415
+	oidcToken         oidcToken // Added for fix CLOUDTRUST-6415
+	validUntil        int64     // Added for fix CLOUDTRUST-6415
+	refreshValidUntil int64     // 0 when the refresh token does not expire, like offline tokens usually
+	forwarded         string
+	mutex             sync.RWMutex // Protects oidcToken, validUntil and refreshValidUntil
+	flight            singleFlight[string]
 	//oauth2Config *oauth2.Config // Commented for fix CLOUDTRUST-6415
 	//tokenSource  oauth2.TokenSource // Commented for fix CLOUDTRUST-6415
 }
@@ -53,10 +58,23 @@ type oidcTokenInfo struct {
 const (
 	// Max processing delay: let's assume that the user of OidcTokenProvider will have a maximum of 5 seconds to use the provided OIDC token
 	maxProcessingDelay = int64(5)
+	scopeOfflineAccess = "offline_access"
 )
 
-// NewOidcTokenProvider creates an OidcTokenProvider
+// NewOidcTokenProvider creates an OidcTokenProvider. Tokens are obtained with the password grant, then renewed with
+// the refresh token grant as long as the refresh token is valid.
 func NewOidcTokenProvider(config keycloak.Config, realm, username, password, clientID string, logger Logger) OidcTokenProvider {
+	return newOidcTokenProvider(config, realm, username, password, clientID, "", logger)
+}
+
+// NewOfflineOidcTokenProvider creates an OidcTokenProvider which requests an offline token: the refresh token is not
+// bound to the session of the technical user and can be used by long-running jobs. The technical user needs the
+// offline_access role.
+func NewOfflineOidcTokenProvider(config keycloak.Config, realm, username, password, clientID string, logger Logger) OidcTokenProvider {
+	return newOidcTokenProvider(config, realm, username, password, clientID, scopeOfflineAccess, logger)
+}
+
+func newOidcTokenProvider(config keycloak.Config, realm, username, password, clientID, scope string, logger Logger) *oidcTokenProvider {
 	var perRealmTokenInfo = make(map[string]*oidcTokenInfo)
 	config.URIProvider.ForEachContextURI(func(targetRealm, host, _ string) {
 		perRealmTokenInfo[targetRealm] = &oidcTokenInfo{
@@ -76,6 +94,9 @@ func NewOidcTokenProvider(config keycloak.Config, realm, username, password, cli
 	// If needed, can add &client_secret={secret}
 	var body = fmt.Sprintf("grant_type=password&client_id=%s&username=%s&password=%s",
 		url.QueryEscape(clientID), url.QueryEscape(username), url.QueryEscape(password))
+	if scope != "" {
+		body += "&scope=" + url.QueryEscape(scope)
+	}
 
 	return &oidcTokenProvider{
 		timeout:           config.Timeout,
@@ -83,6 +104,7 @@ func NewOidcTokenProvider(config keycloak.Config, realm, username, password, cli
 		reqBody:           body, // Added for fix CLOUDTRUST-6415
 		//username:          username, // Commented for fix CLOUDTRUST-6415
 		//password:          password, // Commented for fix CLOUDTRUST-6415
+		clientID:   clientID,
 		defaultKey: config.URIProvider.GetDefaultKey(),
 		logger:     logger,
 	}
@@ -102,16 +124,67 @@ func (o *oidcTokenProvider) ProvideTokenForRealm(ctx context.Context, realm stri
 		return o.ProvideTokenForRealm(ctx, o.defaultKey)
 	}
 	// Added for fix CLOUDTRUST-6415
-	if time.Now().Unix()+maxProcessingDelay < oti.validUntil {
-		return oti.oidcToken.AccessToken, nil
+	if token, ok := oti.validToken(maxProcessingDelay); ok {
+		return token, nil
 	}
+	return oti.flight.do(func() (string, error) {
+		// The token may have been renewed by another caller in the meantime
+		if token, ok := oti.validToken(maxProcessingDelay); ok {
+			return token, nil
+		}
+		return o.renewToken(ctx, oti)
+	})
+}
+
+func (o *oidcTokenProvider) refreshExpiring(ctx context.Context, margin time.Duration) {
+	for _, oti := range o.perRealmTokenInfo {
+		oti.mutex.RLock()
+		var requested = oti.validUntil > 0
+		oti.mutex.RUnlock()
+		if _, ok := oti.validToken(int64(margin.Seconds())); requested && !ok {
+			_, _ = oti.flight.do(func() (string, error) {
+				return o.renewToken(ctx, oti)
+			})
+		}
+	}
+}
 
+// validToken returns the access token if it is still valid in delay seconds
+func (oti *oidcTokenInfo) validToken(delay int64) (string, bool) {
+	oti.mutex.RLock()
+	defer oti.mutex.RUnlock()
+	if time.Now().Unix()+delay < oti.validUntil {
+		return oti.oidcToken.AccessToken, true
+	}
+	return "", false
+}
+
+func (o *oidcTokenProvider) renewToken(ctx context.Context, oti *oidcTokenInfo) (string, error) {
+	oti.mutex.RLock()
+	var refreshToken = oti.oidcToken.RefreshToken
+	var refreshable = refreshToken != "" && (oti.refreshValidUntil == 0 || time.Now().Unix()+maxProcessingDelay < oti.refreshValidUntil)
+	oti.mutex.RUnlock()
+
+	// The refresh token grant avoids sending the password of the technical user again
+	if refreshable {
+		var body = fmt.Sprintf("grant_type=refresh_token&client_id=%s&refresh_token=%s",
+			url.QueryEscape(o.clientID), url.QueryEscape(refreshToken))
+		if token, err := o.requestToken(ctx, oti, body); err == nil {
+			return token, nil
+		}
+		o.logger.Warn(ctx, "msg", "Can't refresh token, falling back to password grant")
+	}
+	return o.requestToken(ctx, oti, o.reqBody)
+}
+
+// requestToken calls the token endpoint and stores the obtained token. The stored token is left unchanged on failure.
+func (o *oidcTokenProvider) requestToken(ctx context.Context, oti *oidcTokenInfo, reqBody string) (string, error) {
 	var mimeType = "application/x-www-form-urlencoded"
 	var httpClient = http.Client{
 		Timeout: o.timeout,
 	}
 	//var req *http.Request
-	var req, err = http.NewRequest("POST", oti.url, strings.NewReader(o.reqBody))
+	var req, err = http.NewRequest("POST", oti.url, strings.NewReader(reqBody))
 	if err != nil {
 		return "", err
 	}
@@ -123,6 +196,7 @@ func (o *oidcTokenProvider) ProvideTokenForRealm(ctx context.Context, realm stri
 		o.logger.Warn(ctx, "msg", err.Error())
 		return "", errorhandler.CreateInternalServerError("unexpected.httpResponse")
 	}
+	defer resp.Body.Close()
 	if resp.StatusCode == http.StatusUnauthorized {
 		o.logger.Warn(ctx, "msg", "Technical user credentials are invalid")
 		return "", errorhandler.Error{
@@ -138,36 +212,20 @@ func (o *oidcTokenProvider) ProvideTokenForRealm(ctx context.Context, realm stri
 	buf := new(bytes.Buffer)
 	_, _ = buf.ReadFrom(resp.Body)
 
-	err = json.Unmarshal(buf.Bytes(), &oti.oidcToken)
+	var token oidcToken
+	err = json.Unmarshal(buf.Bytes(), &token)
 	if err != nil {
 		o.logger.Warn(ctx, "msg", fmt.Sprintf("Can't deserialize token. JSON: %s", buf.String()))
 		return "", errorhandler.CreateInternalServerError("unexpected.oidcToken")
 	}
-	oti.validUntil = time.Now().Unix() + oti.oidcToken.ExpiresIn
-
-	return oti.oidcToken.AccessToken, nil
-	// First time we are requesting a token
-	// Commented for fix CLOUDTRUST-6415
-	/*if oti.tokenSource == nil {
-		client := &http.Client{
-			Transport: &customTransport{
-				base:          http.DefaultTransport,
-				forwardedHost: oti.forwarded,
-			},
-			Timeout: o.timeout,
-		}
-		var tokenCtx = context.WithValue(context.Background(), oauth2.HTTPClient, client)
-		var token, err = oti.oauth2Config.PasswordCredentialsToken(tokenCtx, o.username, o.password)
-		if err != nil {
-			return "", err
-		}
-		oti.tokenSource = oti.oauth2Config.TokenSource(ctx, token)
-		return token.AccessToken, nil
-	}
-	// A token has already been requested... Get it (automatically refresh it if needed)
-	var token, err = oti.tokenSource.Token()
-	if err != nil {
-		return "", err
+	var now = time.Now().Unix()
+	oti.mutex.Lock()
+	defer oti.mutex.Unlock()
+	oti.oidcToken = token
+	oti.validUntil = now + token.ExpiresIn
+	oti.refreshValidUntil = 0
+	if token.RefreshExpiresIn > 0 {
+		oti.refreshValidUntil = now + token.RefreshExpiresIn
 	}
-	return token.AccessToken, nil*/
+	return token.AccessToken, nil
 }
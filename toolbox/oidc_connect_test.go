@@ -94,3 +94,89 @@ func TestCreateToken(t *testing.T) {
 		runFailingTest(t, invalidURIProvider, "bad-json")
 	})
 }
+
+type tokenEndpoint struct {
+	grants       []string
+	scopes       []string
+	refreshError bool
+}
+
+func (te *tokenEndpoint) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	_ = r.ParseForm()
+	var grantType = r.PostForm.Get("grant_type")
+	te.grants = append(te.grants, grantType)
+	te.scopes = append(te.scopes, r.PostForm.Get("scope"))
+	w.Header().Set("Content-Type", "application/json")
+	if grantType == "refresh_token" && te.refreshError {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error": "invalid_grant"}`))
+		return
+	}
+	// Access tokens expire within the max processing delay: they are renewed on each call
+	w.Write([]byte(`{"access_token": "access-` + grantType + `", "expires_in": 1, "refresh_token": "refresh", "refresh_expires_in": 60}`))
+}
+
+func TestRefreshToken(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	var mockLogger = mock.NewLogger(mockCtrl)
+	mockLogger.EXPECT().Warn(gomock.Any(), gomock.Any()).AnyTimes()
+
+	var endpoint = &tokenEndpoint{}
+	r := mux.NewRouter()
+	r.Handle("/auth/realms/master/protocol/openid-connect/token", endpoint)
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	var uriProvider, _ = NewKeycloakURIProviderFromArray([]string{ts.URL})
+	var config = keycloak.Config{URIProvider: uriProvider, AddrInternalAPI: ts.URL}
+	var ctx = context.TODO()
+
+	t.Run("Refresh token grant", func(t *testing.T) {
+		*endpoint = tokenEndpoint{}
+		var p = NewOidcTokenProvider(config, "master", "user", "passwd", "clientID", mockLogger)
+		var token, err = p.ProvideToken(ctx)
+		assert.Nil(t, err)
+		assert.Equal(t, "access-password", token)
+
+		token, err = p.ProvideToken(ctx)
+		assert.Nil(t, err)
+		assert.Equal(t, "access-refresh_token", token)
+		assert.Equal(t, []string{"password", "refresh_token"}, endpoint.grants)
+		assert.Equal(t, []string{"", ""}, endpoint.scopes)
+	})
+	t.Run("Fallback to password grant", func(t *testing.T) {
+		*endpoint = tokenEndpoint{refreshError: true}
+		var p = NewOidcTokenProvider(config, "master", "user", "passwd", "clientID", mockLogger)
+		var _, err = p.ProvideToken(ctx)
+		assert.Nil(t, err)
+
+		var token string
+		token, err = p.ProvideToken(ctx)
+		assert.Nil(t, err)
+		assert.Equal(t, "access-password", token)
+		assert.Equal(t, []string{"password", "refresh_token", "password"}, endpoint.grants)
+	})
+	t.Run("Expired refresh token", func(t *testing.T) {
+		*endpoint = tokenEndpoint{}
+		var p = newOidcTokenProvider(config, "master", "user", "passwd", "clientID", "", mockLogger)
+		var _, err = p.ProvideToken(ctx)
+		assert.Nil(t, err)
+		p.perRealmTokenInfo["default"].refreshValidUntil = time.Now().Unix()
+
+		_, err = p.ProvideToken(ctx)
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"password", "password"}, endpoint.grants)
+	})
+	t.Run("Offline token", func(t *testing.T) {
+		*endpoint = tokenEndpoint{}
+		var offline = true
+		var creds = createTechnicalUser("master", "user", "passwd", "clientID")
+		creds.OfflineAccess = &offline
+		var p = NewOAuth2TokenProvider(config, creds, mockLogger)
+		var _, err = p.ProvideToken(ctx)
+		assert.Nil(t, err)
+		assert.Equal(t, []string{scopeOfflineAccess}, endpoint.scopes)
+	})
+}
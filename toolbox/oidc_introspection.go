@@ -0,0 +1,308 @@
+package toolbox
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cloudtrust/keycloak-client/v2"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Expired entries are removed from the token caches when they grow beyond this size
+const tokenCacheSweepSize = 1000
+
+// IntrospectionResult is the response of the token introspection endpoint (RFC 7662)
+type IntrospectionResult struct {
+	Active    bool   `json:"active"`
+	Scope     string `json:"scope,omitempty"`
+	ClientID  string `json:"client_id,omitempty"`
+	Username  string `json:"username,omitempty"`
+	TokenType string `json:"token_type,omitempty"`
+	ExpiresAt int64  `json:"exp,omitempty"`
+	IssuedAt  int64  `json:"iat,omitempty"`
+	Subject   string `json:"sub,omitempty"`
+	Issuer    string `json:"iss,omitempty"`
+	SessionID string `json:"sid,omitempty"`
+}
+
+// TokenIntrospector asks Keycloak whether a token is still active. Unlike the local verification of OidcVerifier, it
+// detects revoked tokens, opaque tokens and sessions logged out on the server side.
+type TokenIntrospector interface {
+	Introspect(ctx context.Context, issuer string, realm string, token string) (IntrospectionResult, error)
+}
+
+// UserInfoClient gets the claims of the user of an access token from the userinfo endpoint
+type UserInfoClient interface {
+	GetUserInfo(ctx context.Context, issuer string, realm string, accessToken string) (map[string]any, error)
+}
+
+// issuerClients sends requests to the OIDC endpoints of the issuers of a Keycloak configuration. Requests are sent to
+// the internal API with the Forwarded host of the issuer.
+type issuerClients map[string]*http.Client
+
+func newIssuerClients(config keycloak.Config, base http.RoundTripper) (issuerClients, error) {
+	var internalURL, err = url.Parse(config.AddrInternalAPI)
+	if err != nil {
+		return nil, err
+	}
+	var res = issuerClients{}
+	for _, value := range config.URIProvider.GetAllBaseURIs() {
+		var externalURL, err = url.Parse(value)
+		if err != nil {
+			return nil, err
+		}
+		res[getProtocolAndDomain(value)] = &http.Client{
+			Timeout: config.Timeout,
+			Transport: &forwardedTransport{
+				base:        base,
+				internalURL: internalURL,
+				externalURL: externalURL,
+			},
+		}
+	}
+	return res, nil
+}
+
+// realmIssuer returns the issuer of realm on the domain of issuer and the client to use to call it
+func (ic issuerClients) realmIssuer(issuer string, realm string) (string, *http.Client, error) {
+	var domain = getProtocolAndDomain(issuer)
+	if client, ok := ic[domain]; ok {
+		return fmt.Sprintf("%s/auth/realms/%s", domain, url.PathEscape(realm)), client, nil
+	}
+	return "", nil, errors.New("unknownIssuer")
+}
+
+func doOidcRequest(client *http.Client, req *http.Request, data any) error {
+	var resp, err = client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	var body []byte
+	if body, err = io.ReadAll(resp.Body); err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return keycloak.HTTPError{HTTPStatus: resp.StatusCode, Message: string(body)}
+	}
+	if err = json.Unmarshal(body, data); err != nil {
+		return errors.New(keycloak.MsgErrCannotUnmarshal + "." + keycloak.Response)
+	}
+	return nil
+}
+
+type tokenIntrospector struct {
+	clients  issuerClients
+	clientID string
+	secret   string
+	signer   *clientAssertionSigner
+	cache    *tokenCache[IntrospectionResult]
+}
+
+// NewTokenIntrospector creates a TokenIntrospector which authenticates as the client of oauth2Config. This client must
+// exist in the realms of the introspected tokens. Results are cached for cacheTTL at most and never after the expiry of
+// the token. A zero cacheTTL disables the cache.
+func NewTokenIntrospector(kcConfig keycloak.Config, oauth2Config OAuth2Config, cacheTTL time.Duration) (TokenIntrospector, error) {
+	if oauth2Config.ClientID == nil {
+		return nil, errors.New(keycloak.MsgErrMissingParam + ".clientID")
+	}
+	var transport, signer, err = newClientAuthentication(oauth2Config)
+	if err != nil {
+		return nil, err
+	}
+	var clients issuerClients
+	if clients, err = newIssuerClients(kcConfig, transport); err != nil {
+		return nil, err
+	}
+	var res = &tokenIntrospector{
+		clients:  clients,
+		clientID: *oauth2Config.ClientID,
+		signer:   signer,
+		cache:    newTokenCache[IntrospectionResult](cacheTTL),
+	}
+	if oauth2Config.clientAuthMethod() == ClientAuthSecret {
+		res.secret = stringOrEmpty(oauth2Config.ClientSecret)
+	}
+	return res, nil
+}
+
+func (ti *tokenIntrospector) Introspect(ctx context.Context, issuer string, realm string, token string) (IntrospectionResult, error) {
+	var realmIssuer, client, err = ti.clients.realmIssuer(issuer, realm)
+	if err != nil {
+		return IntrospectionResult{}, err
+	}
+	var key = tokenCacheKey(realmIssuer, token)
+	if result, ok := ti.cache.get(key); ok {
+		return result, nil
+	}
+
+	var form = url.Values{"token": {token}}
+	if ti.secret == "" {
+		form.Set("client_id", ti.clientID)
+	}
+	if ti.signer != nil {
+		var assertion string
+		if assertion, err = ti.signer.sign(realmIssuer); err != nil {
+			return IntrospectionResult{}, err
+		}
+		form.Set("client_assertion_type", clientAssertionType)
+		form.Set("client_assertion", assertion)
+	}
+	var req *http.Request
+	req, err = http.NewRequestWithContext(ctx, http.MethodPost, realmIssuer+"/protocol/openid-connect/token/introspect", strings.NewReader(form.Encode()))
+	if err != nil {
+		return IntrospectionResult{}, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if ti.secret != "" {
+		req.SetBasicAuth(url.QueryEscape(ti.clientID), url.QueryEscape(ti.secret))
+	}
+
+	var result IntrospectionResult
+	if err = doOidcRequest(client, req, &result); err != nil {
+		return IntrospectionResult{}, err
+	}
+	var expiresAt time.Time
+	if result.ExpiresAt > 0 {
+		expiresAt = time.Unix(result.ExpiresAt, 0)
+	}
+	// Inactive tokens never become active again
+	ti.cache.set(key, result, expiresAt)
+	return result, nil
+}
+
+type userInfoClient struct {
+	clients issuerClients
+	cache   *tokenCache[map[string]any]
+}
+
+// NewUserInfoClient creates a UserInfoClient. Results are cached for cacheTTL at most and never after the expiry of the
+// access token. A zero cacheTTL disables the cache.
+func NewUserInfoClient(kcConfig keycloak.Config, cacheTTL time.Duration) (UserInfoClient, error) {
+	var clients, err = newIssuerClients(kcConfig, http.DefaultTransport)
+	if err != nil {
+		return nil, err
+	}
+	return &userInfoClient{
+		clients: clients,
+		cache:   newTokenCache[map[string]any](cacheTTL),
+	}, nil
+}
+
+func (uc *userInfoClient) GetUserInfo(ctx context.Context, issuer string, realm string, accessToken string) (map[string]any, error) {
+	var realmIssuer, client, err = uc.clients.realmIssuer(issuer, realm)
+	if err != nil {
+		return nil, err
+	}
+	var key = tokenCacheKey(realmIssuer, accessToken)
+	if claims, ok := uc.cache.get(key); ok {
+		return claims, nil
+	}
+
+	var req *http.Request
+	if req, err = http.NewRequestWithContext(ctx, http.MethodGet, realmIssuer+"/protocol/openid-connect/userinfo", nil); err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	var claims map[string]any
+	if err = doOidcRequest(client, req, &claims); err != nil {
+		return nil, err
+	}
+	uc.cache.set(key, claims, unverifiedExpiry(accessToken))
+	return claims, nil
+}
+
+// unverifiedExpiry returns the expiry of a JWT without verifying it, or a zero time for opaque tokens
+func unverifiedExpiry(token string) time.Time {
+	var parsed, _, err = jwt.NewParser().ParseUnverified(token, jwt.MapClaims{})
+	if err != nil {
+		return time.Time{}
+	}
+	if exp, err := parsed.Claims.GetExpirationTime(); err == nil && exp != nil {
+		return exp.Time
+	}
+	return time.Time{}
+}
+
+func tokenCacheKey(realmIssuer string, token string) string {
+	var hash = sha256.Sum256([]byte(realmIssuer + "\n" + token))
+	return hex.EncodeToString(hash[:])
+}
+
+type tokenCacheEntry[V any] struct {
+	value     V
+	expiresAt time.Time
+}
+
+// tokenCache keeps the values associated to tokens for a TTL bounded by the expiry of the tokens
+type tokenCache[V any] struct {
+	ttl     time.Duration
+	mutex   sync.Mutex
+	entries map[string]tokenCacheEntry[V]
+}
+
+func newTokenCache[V any](ttl time.Duration) *tokenCache[V] {
+	return &tokenCache[V]{
+		ttl:     ttl,
+		entries: map[string]tokenCacheEntry[V]{},
+	}
+}
+
+func (tc *tokenCache[V]) get(key string) (V, bool) {
+	tc.mutex.Lock()
+	defer tc.mutex.Unlock()
+	if entry, ok := tc.entries[key]; ok && time.Now().Before(entry.expiresAt) {
+		return entry.value, true
+	}
+	var zero V
+	return zero, false
+}
+
+// set caches value until now+ttl or tokenExpiry if it is earlier. A zero tokenExpiry means that it is unknown.
+func (tc *tokenCache[V]) set(key string, value V, tokenExpiry time.Time) {
+	tc.store(key, value, tokenExpiry, false)
+}
+
+// add caches value like set, unless a value is already cached for key. It returns false in this case.
+func (tc *tokenCache[V]) add(key string, value V, tokenExpiry time.Time) bool {
+	return tc.store(key, value, tokenExpiry, true)
+}
+
+func (tc *tokenCache[V]) store(key string, value V, tokenExpiry time.Time, onlyIfAbsent bool) bool {
+	if tc.ttl <= 0 {
+		return true
+	}
+	var now = time.Now()
+	var expiresAt = now.Add(tc.ttl)
+	if !tokenExpiry.IsZero() && tokenExpiry.Before(expiresAt) {
+		expiresAt = tokenExpiry
+	}
+	if !now.Before(expiresAt) {
+		return true
+	}
+
+	tc.mutex.Lock()
+	defer tc.mutex.Unlock()
+	if entry, ok := tc.entries[key]; onlyIfAbsent && ok && now.Before(entry.expiresAt) {
+		return false
+	}
+	if len(tc.entries) >= tokenCacheSweepSize {
+		for cachedKey, entry := range tc.entries {
+			if !now.Before(entry.expiresAt) {
+				delete(tc.entries, cachedKey)
+			}
+		}
+	}
+	tc.entries[key] = tokenCacheEntry[V]{value: value, expiresAt: expiresAt}
+	return true
+}
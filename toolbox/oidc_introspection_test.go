@@ -0,0 +1,171 @@
+package toolbox
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cloudtrust/keycloak-client/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+// oidcEndpoints serves the introspection and userinfo endpoints and records the requests it receives
+type oidcEndpoints struct {
+	requests  int
+	form      url.Values
+	path      string
+	forwarded string
+	user      string
+	password  string
+	exp       int64
+}
+
+func (oe *oidcEndpoints) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	oe.requests++
+	_ = r.ParseForm()
+	oe.form = r.PostForm
+	oe.path = r.URL.Path
+	oe.forwarded = r.Header.Get("Forwarded")
+	oe.user, oe.password, _ = r.BasicAuth()
+	w.Header().Set("Content-Type", "application/json")
+	switch {
+	case strings.HasSuffix(r.URL.Path, "/token/introspect"):
+		var active = oe.form.Get("token") != "revoked"
+		json.NewEncoder(w).Encode(IntrospectionResult{Active: active, Username: "john", ExpiresAt: oe.exp})
+	case r.Header.Get("Authorization") == "Bearer invalid":
+		w.WriteHeader(http.StatusUnauthorized)
+	default:
+		w.Write([]byte(`{"sub": "1234", "preferred_username": "john"}`))
+	}
+}
+
+func TestTokenIntrospector(t *testing.T) {
+	var endpoints = &oidcEndpoints{}
+	var ts = httptest.NewServer(endpoints)
+	defer ts.Close()
+
+	var uriProvider, _ = NewKeycloakURIProviderFromArray([]string{"https://public.domain.ch"})
+	var kcConfig = keycloak.Config{URIProvider: uriProvider, AddrInternalAPI: ts.URL}
+	var issuer = "https://public.domain.ch/auth/realms/my-realm"
+	var ctx = context.TODO()
+
+	t.Run("Invalid configuration", func(t *testing.T) {
+		var _, err = NewTokenIntrospector(kcConfig, createKeyConfig(ClientAuthPrivateKeyJWT), time.Minute)
+		assert.NotNil(t, err)
+	})
+
+	var introspector, err = NewTokenIntrospector(kcConfig, createServiceAccount("my-realm", "my-client", "secret"), time.Minute)
+	assert.Nil(t, err)
+
+	t.Run("Unknown issuer", func(t *testing.T) {
+		var _, err = introspector.Introspect(ctx, "https://unknown.domain.ch/auth/realms/my-realm", "my-realm", "token")
+		assert.NotNil(t, err)
+	})
+	t.Run("Active token", func(t *testing.T) {
+		endpoints.requests = 0
+		endpoints.exp = time.Now().Add(time.Hour).Unix()
+		var result, err = introspector.Introspect(ctx, issuer, "my-realm", "active")
+		assert.Nil(t, err)
+		assert.True(t, result.Active)
+		assert.Equal(t, "john", result.Username)
+		assert.Equal(t, "/auth/realms/my-realm/protocol/openid-connect/token/introspect", endpoints.path)
+		assert.Equal(t, "host=public.domain.ch;proto=https", endpoints.forwarded)
+		assert.Equal(t, "active", endpoints.form.Get("token"))
+		assert.Equal(t, "my-client", endpoints.user)
+		assert.Equal(t, "secret", endpoints.password)
+
+		// The result is cached
+		_, err = introspector.Introspect(ctx, issuer, "my-realm", "active")
+		assert.Nil(t, err)
+		assert.Equal(t, 1, endpoints.requests)
+	})
+	t.Run("Revoked token", func(t *testing.T) {
+		var result, err = introspector.Introspect(ctx, issuer, "my-realm", "revoked")
+		assert.Nil(t, err)
+		assert.False(t, result.Active)
+	})
+	t.Run("Cache is bounded by token expiry", func(t *testing.T) {
+		endpoints.requests = 0
+		endpoints.exp = time.Now().Add(-time.Second).Unix()
+		for range 2 {
+			var _, err = introspector.Introspect(ctx, issuer, "my-realm", "expired")
+			assert.Nil(t, err)
+		}
+		assert.Equal(t, 2, endpoints.requests)
+	})
+	t.Run("Client assertion", func(t *testing.T) {
+		var config = createKeyConfig(ClientAuthPrivateKeyJWT)
+		var key, _ = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		var keyDER, _ = x509.MarshalECPrivateKey(key)
+		config.PrivateKey = ptr(string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})))
+		var introspector, err = NewTokenIntrospector(kcConfig, config, 0)
+		assert.Nil(t, err)
+		_, err = introspector.Introspect(ctx, issuer, "my-realm", "active")
+		assert.Nil(t, err)
+		assert.Empty(t, endpoints.user)
+		assert.Equal(t, "my-client", endpoints.form.Get("client_id"))
+		assert.Equal(t, clientAssertionType, endpoints.form.Get("client_assertion_type"))
+		assert.NotEmpty(t, endpoints.form.Get("client_assertion"))
+	})
+}
+
+func TestUserInfoClient(t *testing.T) {
+	var endpoints = &oidcEndpoints{}
+	var ts = httptest.NewServer(endpoints)
+	defer ts.Close()
+
+	var uriProvider, _ = NewKeycloakURIProviderFromArray([]string{"https://public.domain.ch"})
+	var kcConfig = keycloak.Config{URIProvider: uriProvider, AddrInternalAPI: ts.URL}
+	var issuer = "https://public.domain.ch/auth/realms/my-realm"
+	var ctx = context.TODO()
+	var userInfoClient, err = NewUserInfoClient(kcConfig, time.Minute)
+	assert.Nil(t, err)
+
+	t.Run("Success", func(t *testing.T) {
+		endpoints.requests = 0
+		var accessToken, _ = jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		}).SignedString([]byte("secret"))
+		var claims, err = userInfoClient.GetUserInfo(ctx, issuer, "my-realm", accessToken)
+		assert.Nil(t, err)
+		assert.Equal(t, "john", claims["preferred_username"])
+		assert.Equal(t, "/auth/realms/my-realm/protocol/openid-connect/userinfo", endpoints.path)
+		assert.Equal(t, "host=public.domain.ch;proto=https", endpoints.forwarded)
+
+		_, err = userInfoClient.GetUserInfo(ctx, issuer, "my-realm", accessToken)
+		assert.Nil(t, err)
+		assert.Equal(t, 1, endpoints.requests)
+	})
+	t.Run("Invalid token", func(t *testing.T) {
+		var _, err = userInfoClient.GetUserInfo(ctx, issuer, "my-realm", "invalid")
+		assert.Equal(t, keycloak.HTTPError{HTTPStatus: http.StatusUnauthorized, Message: ""}, err)
+	})
+}
+
+func TestTokenCache(t *testing.T) {
+	var cache = newTokenCache[string](time.Minute)
+	cache.set("key", "value", time.Time{})
+	var value, ok = cache.get("key")
+	assert.True(t, ok)
+	assert.Equal(t, "value", value)
+
+	cache.set("expired", "value", time.Now().Add(-time.Second))
+	_, ok = cache.get("expired")
+	assert.False(t, ok)
+
+	var disabled = newTokenCache[string](0)
+	disabled.set("key", "value", time.Time{})
+	_, ok = disabled.get("key")
+	assert.False(t, ok)
+}
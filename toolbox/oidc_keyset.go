@@ -0,0 +1,139 @@
+package toolbox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/go-kit/kit/metrics"
+)
+
+// Signature algorithms accepted when parsing tokens. Verifiers then only accept the algorithms of their options.
+var supportedSigningAlgs = []jose.SignatureAlgorithm{
+	jose.RS256, jose.RS384, jose.RS512,
+	jose.ES256, jose.ES384, jose.ES512,
+	jose.PS256, jose.PS384, jose.PS512,
+	jose.EdDSA,
+}
+
+// verifySignature verifies the signature of jwt with the keys returned by keysFor for the key ID of the token
+func verifySignature(jwt string, keysFor func(keyID string) ([]jose.JSONWebKey, error)) ([]byte, error) {
+	var jws, err = jose.ParseSigned(jwt, supportedSigningAlgs)
+	if err != nil {
+		return nil, fmt.Errorf("malformed jwt: %v", err)
+	}
+	var keys []jose.JSONWebKey
+	if keys, err = keysFor(jws.Signatures[0].Header.KeyID); err != nil {
+		return nil, err
+	}
+	for _, key := range keys {
+		if payload, err := jws.Verify(&key); err == nil {
+			return payload, nil
+		}
+	}
+	return nil, errors.New("failed to verify id token signature")
+}
+
+// filterKeys returns the keys matching keyID, or all of them if keyID is empty
+func filterKeys(keys []jose.JSONWebKey, keyID string) []jose.JSONWebKey {
+	var res []jose.JSONWebKey
+	for _, key := range keys {
+		if keyID == "" || key.KeyID == keyID {
+			res = append(res, key)
+		}
+	}
+	return res
+}
+
+// remoteKeySet contains the keys of a realm. They are downloaded again when a token is signed with an unknown key ID,
+// which happens after a key rotation in Keycloak. Refreshes are done at most once per minRefreshInterval so that
+// tokens with forged key IDs can't flood Keycloak.
+type remoteKeySet struct {
+	client             *http.Client
+	jwksURL            string
+	realm              string
+	minRefreshInterval time.Duration
+	refreshes          metrics.Counter
+	refreshMutex       sync.Mutex
+	mutex              sync.RWMutex
+	keys               []jose.JSONWebKey
+	refreshedAt        time.Time
+}
+
+func (ks *remoteKeySet) VerifySignature(ctx context.Context, jwt string) ([]byte, error) {
+	return verifySignature(jwt, func(keyID string) ([]jose.JSONWebKey, error) {
+		var keys, refreshedAt = ks.keysWithID(keyID)
+		if len(keys) > 0 {
+			return keys, nil
+		}
+		return ks.refresh(ctx, keyID, refreshedAt)
+	})
+}
+
+// keysWithID returns the known keys matching keyID and the time they were downloaded
+func (ks *remoteKeySet) keysWithID(keyID string) ([]jose.JSONWebKey, time.Time) {
+	ks.mutex.RLock()
+	defer ks.mutex.RUnlock()
+	return filterKeys(ks.keys, keyID), ks.refreshedAt
+}
+
+// refresh downloads the keys, unless another caller did it since seenAt, and returns the ones matching keyID
+func (ks *remoteKeySet) refresh(ctx context.Context, keyID string, seenAt time.Time) ([]jose.JSONWebKey, error) {
+	ks.refreshMutex.Lock()
+	defer ks.refreshMutex.Unlock()
+
+	if keys, refreshedAt := ks.keysWithID(keyID); refreshedAt.After(seenAt) {
+		return keys, nil
+	} else if !refreshedAt.IsZero() && time.Since(refreshedAt) < ks.minRefreshInterval {
+		return nil, fmt.Errorf("unknown key ID %s", keyID)
+	}
+
+	var keys, err = ks.download(ctx)
+	if ks.refreshes != nil {
+		var result = "success"
+		if err != nil {
+			result = "failure"
+		}
+		ks.refreshes.With("realm", ks.realm, "result", result).Add(1)
+	}
+	if err != nil {
+		return nil, err
+	}
+	ks.mutex.Lock()
+	ks.keys = keys
+	ks.refreshedAt = time.Now()
+	ks.mutex.Unlock()
+
+	var res, _ = ks.keysWithID(keyID)
+	return res, nil
+}
+
+func (ks *remoteKeySet) download(ctx context.Context) ([]jose.JSONWebKey, error) {
+	var req, err = http.NewRequestWithContext(ctx, http.MethodGet, ks.jwksURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	var resp *http.Response
+	if resp, err = ks.client.Do(req); err != nil {
+		return nil, fmt.Errorf("can't fetch keys: %v", err)
+	}
+	defer resp.Body.Close()
+	var body []byte
+	if body, err = io.ReadAll(resp.Body); err != nil {
+		return nil, fmt.Errorf("can't fetch keys: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("can't fetch keys: %s %s", resp.Status, body)
+	}
+	var keySet jose.JSONWebKeySet
+	if err = json.Unmarshal(body, &keySet); err != nil {
+		return nil, fmt.Errorf("can't decode keys: %v", err)
+	}
+	return keySet.Keys, nil
+}
@@ -0,0 +1,126 @@
+package toolbox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/cloudtrust/keycloak-client/v2"
+	"github.com/go-jose/go-jose/v4"
+)
+
+// staticVerifierProvider creates verifiers which check the tokens with a static key set, without calling Keycloak
+type staticVerifierProvider struct {
+	baseURL   string
+	keySet    *staticKeySet
+	config    VerifierConfig
+	verifiers sync.Map // Verifiers are kept per realm so that they share their DPoP replay cache
+}
+
+// NewStaticVerifierProvider creates an OidcVerifierProvider which verifies the tokens of the realms of baseURL, such as
+// https://idp.domain.ch, with the given keys. No OIDC discovery is done.
+func NewStaticVerifierProvider(baseURL string, keys jose.JSONWebKeySet, config VerifierConfig) OidcVerifierProvider {
+	return &staticVerifierProvider{
+		baseURL: baseURL,
+		keySet:  &staticKeySet{keys: keys.Keys},
+		config:  config,
+	}
+}
+
+// NewJWKSFileVerifierProvider creates an OidcVerifierProvider which verifies the tokens of the realms of baseURL with
+// the keys of a JWKS file. When reloadInterval is not zero, the file is read again when it has been modified, at most
+// once per reloadInterval. The previous keys are kept if the modified file can't be loaded.
+func NewJWKSFileVerifierProvider(baseURL string, filename string, reloadInterval time.Duration, config VerifierConfig) (OidcVerifierProvider, error) {
+	var keySet = &staticKeySet{
+		filename:       filename,
+		reloadInterval: reloadInterval,
+	}
+	if err := keySet.load(); err != nil {
+		return nil, err
+	}
+	return &staticVerifierProvider{
+		baseURL: baseURL,
+		keySet:  keySet,
+		config:  config,
+	}, nil
+}
+
+func (sp *staticVerifierProvider) GetOidcVerifier(realm string) (OidcVerifier, error) {
+	if verifier, ok := sp.verifiers.Load(realm); ok {
+		return verifier.(*cachedVerifier), nil
+	}
+	var issuer = fmt.Sprintf("%s/auth/realms/%s", getProtocolAndDomain(sp.baseURL), realm)
+	var options = sp.config.forRealm(realm)
+	var res = newCachedVerifier(context.Background(), issuer, sp.keySet, options, newDPoPReplayCache(options))
+	var verifier, _ = sp.verifiers.LoadOrStore(realm, &res)
+	return verifier.(*cachedVerifier), nil
+}
+
+// staticKeySet contains keys provided at creation or loaded from a JWKS file
+type staticKeySet struct {
+	filename       string
+	reloadInterval time.Duration
+	mutex          sync.RWMutex
+	keys           []jose.JSONWebKey
+	modTime        time.Time
+	checkedAt      time.Time
+}
+
+func (ks *staticKeySet) VerifySignature(_ context.Context, jwt string) ([]byte, error) {
+	if ks.filename != "" && ks.reloadInterval > 0 {
+		ks.mutex.RLock()
+		var reloadDue = time.Since(ks.checkedAt) >= ks.reloadInterval
+		ks.mutex.RUnlock()
+		if reloadDue {
+			_ = ks.load()
+		}
+	}
+	return verifySignature(jwt, func(keyID string) ([]jose.JSONWebKey, error) {
+		ks.mutex.RLock()
+		defer ks.mutex.RUnlock()
+		return filterKeys(ks.keys, keyID), nil
+	})
+}
+
+// load reads the JWKS file if it has been modified since it was last loaded
+func (ks *staticKeySet) load() error {
+	ks.mutex.Lock()
+	defer ks.mutex.Unlock()
+	ks.checkedAt = time.Now()
+
+	var info, err = os.Stat(ks.filename)
+	if err != nil {
+		return err
+	}
+	if !ks.modTime.IsZero() && info.ModTime().Equal(ks.modTime) {
+		return nil
+	}
+	var data []byte
+	if data, err = os.ReadFile(ks.filename); err != nil {
+		return err
+	}
+	var keySet jose.JSONWebKeySet
+	if err = json.Unmarshal(data, &keySet); err != nil {
+		return errors.New(keycloak.MsgErrCannotParse + ".jwks")
+	}
+	ks.keys = keySet.Keys
+	ks.modTime = info.ModTime()
+	return nil
+}
+
+// NewStaticIssuerManager creates an IssuerManager which returns the given verifier providers. The keys of
+// verifierProviders are the base URLs of the issuers. It allows to use the verifier providers created with
+// NewStaticVerifierProvider or NewJWKSFileVerifierProvider, for instance with api.Client.SetIssuerManager.
+func NewStaticIssuerManager(verifierProviders map[string]OidcVerifierProvider) IssuerManager {
+	var domainToVerifier = make(map[string]OidcVerifierProvider)
+	for baseURL, verifierProvider := range verifierProviders {
+		domainToVerifier[getProtocolAndDomain(baseURL)] = verifierProvider
+	}
+	return &issuerManager{
+		domainToVerifier: domainToVerifier,
+	}
+}
@@ -0,0 +1,105 @@
+package toolbox
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStaticVerifierProvider(t *testing.T) {
+	var signer = newSigningKeycloak("https://public.domain.ch")
+	var keys = jose.JSONWebKeySet{Keys: []jose.JSONWebKey{{Key: &signer.key.PublicKey, KeyID: signer.keyID, Algorithm: "RS256", Use: "sig"}}}
+	var provider = NewStaticVerifierProvider(signer.externalURL, keys, VerifierConfig{
+		PerRealm: map[string]VerifierOptions{"strict-realm": {Audiences: []string{"backend"}}},
+	})
+
+	t.Run("Valid token", func(t *testing.T) {
+		var verifier, err = provider.GetOidcVerifier("my-realm")
+		assert.Nil(t, err)
+		var claims *TokenClaims
+		claims, err = verifier.VerifyAndExtract(signer.sign("my-realm", nil))
+		assert.Nil(t, err)
+		assert.Equal(t, "my-realm", claims.Realm)
+	})
+	t.Run("Token of another realm", func(t *testing.T) {
+		var verifier, _ = provider.GetOidcVerifier("my-realm")
+		assert.NotNil(t, verifier.Verify(signer.sign("other-realm", nil)))
+	})
+	t.Run("Options of the realm", func(t *testing.T) {
+		var verifier, _ = provider.GetOidcVerifier("strict-realm")
+		assert.NotNil(t, verifier.Verify(signer.sign("strict-realm", nil)))
+		assert.Nil(t, verifier.Verify(signer.sign("strict-realm", jwt.MapClaims{"aud": "backend"})))
+	})
+	t.Run("Unknown key", func(t *testing.T) {
+		var other = newSigningKeycloak(signer.externalURL)
+		var verifier, _ = provider.GetOidcVerifier("my-realm")
+		assert.NotNil(t, verifier.Verify(other.sign("my-realm", nil)))
+	})
+	t.Run("Issuer manager", func(t *testing.T) {
+		var issuerManager = NewStaticIssuerManager(map[string]OidcVerifierProvider{signer.externalURL + "/auth": provider})
+		var res, err = issuerManager.GetOidcVerifierProvider(signer.externalURL + "/auth/realms/my-realm")
+		assert.Nil(t, err)
+		assert.Equal(t, provider, res)
+		_, err = issuerManager.GetOidcVerifierProvider("https://other.domain.ch/auth/realms/my-realm")
+		assert.NotNil(t, err)
+	})
+}
+
+func TestJWKSFileVerifierProvider(t *testing.T) {
+	var signer = newSigningKeycloak("https://public.domain.ch")
+	var writeKeys = func(t *testing.T, filename string, keys ...*rsa.PrivateKey) {
+		var jwks jose.JSONWebKeySet
+		for _, key := range keys {
+			jwks.Keys = append(jwks.Keys, jose.JSONWebKey{Key: &key.PublicKey, KeyID: signer.keyID, Algorithm: "RS256", Use: "sig"})
+		}
+		var data, _ = json.Marshal(jwks)
+		assert.Nil(t, os.WriteFile(filename, data, 0600))
+	}
+	var filename = *writeTestFile(t, "jwks.json", nil)
+	writeKeys(t, filename, signer.key)
+
+	t.Run("Invalid file", func(t *testing.T) {
+		var _, err = NewJWKSFileVerifierProvider(signer.externalURL, filename+".unknown", 0, VerifierConfig{})
+		assert.NotNil(t, err)
+		_, err = NewJWKSFileVerifierProvider(signer.externalURL, *writeTestFile(t, "invalid.json", []byte("{")), 0, VerifierConfig{})
+		assert.NotNil(t, err)
+	})
+	t.Run("Reload", func(t *testing.T) {
+		var provider, err = NewJWKSFileVerifierProvider(signer.externalURL, filename, time.Nanosecond, VerifierConfig{})
+		assert.Nil(t, err)
+		var verifier, _ = provider.GetOidcVerifier("my-realm")
+		assert.Nil(t, verifier.Verify(signer.sign("my-realm", nil)))
+
+		// Key rotation
+		var oldKey = signer.key
+		signer.key, _ = rsa.GenerateKey(rand.Reader, 2048)
+		writeKeys(t, filename, signer.key)
+		var modTime = time.Now().Add(time.Minute)
+		assert.Nil(t, os.Chtimes(filename, modTime, modTime))
+		assert.Nil(t, verifier.Verify(signer.sign("my-realm", nil)))
+
+		// Invalid content: the previous keys are kept
+		assert.Nil(t, os.WriteFile(filename, []byte("{"), 0600))
+		modTime = modTime.Add(time.Minute)
+		assert.Nil(t, os.Chtimes(filename, modTime, modTime))
+		assert.Nil(t, verifier.Verify(signer.sign("my-realm", nil)))
+
+		signer.key = oldKey
+		assert.NotNil(t, verifier.Verify(signer.sign("my-realm", nil)))
+	})
+	t.Run("Without reload", func(t *testing.T) {
+		writeKeys(t, filename, signer.key)
+		var provider, _ = NewJWKSFileVerifierProvider(signer.externalURL, filename, 0, VerifierConfig{})
+		var verifier, _ = provider.GetOidcVerifier("my-realm")
+		var token = signer.sign("my-realm", nil)
+		assert.Nil(t, os.Remove(filename))
+		assert.Nil(t, verifier.Verify(token))
+	})
+}
@@ -2,13 +2,18 @@ package toolbox
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"net/http"
 	"net/url"
+	"slices"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/cloudtrust/keycloak-client/v2"
 	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/go-kit/kit/metrics"
 	"github.com/pkg/errors"
 )
 
@@ -20,64 +25,362 @@ type OidcVerifierProvider interface {
 // OidcVerifier is an interface for OIDC token verifiers
 type OidcVerifier interface {
 	Verify(accessToken string) error
+	VerifyAndExtract(accessToken string) (*TokenClaims, error)
+	// VerifyDPoP verifies a DPoP-bound access token and the DPoP proof of the request (RFC 9449)
+	VerifyDPoP(accessToken string, proof string, httpMethod string, targetURL string) (*TokenClaims, error)
+}
+
+// VerifierOptions are the checks done by OIDC verifiers in addition to the signature, issuer and expiry of the tokens.
+// Empty values disable the corresponding checks.
+type VerifierOptions struct {
+	Audiences           []string            // The token must be issued for one of these audiences
+	AuthorizedParties   []string            // The token must be requested by one of these clients (azp)
+	SigningAlgs         []string            // Defaults to RS256
+	Leeway              time.Duration       // Accepted clock skew when checking the expiry, nbf is not affected
+	TokenTypes          []string            // Accepted values of the typ claim, such as Bearer
+	RequiredScopes      []string            // All these scopes must be granted
+	RequiredRealmRoles  []string            // All these realm roles must be granted
+	RequiredClientRoles map[string][]string // All these roles must be granted for each client
+}
+
+// Default values of VerifierConfig
+const (
+	defaultVerifierCacheTTL      = time.Hour
+	defaultDiscoveryBackoff      = 5 * time.Second
+	defaultMaxDiscoveryBackoff   = 5 * time.Minute
+	defaultMinKeyRefreshInterval = 10 * time.Second
+)
+
+// notBeforeLeeway is the clock skew accepted when checking the nbf claim, as done by go-oidc
+const notBeforeLeeway = 5 * time.Minute
+
+// VerifierConfig contains the options of the verifiers of each realm and the settings of the verifiers cache. Realms
+// without specific options use Default. Zero durations are replaced by default values.
+type VerifierConfig struct {
+	Default  VerifierOptions
+	PerRealm map[string]VerifierOptions
+
+	CacheTTL              time.Duration   // Verifiers are created again with the discovery document after this delay
+	DiscoveryBackoff      time.Duration   // Delay before retrying a failed discovery, doubled after each failure
+	MaxDiscoveryBackoff   time.Duration   // Maximum delay before retrying a failed discovery
+	MinKeyRefreshInterval time.Duration   // Minimum delay between two downloads of the keys of a realm
+	KeyRefreshes          metrics.Counter // Counts the downloads of the keys, with labels realm and result
+	DiscoveryFailures     metrics.Counter // Counts the failed discoveries, with label realm
+}
+
+func (vc VerifierConfig) forRealm(realm string) VerifierOptions {
+	if options, ok := vc.PerRealm[realm]; ok {
+		return options
+	}
+	return vc.Default
+}
+
+func durationOrDefault(value time.Duration, defaultValue time.Duration) time.Duration {
+	if value > 0 {
+		return value
+	}
+	return defaultValue
 }
 
 type verifierCache struct {
 	internalURL    *url.URL
 	externalURL    *url.URL
+	config         VerifierConfig
+	client         *http.Client
 	verifiers      map[string]cachedVerifier
+	failures       map[string]discoveryFailure
+	dpopProofs     map[string]*tokenCache[bool] // Kept per realm when verifiers are created again
+	flights        map[string]*singleFlight[*cachedVerifier]
 	verifiersMutex sync.RWMutex
 }
 
 type cachedVerifier struct {
 	verifier  *oidc.IDTokenVerifier
+	options   VerifierOptions
 	createdAt time.Time
 	ctx       context.Context
+	// Identifiers of the DPoP proofs already used in the realm
+	dpopProofs *tokenCache[bool]
+}
+
+// discoveryFailure is kept to avoid calling Keycloak again before retryAt
+type discoveryFailure struct {
+	err     error
+	backoff time.Duration
+	retryAt time.Time
 }
 
 // NewVerifierCache create an instance of OIDC verifier cache
 func NewVerifierCache(internalURL *url.URL, externalURL *url.URL) OidcVerifierProvider {
+	return NewVerifierCacheWithConfig(internalURL, externalURL, VerifierConfig{})
+}
+
+// NewVerifierCacheWithConfig create an instance of OIDC verifier cache whose verifiers check the options of config
+func NewVerifierCacheWithConfig(internalURL *url.URL, externalURL *url.URL, config VerifierConfig) OidcVerifierProvider {
+	config.CacheTTL = durationOrDefault(config.CacheTTL, defaultVerifierCacheTTL)
+	config.DiscoveryBackoff = durationOrDefault(config.DiscoveryBackoff, defaultDiscoveryBackoff)
+	config.MaxDiscoveryBackoff = durationOrDefault(config.MaxDiscoveryBackoff, defaultMaxDiscoveryBackoff)
+	config.MinKeyRefreshInterval = durationOrDefault(config.MinKeyRefreshInterval, defaultMinKeyRefreshInterval)
 	return &verifierCache{
 		internalURL:    internalURL,
 		externalURL:    externalURL,
+		config:         config,
+		client:         newForwardedClient(internalURL, externalURL),
 		verifiers:      make(map[string]cachedVerifier),
+		failures:       make(map[string]discoveryFailure),
+		dpopProofs:     make(map[string]*tokenCache[bool]),
+		flights:        make(map[string]*singleFlight[*cachedVerifier]),
 		verifiersMutex: sync.RWMutex{},
 	}
 }
 
+// GetOidcVerifier returns the verifier of the realm. Verifiers are created again once they are older than the cache
+// TTL, by a single caller at a time for each realm. When Keycloak can't be reached, the expired verifier is still used
+// and the failure is kept until the backoff delay is over.
 func (vc *verifierCache) GetOidcVerifier(realm string) (OidcVerifier, error) {
+	var res, found, err = vc.cachedVerifier(realm)
+	if !found {
+		res, err = vc.flightOf(realm).do(func() (*cachedVerifier, error) {
+			// The verifier may have been created by another caller in the meantime
+			if v, found, err := vc.cachedVerifier(realm); found {
+				return v, err
+			}
+			return vc.renewVerifier(realm)
+		})
+	}
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// cachedVerifier returns the verifier of the realm, or the last discovery failure, when the verifier must not be
+// created again
+func (vc *verifierCache) cachedVerifier(realm string) (*cachedVerifier, bool, error) {
 	vc.verifiersMutex.RLock()
 	v, ok := vc.verifiers[realm]
+	failure, failed := vc.failures[realm]
 	vc.verifiersMutex.RUnlock()
-	if ok {
-		return &v, nil
+	if ok && time.Since(v.createdAt) < vc.config.CacheTTL {
+		return &v, true, nil
 	}
+	if failed && time.Now().Before(failure.retryAt) {
+		if ok {
+			return &v, true, nil
+		}
+		return nil, true, failure.err
+	}
+	return nil, false, nil
+}
 
+func (vc *verifierCache) flightOf(realm string) *singleFlight[*cachedVerifier] {
+	vc.verifiersMutex.Lock()
+	defer vc.verifiersMutex.Unlock()
+
+	var flight, ok = vc.flights[realm]
+	if !ok {
+		flight = &singleFlight[*cachedVerifier]{}
+		vc.flights[realm] = flight
+	}
+	return flight
+}
+
+func (vc *verifierCache) renewVerifier(realm string) (*cachedVerifier, error) {
+	res, err := vc.createVerifier(realm)
+	vc.verifiersMutex.Lock()
+	defer vc.verifiersMutex.Unlock()
+	v, ok := vc.verifiers[realm]
+	failure, failed := vc.failures[realm]
+	if err != nil {
+		var backoff = vc.config.DiscoveryBackoff
+		if failed {
+			backoff = min(2*failure.backoff, vc.config.MaxDiscoveryBackoff)
+		}
+		vc.failures[realm] = discoveryFailure{err: err, backoff: backoff, retryAt: time.Now().Add(backoff)}
+		if vc.config.DiscoveryFailures != nil {
+			vc.config.DiscoveryFailures.With("realm", realm).Add(1)
+		}
+		if ok {
+			return &v, nil
+		}
+		return nil, err
+	}
+	vc.verifiers[realm] = res
+	delete(vc.failures, realm)
+	return &res, nil
+}
+
+func (vc *verifierCache) createVerifier(realm string) (cachedVerifier, error) {
 	ctx := ContextWithForwarded(context.Background(), vc.internalURL, vc.externalURL)
-	var oidcProvider *oidc.Provider
+	var issuer = fmt.Sprintf("%s://%s/auth/realms/%s", vc.externalURL.Scheme, vc.externalURL.Host, realm)
+	var discovery struct {
+		JWKSURL string `json:"jwks_uri"`
+	}
 	{
-		var err error
-		var issuer = fmt.Sprintf("%s://%s/auth/realms/%s", vc.externalURL.Scheme, vc.externalURL.Host, realm)
-		oidcProvider, err = oidc.NewProvider(ctx, issuer)
+		oidcProvider, err := oidc.NewProvider(ctx, issuer)
+		if err == nil {
+			err = oidcProvider.Claims(&discovery)
+		}
 		if err != nil {
-			return nil, errors.Wrap(err, keycloak.MsgErrCannotCreate+"."+keycloak.OIDCProvider)
+			return cachedVerifier{}, errors.Wrap(err, keycloak.MsgErrCannotCreate+"."+keycloak.OIDCProvider)
 		}
 	}
 
-	ov := oidcProvider.Verifier(&oidc.Config{SkipClientIDCheck: true})
-	res := cachedVerifier{
-		createdAt: time.Now(),
-		verifier:  ov,
-		ctx:       ctx,
+	var keySet = &remoteKeySet{
+		client:             vc.client,
+		jwksURL:            discovery.JWKSURL,
+		realm:              realm,
+		minRefreshInterval: vc.config.MinKeyRefreshInterval,
+		refreshes:          vc.config.KeyRefreshes,
 	}
+	var options = vc.config.forRealm(realm)
+	return newCachedVerifier(ctx, issuer, keySet, options, vc.dpopProofsOf(realm, options)), nil
+}
+
+// dpopProofsOf returns the DPoP replay cache of the realm, which outlives its verifiers
+func (vc *verifierCache) dpopProofsOf(realm string, options VerifierOptions) *tokenCache[bool] {
 	vc.verifiersMutex.Lock()
-	vc.verifiers[realm] = res
-	vc.verifiersMutex.Unlock()
+	defer vc.verifiersMutex.Unlock()
+	var res, ok = vc.dpopProofs[realm]
+	if !ok {
+		res = newDPoPReplayCache(options)
+		vc.dpopProofs[realm] = res
+	}
+	return res
+}
 
-	return &res, nil
+// newDPoPReplayCache creates a cache keeping the used DPoP proofs as long as they could be accepted
+func newDPoPReplayCache(options VerifierOptions) *tokenCache[bool] {
+	return newTokenCache[bool](2 * (dpopProofMaxAge + options.Leeway))
+}
+
+func newCachedVerifier(ctx context.Context, issuer string, keySet oidc.KeySet, options VerifierOptions, dpopProofs *tokenCache[bool]) cachedVerifier {
+	ov := oidc.NewVerifier(issuer, keySet, &oidc.Config{
+		// Audiences and expiry are checked by VerifierOptions.check as tokens can be accepted for several audiences and
+		// the leeway only applies to the expiry
+		SkipClientIDCheck:    true,
+		SkipExpiryCheck:      true,
+		SupportedSigningAlgs: options.SigningAlgs,
+	})
+	return cachedVerifier{
+		createdAt:  time.Now(),
+		verifier:   ov,
+		options:    options,
+		ctx:        ctx,
+		dpopProofs: dpopProofs,
+	}
 }
 
 func (cv *cachedVerifier) Verify(accessToken string) error {
-	_, err := cv.verifier.Verify(cv.ctx, accessToken)
+	_, err := cv.VerifyAndExtract(accessToken)
 	return err
 }
+
+// VerifyAndExtract verifies accessToken and returns its claims. DPoP-bound tokens are rejected as they must be verified
+// with their proof by VerifyDPoP.
+func (cv *cachedVerifier) VerifyAndExtract(accessToken string) (*TokenClaims, error) {
+	claims, err := cv.verifyAndExtract(accessToken)
+	if err != nil {
+		return nil, err
+	}
+	if claims.ConfirmationThumbprint != "" {
+		return nil, invalidTokenError("cnf")
+	}
+	return claims, nil
+}
+
+func (cv *cachedVerifier) verifyAndExtract(accessToken string) (*TokenClaims, error) {
+	idToken, err := cv.verifier.Verify(cv.ctx, accessToken)
+	if err != nil {
+		return nil, err
+	}
+	var payload json.RawMessage
+	if err = idToken.Claims(&payload); err != nil {
+		return nil, err
+	}
+	claims, err := parseTokenClaims(payload)
+	if err != nil {
+		return nil, err
+	}
+	if err = cv.options.check(claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// VerifyDPoP verifies accessToken, checks that proof is a valid DPoP proof of the request, that it has not been used
+// yet and that the token is bound to its key
+func (cv *cachedVerifier) VerifyDPoP(accessToken string, proof string, httpMethod string, targetURL string) (*TokenClaims, error) {
+	claims, err := cv.verifyAndExtract(accessToken)
+	if err != nil {
+		return nil, err
+	}
+	thumbprint, jti, err := verifyDPoPProof(proof, httpMethod, targetURL, accessToken, cv.options.Leeway)
+	if err != nil {
+		return nil, err
+	}
+	if claims.ConfirmationThumbprint == "" || claims.ConfirmationThumbprint != thumbprint {
+		return nil, invalidTokenError("cnf")
+	}
+	if !cv.dpopProofs.add(thumbprint+"."+jti, true, time.Time{}) {
+		return nil, invalidTokenError("dpop.jti")
+	}
+	return claims, nil
+}
+
+// check returns an oidc.TokenExpiredError if the token is expired, taking the leeway into account, and the errors of
+// checkClaims
+func (options VerifierOptions) check(claims *TokenClaims) error {
+	var now = time.Now()
+	if now.After(claims.ExpiresAt.Add(options.Leeway)) {
+		return &oidc.TokenExpiredError{Expiry: claims.ExpiresAt}
+	}
+	if now.Add(notBeforeLeeway).Before(claims.NotBefore) {
+		return invalidTokenError("nbf")
+	}
+	return options.checkClaims(claims)
+}
+
+// checkClaims returns an Unauthorized error if the token is not intended for this verifier and a Forbidden error if it
+// does not grant the required scopes or roles
+func (options VerifierOptions) checkClaims(claims *TokenClaims) error {
+	if len(options.Audiences) > 0 && !slices.ContainsFunc(claims.Audience, func(aud string) bool {
+		return slices.Contains(options.Audiences, aud)
+	}) {
+		return invalidTokenError("audience")
+	}
+	if len(options.AuthorizedParties) > 0 && !slices.Contains(options.AuthorizedParties, claims.AuthorizedParty) {
+		return invalidTokenError("azp")
+	}
+	if len(options.TokenTypes) > 0 && !slices.ContainsFunc(options.TokenTypes, func(typ string) bool {
+		return strings.EqualFold(typ, claims.TokenType)
+	}) {
+		return invalidTokenError("typ")
+	}
+	for _, scope := range options.RequiredScopes {
+		if !claims.HasScope(scope) {
+			return forbiddenTokenError("scope." + scope)
+		}
+	}
+	for _, role := range options.RequiredRealmRoles {
+		if !claims.HasRealmRole(role) {
+			return forbiddenTokenError("role." + role)
+		}
+	}
+	for clientID, roles := range options.RequiredClientRoles {
+		for _, role := range roles {
+			if !claims.HasClientRole(clientID, role) {
+				return forbiddenTokenError("role." + clientID + "." + role)
+			}
+		}
+	}
+	return nil
+}
+
+func invalidTokenError(claim string) error {
+	return keycloak.ClientDetailedError{HTTPStatus: http.StatusUnauthorized, Message: keycloak.MsgErrInvalidToken + "." + claim}
+}
+
+func forbiddenTokenError(missing string) error {
+	return keycloak.ClientDetailedError{HTTPStatus: http.StatusForbidden, Message: keycloak.MsgErrInsufficientToken + "." + missing}
+}
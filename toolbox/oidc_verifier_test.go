@@ -2,6 +2,8 @@ package toolbox
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/rsa"
 	"encoding/base64"
 	"encoding/json"
 	"errors"
@@ -9,11 +11,19 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"slices"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/cloudtrust/keycloak-client/v2"
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/go-jose/go-jose/v4"
+	"github.com/go-kit/kit/metrics"
 	http_transport "github.com/go-kit/kit/transport/http"
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/gorilla/mux"
 	"github.com/stretchr/testify/assert"
 )
@@ -190,3 +200,357 @@ func TestGetOidcVerifier(t *testing.T) {
 		})
 	}
 }
+
+// signingKeycloak serves the OIDC discovery documents and the keys of realms whose tokens are signed with key
+type signingKeycloak struct {
+	key            *rsa.PrivateKey
+	keyID          string
+	externalURL    string
+	discoveries    atomic.Int32
+	keyDownloads   atomic.Int32
+	unavailable    atomic.Bool
+	discoveryDelay atomic.Int64 // Nanoseconds
+}
+
+func newSigningKeycloak(externalURL string) *signingKeycloak {
+	var key, _ = rsa.GenerateKey(rand.Reader, 2048)
+	return &signingKeycloak{key: key, keyID: "kid1", externalURL: externalURL}
+}
+
+func (sk *signingKeycloak) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var realm = strings.Split(strings.TrimPrefix(r.URL.Path, "/auth/realms/"), "/")[0]
+	var res any
+	switch {
+	case sk.unavailable.Load():
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	case strings.HasSuffix(r.URL.Path, "/.well-known/openid-configuration"):
+		sk.discoveries.Add(1)
+		time.Sleep(time.Duration(sk.discoveryDelay.Load()))
+		res = createProtocolOIDC(sk.externalURL, realm)
+	case strings.HasSuffix(r.URL.Path, "/certs"):
+		sk.keyDownloads.Add(1)
+		res = jose.JSONWebKeySet{Keys: []jose.JSONWebKey{{Key: &sk.key.PublicKey, KeyID: sk.keyID, Algorithm: "RS256", Use: "sig"}}}
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(res)
+}
+
+// sign creates a token of realm valid for one hour. claims are added to the default ones.
+func (sk *signingKeycloak) sign(realm string, claims jwt.MapClaims) string {
+	var tokenClaims = jwt.MapClaims{
+		"iss": sk.externalURL + "/auth/realms/" + realm,
+		"sub": "1234",
+		"iat": time.Now().Unix(),
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}
+	for name, value := range claims {
+		tokenClaims[name] = value
+	}
+	var token = jwt.NewWithClaims(jwt.SigningMethodRS256, tokenClaims)
+	token.Header["kid"] = sk.keyID
+	var res, _ = token.SignedString(sk.key)
+	return res
+}
+
+func TestVerifyAndExtract(t *testing.T) {
+	var keycloakServer = newSigningKeycloak("https://public.domain.ch")
+	var ts = httptest.NewServer(keycloakServer)
+	defer ts.Close()
+
+	var internalURL, _ = url.Parse(ts.URL)
+	var externalURL, _ = url.Parse(keycloakServer.externalURL)
+	var verifier, err = NewVerifierCache(internalURL, externalURL).GetOidcVerifier("my-realm")
+	assert.Nil(t, err)
+
+	t.Run("Valid token", func(t *testing.T) {
+		var token = keycloakServer.sign("my-realm", jwt.MapClaims{
+			"azp":          "my-app",
+			"realm_access": map[string]any{"roles": []string{"admin"}},
+			"scope":        "openid email",
+		})
+		assert.Nil(t, verifier.Verify(token))
+		var claims, err = verifier.VerifyAndExtract(token)
+		assert.Nil(t, err)
+		assert.Equal(t, "1234", claims.Subject)
+		assert.Equal(t, "my-realm", claims.Realm)
+		assert.Equal(t, "my-app", claims.AuthorizedParty)
+		assert.True(t, claims.HasRealmRole("admin"))
+		assert.True(t, claims.HasScope("email"))
+	})
+	t.Run("Token of another realm", func(t *testing.T) {
+		var _, err = verifier.VerifyAndExtract(keycloakServer.sign("other-realm", nil))
+		assert.NotNil(t, err)
+	})
+	t.Run("Invalid signature", func(t *testing.T) {
+		var _, err = verifier.VerifyAndExtract(generateJWT(keycloakServer.externalURL + "/auth/realms/my-realm"))
+		assert.NotNil(t, err)
+	})
+}
+
+func TestVerifierOptions(t *testing.T) {
+	var keycloakServer = newSigningKeycloak("https://public.domain.ch")
+	var ts = httptest.NewServer(keycloakServer)
+	defer ts.Close()
+
+	var internalURL, _ = url.Parse(ts.URL)
+	var externalURL, _ = url.Parse(keycloakServer.externalURL)
+	var cache = NewVerifierCacheWithConfig(internalURL, externalURL, VerifierConfig{
+		Default: VerifierOptions{Audiences: []string{"backend"}},
+		PerRealm: map[string]VerifierOptions{
+			"strict-realm": {
+				Audiences:           []string{"backend", "other"},
+				AuthorizedParties:   []string{"frontend"},
+				SigningAlgs:         []string{"ES256"},
+				TokenTypes:          []string{"Bearer"},
+				RequiredScopes:      []string{"read"},
+				RequiredRealmRoles:  []string{"user"},
+				RequiredClientRoles: map[string][]string{"backend": {"reader"}},
+			},
+			"leeway-realm": {Leeway: time.Minute},
+		},
+	})
+
+	t.Run("Default options", func(t *testing.T) {
+		var verifier, err = cache.GetOidcVerifier("my-realm")
+		assert.Nil(t, err)
+		assert.Nil(t, verifier.Verify(keycloakServer.sign("my-realm", jwt.MapClaims{"aud": []string{"account", "backend"}})))
+
+		err = verifier.Verify(keycloakServer.sign("my-realm", jwt.MapClaims{"aud": "account"}))
+		assert.Equal(t, keycloak.ClientDetailedError{HTTPStatus: http.StatusUnauthorized, Message: "invalidToken.audience"}, err)
+	})
+	t.Run("Signing algorithm not allowed", func(t *testing.T) {
+		var verifier, err = cache.GetOidcVerifier("strict-realm")
+		assert.Nil(t, err)
+		err = verifier.Verify(keycloakServer.sign("strict-realm", jwt.MapClaims{"aud": "backend"}))
+		assert.NotNil(t, err)
+		assert.Contains(t, err.Error(), "RS256")
+	})
+	t.Run("Leeway", func(t *testing.T) {
+		var verifier, err = cache.GetOidcVerifier("leeway-realm")
+		assert.Nil(t, err)
+		var expiredSince = func(d time.Duration) string {
+			return keycloakServer.sign("leeway-realm", jwt.MapClaims{"exp": time.Now().Add(-d).Unix()})
+		}
+		assert.Nil(t, verifier.Verify(expiredSince(10*time.Second)))
+		assert.NotNil(t, verifier.Verify(expiredSince(2*time.Minute)))
+		// The leeway only applies to the expiry
+		assert.Nil(t, verifier.Verify(keycloakServer.sign("leeway-realm", jwt.MapClaims{"nbf": time.Now().Add(270 * time.Second).Unix()})))
+	})
+}
+
+func TestCheckVerifierOptions(t *testing.T) {
+	var options = VerifierOptions{
+		AuthorizedParties:   []string{"frontend"},
+		TokenTypes:          []string{"Bearer"},
+		RequiredScopes:      []string{"read"},
+		RequiredRealmRoles:  []string{"user"},
+		RequiredClientRoles: map[string][]string{"backend": {"reader"}},
+	}
+	var validClaims = func() *TokenClaims {
+		return &TokenClaims{
+			AuthorizedParty: "frontend",
+			TokenType:       "bearer",
+			Scope:           "openid read",
+			RealmRoles:      []string{"user"},
+			ClientRoles:     map[string][]string{"backend": {"reader", "writer"}},
+		}
+	}
+	var statusOf = func(err error) int {
+		if detailedErr, ok := err.(keycloak.ClientDetailedError); ok {
+			return detailedErr.Status()
+		}
+		return 0
+	}
+
+	assert.Nil(t, options.checkClaims(validClaims()))
+	assert.Nil(t, VerifierOptions{}.checkClaims(&TokenClaims{}))
+
+	var claims = validClaims()
+	claims.AuthorizedParty = "other"
+	assert.Equal(t, http.StatusUnauthorized, statusOf(options.checkClaims(claims)))
+
+	claims = validClaims()
+	claims.TokenType = "Refresh"
+	assert.Equal(t, http.StatusUnauthorized, statusOf(options.checkClaims(claims)))
+
+	claims = validClaims()
+	claims.Scope = "openid"
+	assert.Equal(t, http.StatusForbidden, statusOf(options.checkClaims(claims)))
+
+	claims = validClaims()
+	claims.RealmRoles = nil
+	assert.Equal(t, http.StatusForbidden, statusOf(options.checkClaims(claims)))
+
+	claims = validClaims()
+	claims.ClientRoles["backend"] = []string{"writer"}
+	var err = options.checkClaims(claims)
+	assert.Equal(t, http.StatusForbidden, statusOf(err))
+	assert.Contains(t, err.Error(), "role.backend.reader")
+}
+
+func TestCheckVerifierOptionsExpiry(t *testing.T) {
+	var options = VerifierOptions{Leeway: time.Minute}
+	var expiredErr *oidc.TokenExpiredError
+
+	assert.Nil(t, options.check(&TokenClaims{ExpiresAt: time.Now().Add(-10 * time.Second)}))
+	assert.ErrorAs(t, options.check(&TokenClaims{ExpiresAt: time.Now().Add(-2 * time.Minute)}), &expiredErr)
+	// Tokens without expiry are rejected
+	assert.ErrorAs(t, options.check(&TokenClaims{}), &expiredErr)
+
+	// The leeway does not make the nbf check stricter
+	var claims = &TokenClaims{ExpiresAt: time.Now().Add(time.Hour), NotBefore: time.Now().Add(4 * time.Minute)}
+	assert.Nil(t, options.check(claims))
+	claims.NotBefore = time.Now().Add(10 * time.Minute)
+	assert.NotNil(t, options.check(claims))
+}
+
+// labelCounter is a metrics.Counter which records the values per set of labels
+type labelCounter struct {
+	values map[string]float64
+	labels []string
+}
+
+func (lc *labelCounter) With(labelValues ...string) metrics.Counter {
+	return &labelCounter{values: lc.values, labels: append(slices.Clone(lc.labels), labelValues...)}
+}
+
+func (lc *labelCounter) Add(delta float64) {
+	lc.values[strings.Join(lc.labels, ",")] += delta
+}
+
+func TestKeyRotation(t *testing.T) {
+	var keycloakServer = newSigningKeycloak("https://public.domain.ch")
+	var ts = httptest.NewServer(keycloakServer)
+	defer ts.Close()
+
+	var internalURL, _ = url.Parse(ts.URL)
+	var externalURL, _ = url.Parse(keycloakServer.externalURL)
+	var refreshes = &labelCounter{values: map[string]float64{}}
+	var verifier, err = NewVerifierCacheWithConfig(internalURL, externalURL, VerifierConfig{
+		MinKeyRefreshInterval: time.Hour,
+		KeyRefreshes:          refreshes,
+	}).GetOidcVerifier("my-realm")
+	assert.Nil(t, err)
+
+	t.Run("Keys are downloaded once", func(t *testing.T) {
+		for range 3 {
+			assert.Nil(t, verifier.Verify(keycloakServer.sign("my-realm", nil)))
+		}
+		assert.Equal(t, int32(1), keycloakServer.keyDownloads.Load())
+	})
+	t.Run("Unknown key ID after a rotation", func(t *testing.T) {
+		var oldToken = keycloakServer.sign("my-realm", nil)
+		keycloakServer.key, _ = rsa.GenerateKey(rand.Reader, 2048)
+		keycloakServer.keyID = "kid2"
+		var verifier, _ = NewVerifierCacheWithConfig(internalURL, externalURL, VerifierConfig{KeyRefreshes: refreshes}).GetOidcVerifier("my-realm")
+		keycloakServer.keyDownloads.Store(0)
+		assert.Nil(t, verifier.Verify(keycloakServer.sign("my-realm", nil)))
+		assert.NotNil(t, verifier.Verify(oldToken))
+		assert.Equal(t, int32(1), keycloakServer.keyDownloads.Load())
+	})
+	t.Run("Refreshes are limited", func(t *testing.T) {
+		keycloakServer.keyDownloads.Store(0)
+		// The first verifier still knows the old key only and already downloaded the keys less than an hour ago
+		var err = verifier.Verify(keycloakServer.sign("my-realm", nil))
+		assert.NotNil(t, err)
+		assert.Contains(t, err.Error(), "unknown key ID kid2")
+		assert.Equal(t, int32(0), keycloakServer.keyDownloads.Load())
+	})
+	t.Run("Download fails", func(t *testing.T) {
+		var verifier, _ = NewVerifierCacheWithConfig(internalURL, externalURL, VerifierConfig{KeyRefreshes: refreshes}).GetOidcVerifier("my-realm")
+		keycloakServer.unavailable.Store(true)
+		defer keycloakServer.unavailable.Store(false)
+		assert.NotNil(t, verifier.Verify(keycloakServer.sign("my-realm", nil)))
+	})
+	assert.Equal(t, map[string]float64{"realm,my-realm,result,success": 2, "realm,my-realm,result,failure": 1}, refreshes.values)
+}
+
+func TestVerifierCacheExpiry(t *testing.T) {
+	var keycloakServer = newSigningKeycloak("https://public.domain.ch")
+	var ts = httptest.NewServer(keycloakServer)
+	defer ts.Close()
+
+	var internalURL, _ = url.Parse(ts.URL)
+	var externalURL, _ = url.Parse(keycloakServer.externalURL)
+	var failures = &labelCounter{values: map[string]float64{}}
+
+	t.Run("Discovery failures are cached", func(t *testing.T) {
+		var cache = NewVerifierCacheWithConfig(internalURL, externalURL, VerifierConfig{
+			DiscoveryBackoff:  time.Hour,
+			DiscoveryFailures: failures,
+		})
+		keycloakServer.unavailable.Store(true)
+		var _, err1 = cache.GetOidcVerifier("my-realm")
+		assert.NotNil(t, err1)
+
+		// Keycloak is not called again before the end of the backoff delay
+		keycloakServer.unavailable.Store(false)
+		var _, err2 = cache.GetOidcVerifier("my-realm")
+		assert.Equal(t, err1, err2)
+		assert.Equal(t, int32(0), keycloakServer.discoveries.Load())
+		assert.Equal(t, map[string]float64{"realm,my-realm": 1}, failures.values)
+	})
+	t.Run("Backoff", func(t *testing.T) {
+		var cache = NewVerifierCacheWithConfig(internalURL, externalURL, VerifierConfig{
+			DiscoveryBackoff:    time.Nanosecond,
+			MaxDiscoveryBackoff: time.Minute,
+		}).(*verifierCache)
+		keycloakServer.unavailable.Store(true)
+		defer keycloakServer.unavailable.Store(false)
+		_, _ = cache.GetOidcVerifier("my-realm")
+		time.Sleep(time.Millisecond)
+		_, _ = cache.GetOidcVerifier("my-realm")
+		assert.Equal(t, 2*time.Nanosecond, cache.failures["my-realm"].backoff)
+
+		cache.failures["my-realm"] = discoveryFailure{backoff: time.Minute}
+		_, _ = cache.GetOidcVerifier("my-realm")
+		assert.Equal(t, time.Minute, cache.failures["my-realm"].backoff)
+	})
+	t.Run("Verifiers expire", func(t *testing.T) {
+		keycloakServer.discoveries.Store(0)
+		var cache = NewVerifierCacheWithConfig(internalURL, externalURL, VerifierConfig{CacheTTL: time.Nanosecond})
+		var _, err = cache.GetOidcVerifier("my-realm")
+		assert.Nil(t, err)
+		time.Sleep(time.Millisecond)
+		_, err = cache.GetOidcVerifier("my-realm")
+		assert.Nil(t, err)
+		assert.Equal(t, int32(2), keycloakServer.discoveries.Load())
+
+		// The expired verifier is still used while Keycloak is not available
+		keycloakServer.unavailable.Store(true)
+		defer keycloakServer.unavailable.Store(false)
+		time.Sleep(time.Millisecond)
+		var verifier OidcVerifier
+		verifier, err = cache.GetOidcVerifier("my-realm")
+		assert.Nil(t, err)
+		assert.NotNil(t, verifier)
+	})
+	t.Run("Verifiers are created once for concurrent callers", func(t *testing.T) {
+		keycloakServer.discoveries.Store(0)
+		keycloakServer.discoveryDelay.Store(int64(20 * time.Millisecond))
+		defer keycloakServer.discoveryDelay.Store(0)
+		var cache = NewVerifierCacheWithConfig(internalURL, externalURL, VerifierConfig{CacheTTL: 50 * time.Millisecond})
+		var getVerifiers = func() {
+			var wg sync.WaitGroup
+			for range 10 {
+				wg.Go(func() {
+					var verifier, err = cache.GetOidcVerifier("my-realm")
+					assert.Nil(t, err)
+					assert.NotNil(t, verifier)
+				})
+			}
+			wg.Wait()
+		}
+		getVerifiers()
+		assert.Equal(t, int32(1), keycloakServer.discoveries.Load())
+
+		// Expired verifiers are created again once as well
+		time.Sleep(60 * time.Millisecond)
+		getVerifiers()
+		assert.Equal(t, int32(2), keycloakServer.discoveries.Load())
+	})
+}
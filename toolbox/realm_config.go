@@ -0,0 +1,91 @@
+package toolbox
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"strings"
+
+	"github.com/cloudtrust/keycloak-client/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// RealmConfig is the desired state of a realm. Only the sections which are set are reconciled: for instance, roles are
+// left untouched when Roles is nil, even in prune mode.
+type RealmConfig struct {
+	Realm             *keycloak.RealmRepresentation             `json:"realm,omitempty"`
+	Roles             []keycloak.RoleRepresentation             `json:"roles,omitempty"`
+	ClientRoles       map[string][]keycloak.RoleRepresentation  `json:"clientRoles,omitempty"` // Roles by clientId
+	Groups            []keycloak.GroupRepresentation            `json:"groups,omitempty"`      // Sub-groups can be nested or given with their full path
+	Clients           []keycloak.ClientRepresentation           `json:"clients,omitempty"`
+	IdentityProviders []keycloak.IdentityProviderRepresentation `json:"identityProviders,omitempty"`
+	Components        []keycloak.ComponentRepresentation        `json:"components,omitempty"`
+	RequiredActions   []RequiredActionSpec                      `json:"requiredActions,omitempty"`
+	Flows             []FlowSpec                                `json:"flows,omitempty"`
+}
+
+// LoadRealmConfig parses a realm configuration written in YAML or JSON. Field names are the ones of the Keycloak
+// JSON representations.
+func LoadRealmConfig(data []byte) (RealmConfig, error) {
+	var config RealmConfig
+	var content any
+	if err := yaml.Unmarshal(data, &content); err != nil {
+		return config, errors.New(keycloak.MsgErrCannotParse + ".realmConfig: " + err.Error())
+	}
+	// YAML is converted to JSON to benefit from the JSON tags of the Keycloak representations
+	var jsonContent, err = json.Marshal(content)
+	if err != nil {
+		return config, errors.New(keycloak.MsgErrCannotMarshal + ".realmConfig: " + err.Error())
+	}
+	var decoder = json.NewDecoder(strings.NewReader(string(jsonContent)))
+	decoder.DisallowUnknownFields()
+	if err = decoder.Decode(&config); err != nil {
+		return config, errors.New(keycloak.MsgErrCannotUnmarshal + ".realmConfig: " + err.Error())
+	}
+	return config, nil
+}
+
+// LoadRealmConfigFile reads and parses a realm configuration file
+func LoadRealmConfigFile(filename string) (RealmConfig, error) {
+	var data, err = os.ReadFile(filename)
+	if err != nil {
+		return RealmConfig{}, err
+	}
+	return LoadRealmConfig(data)
+}
+
+// flattenGroups returns the groups and their nested sub-groups indexed by path, parents first
+func flattenGroups(groups []keycloak.GroupRepresentation, parentPath string) ([]string, map[string]keycloak.GroupRepresentation) {
+	var paths []string
+	var res = map[string]keycloak.GroupRepresentation{}
+	for _, group := range groups {
+		var path string
+		switch {
+		case group.Path != nil && *group.Path != "":
+			path = "/" + strings.Trim(*group.Path, "/")
+		case group.Name != nil:
+			path = parentPath + "/" + *group.Name
+		default:
+			continue
+		}
+		var name = path[strings.LastIndex(path, "/")+1:]
+		var flat = group
+		flat.Name = &name
+		flat.Path = &path
+		flat.SubGroups = nil
+		if _, ok := res[path]; !ok {
+			paths = append(paths, path)
+		}
+		res[path] = flat
+		if group.SubGroups != nil {
+			var subPaths, subGroups = flattenGroups(*group.SubGroups, path)
+			for _, subPath := range subPaths {
+				if _, ok := res[subPath]; !ok {
+					paths = append(paths, subPath)
+				}
+				res[subPath] = subGroups[subPath]
+			}
+		}
+	}
+	return paths, res
+}
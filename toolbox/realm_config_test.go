@@ -0,0 +1,86 @@
+package toolbox
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cloudtrust/keycloak-client/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+const realmConfigYAML = `
+realm:
+  displayName: My realm
+  bruteForceProtected: true
+roles:
+  - name: reader
+    description: Read only access
+clientRoles:
+  my-client:
+    - name: admin
+groups:
+  - name: parent
+    subGroups:
+      - name: child
+  - path: /parent/other
+clients:
+  - clientId: my-client
+    enabled: true
+requiredActions:
+  - alias: VERIFY_EMAIL
+    enabled: true
+flows:
+  - alias: my-browser
+    executions:
+      - authenticator: auth-cookie
+        requirement: ALTERNATIVE
+`
+
+func TestLoadRealmConfig(t *testing.T) {
+	t.Run("YAML", func(t *testing.T) {
+		var config, err = LoadRealmConfig([]byte(realmConfigYAML))
+		assert.Nil(t, err)
+		assert.Equal(t, "My realm", *config.Realm.DisplayName)
+		assert.True(t, *config.Realm.BruteForceProtected)
+		assert.Equal(t, "Read only access", *config.Roles[0].Description)
+		assert.Equal(t, "admin", *config.ClientRoles["my-client"][0].Name)
+		assert.Len(t, *config.Groups[0].SubGroups, 1)
+		assert.Equal(t, "my-client", *config.Clients[0].ClientID)
+		assert.Equal(t, []RequiredActionSpec{{Alias: "VERIFY_EMAIL", Enabled: true}}, config.RequiredActions)
+		assert.Equal(t, "auth-cookie", config.Flows[0].Executions[0].Authenticator)
+		assert.Nil(t, config.IdentityProviders)
+	})
+	t.Run("JSON", func(t *testing.T) {
+		var config, err = LoadRealmConfig([]byte(`{"roles": [{"name": "reader"}], "identityProviders": []}`))
+		assert.Nil(t, err)
+		assert.Len(t, config.Roles, 1)
+		assert.NotNil(t, config.IdentityProviders)
+	})
+	t.Run("Invalid YAML", func(t *testing.T) {
+		var _, err = LoadRealmConfig([]byte("roles: [name: reader"))
+		assert.NotNil(t, err)
+	})
+	t.Run("Unknown field", func(t *testing.T) {
+		var _, err = LoadRealmConfig([]byte("unknown: value"))
+		assert.NotNil(t, err)
+	})
+	t.Run("File", func(t *testing.T) {
+		var filename = filepath.Join(t.TempDir(), "realm.yaml")
+		assert.Nil(t, os.WriteFile(filename, []byte(realmConfigYAML), 0600))
+		var config, err = LoadRealmConfigFile(filename)
+		assert.Nil(t, err)
+		assert.Len(t, config.Roles, 1)
+
+		_, err = LoadRealmConfigFile(filepath.Join(t.TempDir(), "missing.yaml"))
+		assert.NotNil(t, err)
+	})
+}
+
+func TestFlattenGroups(t *testing.T) {
+	var config, _ = LoadRealmConfig([]byte(realmConfigYAML))
+	var paths, groups = flattenGroups(append(config.Groups, keycloak.GroupRepresentation{}), "")
+	assert.Equal(t, []string{"/parent", "/parent/child", "/parent/other"}, paths)
+	assert.Equal(t, "other", *groups["/parent/other"].Name)
+	assert.Nil(t, groups["/parent"].SubGroups)
+}
@@ -0,0 +1,212 @@
+package toolbox
+
+import (
+	"encoding/json"
+	"errors"
+	"maps"
+	"reflect"
+	"slices"
+
+	"github.com/cloudtrust/keycloak-client/v2"
+)
+
+// realmSettings is the name of the realm resource in a snapshot: realms are compared whatever their names
+const realmSettings = "settings"
+
+var (
+	// Fields which depend on the Keycloak instance, on the caller or which are secret. They are ignored at any depth.
+	realmDiffIgnoredFields = []string{"id", "internalId", "containerId", "parentId", "flowId", "createdTimestamp", "access", "secret", "registrationAccessToken"}
+	realmDiffKinds         = []string{RealmKindRealm, RealmKindRole, RealmKindClient, RealmKindGroup, RealmKindIdp, RealmKindComponent, RealmKindFlow}
+)
+
+// RealmDiffClient is the part of the Keycloak client used to compare realms
+type RealmDiffClient interface {
+	GetRealm(accessToken string, realmName string) (keycloak.RealmRepresentation, error)
+	GetRoles(accessToken string, realmName string) ([]keycloak.RoleRepresentation, error)
+	GetClients(accessToken string, realmName string, paramKV ...string) ([]keycloak.ClientRepresentation, error)
+	GetGroups(accessToken string, realmName string) ([]keycloak.GroupRepresentation, error)
+	GetIdps(accessToken string, realmName string) ([]keycloak.IdentityProviderRepresentation, error)
+	GetComponents(accessToken string, realmName string, paramKV ...string) ([]keycloak.ComponentRepresentation, error)
+	GetAuthenticationFlows(accessToken string, realmName string) ([]keycloak.AuthenticationFlowRepresentation, error)
+}
+
+// RealmSnapshot is the configuration of a realm, indexed by kind and by name. Resources are stored as JSON fields
+// from which identifiers, timestamps and secrets are removed.
+type RealmSnapshot map[string]map[string]map[string]any
+
+// TakeRealmSnapshot fetches the realm settings, roles, clients, groups, identity providers, components and
+// authentication flows of a realm
+func TakeRealmSnapshot(client RealmDiffClient, accessToken string, realmName string) (RealmSnapshot, error) {
+	var snapshot = RealmSnapshot{}
+	for _, kind := range realmDiffKinds {
+		snapshot[kind] = map[string]map[string]any{}
+	}
+
+	var realm, err = client.GetRealm(accessToken, realmName)
+	if err != nil {
+		return nil, err
+	}
+	var realmID = stringOrEmpty(realm.ID)
+	realm.Realm = nil
+	if err = snapshot.add(RealmKindRealm, realmSettings, realm); err != nil {
+		return nil, err
+	}
+
+	var roles []keycloak.RoleRepresentation
+	if roles, err = client.GetRoles(accessToken, realmName); err != nil {
+		return nil, err
+	}
+	for _, role := range roles {
+		if err = snapshot.add(RealmKindRole, roleName(role), role); err != nil {
+			return nil, err
+		}
+	}
+
+	var clients []keycloak.ClientRepresentation
+	if clients, err = client.GetClients(accessToken, realmName); err != nil {
+		return nil, err
+	}
+	for _, client := range clients {
+		if err = snapshot.add(RealmKindClient, clientID(client), client); err != nil {
+			return nil, err
+		}
+	}
+
+	var groups []keycloak.GroupRepresentation
+	if groups, err = client.GetGroups(accessToken, realmName); err != nil {
+		return nil, err
+	}
+	var _, flatGroups = flattenGroups(groups, "")
+	for path, group := range flatGroups {
+		if err = snapshot.add(RealmKindGroup, path, group); err != nil {
+			return nil, err
+		}
+	}
+
+	var idps []keycloak.IdentityProviderRepresentation
+	if idps, err = client.GetIdps(accessToken, realmName); err != nil {
+		return nil, err
+	}
+	for _, idp := range idps {
+		if err = snapshot.add(RealmKindIdp, stringOrEmpty(idp.Alias), idp); err != nil {
+			return nil, err
+		}
+	}
+
+	var components []keycloak.ComponentRepresentation
+	if components, err = client.GetComponents(accessToken, realmName); err != nil {
+		return nil, err
+	}
+	var componentNames = map[string]string{}
+	for _, component := range components {
+		componentNames[stringOrEmpty(component.ID)] = stringOrEmpty(component.Name)
+	}
+	for _, component := range components {
+		// Parents are identified by name as their identifiers differ between instances
+		var name = stringOrEmpty(component.ProviderType) + "/" + stringOrEmpty(component.Name)
+		if parentID := stringOrEmpty(component.ParentID); parentID != realmID {
+			name = stringOrEmpty(component.ProviderType) + "/" + componentNames[parentID] + "/" + stringOrEmpty(component.Name)
+		}
+		if err = snapshot.add(RealmKindComponent, name, component); err != nil {
+			return nil, err
+		}
+	}
+
+	var flows []keycloak.AuthenticationFlowRepresentation
+	if flows, err = client.GetAuthenticationFlows(accessToken, realmName); err != nil {
+		return nil, err
+	}
+	for _, flow := range flows {
+		if err = snapshot.add(RealmKindFlow, stringOrEmpty(flow.Alias), flow); err != nil {
+			return nil, err
+		}
+	}
+	return snapshot, nil
+}
+
+func (rs RealmSnapshot) add(kind string, name string, resource any) error {
+	var fields map[string]any
+	if err := toJSONFields(resource, &fields); err != nil {
+		return errors.New(keycloak.MsgErrCannotMarshal + "." + kind)
+	}
+	removeIgnoredFields(fields)
+	rs[kind][name] = fields
+	return nil
+}
+
+func removeIgnoredFields(value any) {
+	switch v := value.(type) {
+	case map[string]any:
+		for _, field := range realmDiffIgnoredFields {
+			delete(v, field)
+		}
+		for _, child := range v {
+			removeIgnoredFields(child)
+		}
+	case []any:
+		for _, child := range v {
+			removeIgnoredFields(child)
+		}
+	}
+}
+
+// DiffRealmSnapshots returns the changes which would align the target realm on the source one: resources which only
+// exist in the source are created, resources which only exist in the target are deleted. The Current value of a field
+// is the one of the target, the Desired value is the one of the source.
+func DiffRealmSnapshots(source RealmSnapshot, target RealmSnapshot) []RealmChange {
+	var res []RealmChange
+	for _, kind := range realmDiffKinds {
+		var names = slices.Collect(maps.Keys(source[kind]))
+		for name := range target[kind] {
+			if _, ok := source[kind][name]; !ok {
+				names = append(names, name)
+			}
+		}
+		slices.Sort(names)
+		for _, name := range names {
+			var sourceFields, inSource = source[kind][name]
+			var targetFields, inTarget = target[kind][name]
+			switch {
+			case !inTarget:
+				res = append(res, RealmChange{Action: RealmChangeCreate, Kind: kind, Name: name})
+			case !inSource:
+				res = append(res, RealmChange{Action: RealmChangeDelete, Kind: kind, Name: name})
+			default:
+				if diffs := diffFields(targetFields, sourceFields); len(diffs) > 0 {
+					res = append(res, RealmChange{Action: RealmChangeUpdate, Kind: kind, Name: name, Fields: diffs})
+				}
+			}
+		}
+	}
+	return res
+}
+
+func diffFields(current map[string]any, desired map[string]any) []FieldDiff {
+	var fields = slices.Collect(maps.Keys(desired))
+	for field := range current {
+		if _, ok := desired[field]; !ok {
+			fields = append(fields, field)
+		}
+	}
+	slices.Sort(fields)
+
+	var res []FieldDiff
+	for _, field := range fields {
+		if !reflect.DeepEqual(current[field], desired[field]) {
+			res = append(res, FieldDiff{Field: field, Current: current[field], Desired: desired[field]})
+		}
+	}
+	return res
+}
+
+// FormatRealmChangesJSON returns the changes as a JSON array
+func FormatRealmChangesJSON(changes []RealmChange) ([]byte, error) {
+	if changes == nil {
+		changes = []RealmChange{}
+	}
+	var res, err = json.MarshalIndent(changes, "", "  ")
+	if err != nil {
+		return nil, errors.New(keycloak.MsgErrCannotMarshal + ".realmChanges")
+	}
+	return res, nil
+}
@@ -0,0 +1,114 @@
+package toolbox
+
+import (
+	"testing"
+
+	"github.com/cloudtrust/keycloak-client/v2"
+	"github.com/cloudtrust/keycloak-client/v2/toolbox/mock"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+func expectRealmSnapshot(mockClient *mock.RealmConfigClient, realmName string, suffix string, displayName string, roles []keycloak.RoleRepresentation) {
+	var realmID = "realm-" + suffix
+	mockClient.EXPECT().GetRealm(token, realmName).Return(keycloak.RealmRepresentation{ID: &realmID, Realm: &realmName, DisplayName: &displayName}, nil)
+	mockClient.EXPECT().GetRoles(token, realmName).Return(roles, nil)
+	mockClient.EXPECT().GetClients(token, realmName).Return([]keycloak.ClientRepresentation{
+		{ID: ptr("client-" + suffix), ClientID: ptr("my-client"), Secret: ptr("secret-" + suffix), Enabled: boolPtr(true)},
+	}, nil)
+	mockClient.EXPECT().GetGroups(token, realmName).Return([]keycloak.GroupRepresentation{
+		{ID: ptr("parent-" + suffix), Name: ptr("parent"), Path: ptr("/parent"), SubGroups: &[]keycloak.GroupRepresentation{
+			{ID: ptr("child-" + suffix), Name: ptr("child"), Path: ptr("/parent/child")},
+		}},
+	}, nil)
+	mockClient.EXPECT().GetIdps(token, realmName).Return(nil, nil)
+	mockClient.EXPECT().GetComponents(token, realmName).Return([]keycloak.ComponentRepresentation{
+		{ID: ptr("ldap-" + suffix), Name: ptr("ldap"), ProviderType: ptr("storage"), ParentID: &realmID},
+		{ID: ptr("mapper-" + suffix), Name: ptr("email"), ProviderType: ptr("mapper"), ParentID: ptr("ldap-" + suffix)},
+	}, nil)
+	mockClient.EXPECT().GetAuthenticationFlows(token, realmName).Return([]keycloak.AuthenticationFlowRepresentation{
+		{ID: ptr("browser-" + suffix), Alias: ptr("browser"), BuiltIn: boolPtr(true)},
+	}, nil)
+}
+
+func TestTakeRealmSnapshot(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	var mockClient = mock.NewRealmConfigClient(mockCtrl)
+
+	t.Run("Success", func(t *testing.T) {
+		expectRealmSnapshot(mockClient, realm, "a", "My realm", []keycloak.RoleRepresentation{{ID: ptr("role-a"), Name: ptr("reader"), ContainerID: ptr("realm-a")}})
+		var snapshot, err = TakeRealmSnapshot(mockClient, token, realm)
+		assert.Nil(t, err)
+		assert.Equal(t, map[string]any{"displayName": "My realm"}, snapshot[RealmKindRealm][realmSettings])
+		assert.Equal(t, map[string]any{"name": "reader"}, snapshot[RealmKindRole]["reader"])
+		assert.Equal(t, map[string]any{"clientId": "my-client", "enabled": true}, snapshot[RealmKindClient]["my-client"])
+		assert.Equal(t, map[string]any{"name": "child", "path": "/parent/child"}, snapshot[RealmKindGroup]["/parent/child"])
+		assert.Contains(t, snapshot[RealmKindComponent], "storage/ldap")
+		assert.Contains(t, snapshot[RealmKindComponent], "mapper/ldap/email")
+		assert.Contains(t, snapshot[RealmKindFlow], "browser")
+		assert.Empty(t, snapshot[RealmKindIdp])
+	})
+	t.Run("Can't get roles", func(t *testing.T) {
+		mockClient.EXPECT().GetRealm(token, realm).Return(keycloak.RealmRepresentation{}, nil)
+		mockClient.EXPECT().GetRoles(token, realm).Return(nil, errAny)
+		var _, err = TakeRealmSnapshot(mockClient, token, realm)
+		assert.Equal(t, errAny, err)
+	})
+}
+
+func TestDiffRealmSnapshots(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	var mockClient = mock.NewRealmConfigClient(mockCtrl)
+	var diffRealms = func() ([]RealmChange, error) {
+		var source, err = TakeRealmSnapshot(mockClient, token, "staging")
+		if err != nil {
+			return nil, err
+		}
+		var target RealmSnapshot
+		if target, err = TakeRealmSnapshot(mockClient, token, "production"); err != nil {
+			return nil, err
+		}
+		return DiffRealmSnapshots(source, target), nil
+	}
+
+	t.Run("Identical realms", func(t *testing.T) {
+		var roles = []keycloak.RoleRepresentation{{ID: ptr("role"), Name: ptr("reader")}}
+		expectRealmSnapshot(mockClient, "staging", "a", "My realm", roles)
+		expectRealmSnapshot(mockClient, "production", "b", "My realm", roles)
+		var changes, err = diffRealms()
+		assert.Nil(t, err)
+		assert.Empty(t, changes)
+	})
+	t.Run("Drift", func(t *testing.T) {
+		expectRealmSnapshot(mockClient, "staging", "a", "New", []keycloak.RoleRepresentation{{Name: ptr("reader"), Description: ptr("Read only")}, {Name: ptr("writer")}})
+		expectRealmSnapshot(mockClient, "production", "b", "Old", []keycloak.RoleRepresentation{{Name: ptr("reader")}, {Name: ptr("legacy")}})
+		var changes, err = diffRealms()
+		assert.Nil(t, err)
+		assert.Equal(t, []RealmChange{
+			{Action: RealmChangeUpdate, Kind: RealmKindRealm, Name: realmSettings, Fields: []FieldDiff{{Field: "displayName", Current: "Old", Desired: "New"}}},
+			{Action: RealmChangeDelete, Kind: RealmKindRole, Name: "legacy"},
+			{Action: RealmChangeUpdate, Kind: RealmKindRole, Name: "reader", Fields: []FieldDiff{{Field: "description", Desired: "Read only"}}},
+			{Action: RealmChangeCreate, Kind: RealmKindRole, Name: "writer"},
+		}, changes)
+	})
+	t.Run("Target fails", func(t *testing.T) {
+		expectRealmSnapshot(mockClient, "staging", "a", "My realm", nil)
+		mockClient.EXPECT().GetRealm(token, "production").Return(keycloak.RealmRepresentation{}, errAny)
+		var _, err = diffRealms()
+		assert.Equal(t, errAny, err)
+	})
+}
+
+func TestFormatRealmChangesJSON(t *testing.T) {
+	var res, err = FormatRealmChangesJSON(nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "[]", string(res))
+
+	res, err = FormatRealmChangesJSON([]RealmChange{{Action: RealmChangeUpdate, Kind: RealmKindRole, Name: "reader", Fields: []FieldDiff{{Field: "description", Desired: "Read only"}}}})
+	assert.Nil(t, err)
+	assert.JSONEq(t, `[{"action": "update", "kind": "role", "name": "reader", "fields": [{"field": "description", "current": null, "desired": "Read only"}]}]`, string(res))
+}
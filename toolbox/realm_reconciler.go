@@ -0,0 +1,631 @@
+package toolbox
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/cloudtrust/keycloak-client/v2"
+)
+
+// Modes of the realm reconciler
+const (
+	ReconcilePlan  = "plan"  // Only computes and prints the changes
+	ReconcileApply = "apply" // Creates and updates resources
+	ReconcilePrune = "prune" // Creates and updates resources, then deletes the ones which are not part of the configuration
+)
+
+// Actions of a realm change
+const (
+	RealmChangeCreate = "create"
+	RealmChangeUpdate = "update"
+	RealmChangeDelete = "delete"
+)
+
+// Kinds of resources managed by the realm reconciler
+const (
+	RealmKindRealm          = "realm"
+	RealmKindRole           = "role"
+	RealmKindClientRole     = "clientRole"
+	RealmKindGroup          = "group"
+	RealmKindClient         = "client"
+	RealmKindIdp            = "identityProvider"
+	RealmKindComponent      = "component"
+	RealmKindRequiredAction = "requiredAction"
+	RealmKindFlow           = "flow"
+)
+
+var (
+	builtInRoles   = []string{"offline_access", "uma_authorization"}
+	builtInClients = []string{"account", "account-console", "admin-cli", "broker", "realm-management", "security-admin-console"}
+)
+
+// RealmConfigClient is the part of the Keycloak client used by the realm reconciler
+type RealmConfigClient interface {
+	ResourcesClient
+	RequiredActionsClient
+	AuthenticationFlowsClient
+	GetRealm(accessToken string, realmName string) (keycloak.RealmRepresentation, error)
+	UpdateRealm(accessToken string, realmName string, realm keycloak.RealmRepresentation) error
+	DeleteRole(accessToken string, realmName string, roleID string) error
+	DeleteGroup(accessToken string, realmName string, groupID string) error
+	DeleteClient(accessToken string, realmName, idClient string) error
+	DeleteIdp(accessToken string, realmName string, idpAlias string) error
+	DeleteComponent(accessToken string, realmName, componentID string) error
+	DeleteAuthenticationFlow(accessToken string, realmName, flowID string) error
+}
+
+// RealmChange is a difference between the live realm and its configuration
+type RealmChange struct {
+	Action string      `json:"action"`
+	Kind   string      `json:"kind"`
+	Name   string      `json:"name"`
+	Fields []FieldDiff `json:"fields,omitempty"`
+	Detail string      `json:"detail,omitempty"`
+	apply  func() error
+}
+
+func (rc RealmChange) String() string {
+	var symbol = map[string]string{RealmChangeCreate: "+", RealmChangeUpdate: "~", RealmChangeDelete: "-"}[rc.Action]
+	var res = fmt.Sprintf("%s %s %s", symbol, rc.Kind, rc.Name)
+	if rc.Detail != "" {
+		res += " (" + rc.Detail + ")"
+	}
+	return res
+}
+
+// FormatRealmChanges returns a human-readable diff. Updated fields are displayed with their current and desired values.
+func FormatRealmChanges(changes []RealmChange) string {
+	if len(changes) == 0 {
+		return "No changes\n"
+	}
+	var sb strings.Builder
+	for _, change := range changes {
+		sb.WriteString(change.String())
+		sb.WriteString("\n")
+		for _, field := range change.Fields {
+			fmt.Fprintf(&sb, "    %s: %s -> %s\n", field.Field, formatValue(field.Current), formatValue(field.Desired))
+		}
+	}
+	return sb.String()
+}
+
+func formatValue(value any) string {
+	if value == nil {
+		return "<unset>"
+	}
+	var bytes, err = json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(bytes)
+}
+
+// RealmReconciler makes a live realm match its configuration
+type RealmReconciler struct {
+	client  RealmConfigClient
+	ensurer *ResourceEnsurer
+	flows   *FlowReconciler
+	out     io.Writer
+}
+
+// NewRealmReconciler creates a RealmReconciler. The diff is printed to out before being applied. out can be nil.
+func NewRealmReconciler(client RealmConfigClient, out io.Writer) *RealmReconciler {
+	return &RealmReconciler{
+		client:  client,
+		ensurer: NewResourceEnsurer(client),
+		flows:   NewFlowReconciler(client),
+		out:     out,
+	}
+}
+
+type realmPlan struct {
+	accessToken string
+	realmName   string
+	realmID     string
+	prune       bool
+	boundFlows  []string // Aliases of the flows bound to the realm, which can't be deleted
+	changes     []RealmChange
+	deletions   []RealmChange
+}
+
+// Reconcile computes the differences between the live realm and its configuration, prints them and, unless mode is
+// ReconcilePlan, applies them. Deletions are only applied in ReconcilePrune mode.
+func (rr *RealmReconciler) Reconcile(accessToken string, realmName string, config RealmConfig, mode string) ([]RealmChange, error) {
+	if mode != ReconcilePlan && mode != ReconcileApply && mode != ReconcilePrune {
+		return nil, errors.New(keycloak.MsgErrInvalidParam + ".mode")
+	}
+	var changes, err = rr.plan(accessToken, realmName, config, mode == ReconcilePrune)
+	if err != nil {
+		return nil, err
+	}
+	if rr.out != nil {
+		if _, err = io.WriteString(rr.out, FormatRealmChanges(changes)); err != nil {
+			return changes, err
+		}
+	}
+	if mode == ReconcilePlan {
+		return changes, nil
+	}
+	for _, change := range changes {
+		if change.apply == nil {
+			continue
+		}
+		if err = change.apply(); err != nil {
+			return changes, fmt.Errorf("%s: %w", change.String(), err)
+		}
+	}
+	return changes, nil
+}
+
+func (rr *RealmReconciler) plan(accessToken string, realmName string, config RealmConfig, prune bool) ([]RealmChange, error) {
+	var realm, err = rr.client.GetRealm(accessToken, realmName)
+	if err != nil {
+		return nil, err
+	}
+	var p = &realmPlan{
+		accessToken: accessToken,
+		realmName:   realmName,
+		realmID:     realmName,
+		prune:       prune,
+	}
+	if realm.ID != nil {
+		p.realmID = *realm.ID
+	}
+	p.boundFlows = realmBoundFlows(realm)
+	if config.Realm != nil {
+		p.boundFlows = append(p.boundFlows, realmBoundFlows(*config.Realm)...)
+	}
+
+	// Flows are created before the clients and identity providers which can be bound to them
+	var steps = []func(p *realmPlan, config RealmConfig) error{
+		rr.planRoles,
+		rr.planFlows,
+		rr.planClients,
+		rr.planClientRoles,
+		rr.planGroups,
+		rr.planIdps,
+		rr.planComponents,
+		rr.planRequiredActions,
+	}
+	for _, step := range steps {
+		if err = step(p, config); err != nil {
+			return nil, err
+		}
+	}
+
+	// The realm is updated once the other resources exist: its flow bindings can refer to the flows created above
+	if config.Realm != nil {
+		var diffs []FieldDiff
+		if diffs, err = diffRepresentation(realm, *config.Realm); err != nil {
+			return nil, err
+		}
+		if len(diffs) > 0 {
+			var desired = *config.Realm
+			p.changes = append(p.changes, RealmChange{Action: RealmChangeUpdate, Kind: RealmKindRealm, Name: realmName, Fields: diffs, apply: func() error {
+				return rr.client.UpdateRealm(accessToken, realmName, desired)
+			}})
+		}
+	}
+
+	// Resources are deleted once all the other changes have been applied, dependents first
+	slices.Reverse(p.deletions)
+	return append(p.changes, p.deletions...), nil
+}
+
+// resourcePlanner compares live and desired resources of a given kind
+type resourcePlanner[T any] struct {
+	kind   string
+	key    func(T) string
+	ensure func(T) error
+	remove func(T) error
+	keep   func(T) bool // Tells whether a resource must never be pruned
+}
+
+func (rp resourcePlanner[T]) plan(p *realmPlan, live []T, desired []T) error {
+	var liveByKey = map[string]T{}
+	for _, resource := range live {
+		liveByKey[rp.key(resource)] = resource
+	}
+	var desiredKeys = map[string]bool{}
+	for _, resource := range desired {
+		var key = rp.key(resource)
+		desiredKeys[key] = true
+		var current, ok = liveByKey[key]
+		if !ok {
+			p.changes = append(p.changes, RealmChange{Action: RealmChangeCreate, Kind: rp.kind, Name: key, apply: func() error {
+				return rp.ensure(resource)
+			}})
+			continue
+		}
+		var diffs, err = diffRepresentation(current, resource)
+		if err != nil {
+			return err
+		}
+		if len(diffs) > 0 {
+			p.changes = append(p.changes, RealmChange{Action: RealmChangeUpdate, Kind: rp.kind, Name: key, Fields: diffs, apply: func() error {
+				return rp.ensure(resource)
+			}})
+		}
+	}
+	if p.prune && rp.remove != nil {
+		for _, resource := range live {
+			var key = rp.key(resource)
+			if desiredKeys[key] || (rp.keep != nil && rp.keep(resource)) {
+				continue
+			}
+			p.deletions = append(p.deletions, RealmChange{Action: RealmChangeDelete, Kind: rp.kind, Name: key, apply: func() error {
+				return rp.remove(resource)
+			}})
+		}
+	}
+	return nil
+}
+
+func (rr *RealmReconciler) planRoles(p *realmPlan, config RealmConfig) error {
+	if config.Roles == nil {
+		return nil
+	}
+	var live, err = rr.client.GetRoles(p.accessToken, p.realmName)
+	if err != nil {
+		return err
+	}
+	return resourcePlanner[keycloak.RoleRepresentation]{
+		kind: RealmKindRole,
+		key:  roleName,
+		ensure: func(role keycloak.RoleRepresentation) error {
+			var _, err = rr.ensurer.EnsureRole(p.accessToken, p.realmName, role)
+			return err
+		},
+		remove: func(role keycloak.RoleRepresentation) error {
+			return rr.client.DeleteRole(p.accessToken, p.realmName, *role.ID)
+		},
+		keep: func(role keycloak.RoleRepresentation) bool {
+			var name = roleName(role)
+			return slices.Contains(builtInRoles, name) || name == "default-roles-"+strings.ToLower(p.realmName)
+		},
+	}.plan(p, live, config.Roles)
+}
+
+func (rr *RealmReconciler) planClients(p *realmPlan, config RealmConfig) error {
+	if config.Clients == nil {
+		return nil
+	}
+	var live, err = rr.client.GetClients(p.accessToken, p.realmName)
+	if err != nil {
+		return err
+	}
+	return resourcePlanner[keycloak.ClientRepresentation]{
+		kind: RealmKindClient,
+		key:  clientID,
+		ensure: func(client keycloak.ClientRepresentation) error {
+			var _, err = rr.ensurer.EnsureClient(p.accessToken, p.realmName, client)
+			return err
+		},
+		remove: func(client keycloak.ClientRepresentation) error {
+			return rr.client.DeleteClient(p.accessToken, p.realmName, *client.ID)
+		},
+		keep: func(client keycloak.ClientRepresentation) bool {
+			return slices.Contains(builtInClients, clientID(client)) || strings.HasSuffix(clientID(client), "-realm")
+		},
+	}.plan(p, live, config.Clients)
+}
+
+func (rr *RealmReconciler) planClientRoles(p *realmPlan, config RealmConfig) error {
+	var clientIDs = make([]string, 0, len(config.ClientRoles))
+	for id := range config.ClientRoles {
+		clientIDs = append(clientIDs, id)
+	}
+	slices.Sort(clientIDs)
+
+	for _, id := range clientIDs {
+		var clients, err = rr.client.GetClients(p.accessToken, p.realmName, "clientId", id)
+		if err != nil {
+			return err
+		}
+		var live []keycloak.RoleRepresentation
+		for _, client := range clients {
+			if clientID(client) == id {
+				if live, err = rr.client.GetClientRoles(p.accessToken, p.realmName, *client.ID); err != nil {
+					return err
+				}
+				break
+			}
+		}
+		err = resourcePlanner[keycloak.RoleRepresentation]{
+			kind: RealmKindClientRole,
+			key: func(role keycloak.RoleRepresentation) string {
+				return id + "/" + roleName(role)
+			},
+			ensure: func(role keycloak.RoleRepresentation) error {
+				var _, err = rr.ensurer.EnsureClientRole(p.accessToken, p.realmName, id, role)
+				return err
+			},
+			remove: func(role keycloak.RoleRepresentation) error {
+				return rr.client.DeleteRole(p.accessToken, p.realmName, *role.ID)
+			},
+		}.plan(p, live, config.ClientRoles[id])
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (rr *RealmReconciler) planGroups(p *realmPlan, config RealmConfig) error {
+	if config.Groups == nil {
+		return nil
+	}
+	var groups, err = rr.client.GetGroups(p.accessToken, p.realmName)
+	if err != nil {
+		return err
+	}
+	var livePaths, liveGroups = flattenGroups(groups, "")
+	var desiredPaths, desiredGroups = flattenGroups(config.Groups, "")
+	var isDesiredGroup = func(path string) bool {
+		var _, ok = desiredGroups[path]
+		return ok
+	}
+	var hasDesiredDescendant = func(path string) bool {
+		for desiredPath := range desiredGroups {
+			if strings.HasPrefix(desiredPath, path+"/") {
+				return true
+			}
+		}
+		return false
+	}
+	var live, desired []keycloak.GroupRepresentation
+	for _, path := range livePaths {
+		live = append(live, liveGroups[path])
+	}
+	for _, path := range desiredPaths {
+		desired = append(desired, desiredGroups[path])
+	}
+	return resourcePlanner[keycloak.GroupRepresentation]{
+		kind: RealmKindGroup,
+		key: func(group keycloak.GroupRepresentation) string {
+			return *group.Path
+		},
+		ensure: func(group keycloak.GroupRepresentation) error {
+			var _, err = rr.ensurer.EnsureGroup(p.accessToken, p.realmName, group)
+			return err
+		},
+		remove: func(group keycloak.GroupRepresentation) error {
+			return rr.client.DeleteGroup(p.accessToken, p.realmName, *group.ID)
+		},
+		keep: func(group keycloak.GroupRepresentation) bool {
+			// Deleting a group deletes its sub-groups: groups having desired descendants are kept and only the top-most
+			// pruned groups are deleted
+			var path = *group.Path
+			var parent = path[:strings.LastIndex(path, "/")]
+			return hasDesiredDescendant(path) || (parent != "" && !hasDesiredDescendant(parent) && !isDesiredGroup(parent))
+		},
+	}.plan(p, live, desired)
+}
+
+func (rr *RealmReconciler) planIdps(p *realmPlan, config RealmConfig) error {
+	if config.IdentityProviders == nil {
+		return nil
+	}
+	var live, err = rr.client.GetIdps(p.accessToken, p.realmName)
+	if err != nil {
+		return err
+	}
+	return resourcePlanner[keycloak.IdentityProviderRepresentation]{
+		kind: RealmKindIdp,
+		key: func(idp keycloak.IdentityProviderRepresentation) string {
+			return stringOrEmpty(idp.Alias)
+		},
+		ensure: func(idp keycloak.IdentityProviderRepresentation) error {
+			var _, err = rr.ensurer.EnsureIdp(p.accessToken, p.realmName, idp)
+			return err
+		},
+		remove: func(idp keycloak.IdentityProviderRepresentation) error {
+			return rr.client.DeleteIdp(p.accessToken, p.realmName, *idp.Alias)
+		},
+	}.plan(p, live, config.IdentityProviders)
+}
+
+func (rr *RealmReconciler) planComponents(p *realmPlan, config RealmConfig) error {
+	if config.Components == nil {
+		return nil
+	}
+	var live, err = rr.client.GetComponents(p.accessToken, p.realmName)
+	if err != nil {
+		return err
+	}
+	var desired []keycloak.ComponentRepresentation
+	var providerTypes = map[string]bool{}
+	for _, component := range config.Components {
+		if component.ParentID == nil {
+			// Components are attached to the realm by default
+			component.ParentID = &p.realmID
+		}
+		providerTypes[stringOrEmpty(component.ProviderType)] = true
+		desired = append(desired, component)
+	}
+	return resourcePlanner[keycloak.ComponentRepresentation]{
+		kind: RealmKindComponent,
+		key: func(component keycloak.ComponentRepresentation) string {
+			return stringOrEmpty(component.ProviderType) + "/" + stringOrEmpty(component.ParentID) + "/" + stringOrEmpty(component.Name)
+		},
+		ensure: func(component keycloak.ComponentRepresentation) error {
+			var _, err = rr.ensurer.EnsureComponent(p.accessToken, p.realmName, component)
+			return err
+		},
+		remove: func(component keycloak.ComponentRepresentation) error {
+			return rr.client.DeleteComponent(p.accessToken, p.realmName, *component.ID)
+		},
+		keep: func(component keycloak.ComponentRepresentation) bool {
+			// Only the types of components which are configured are pruned
+			return !providerTypes[stringOrEmpty(component.ProviderType)]
+		},
+	}.plan(p, live, desired)
+}
+
+func (rr *RealmReconciler) planRequiredActions(p *realmPlan, config RealmConfig) error {
+	if config.RequiredActions == nil {
+		return nil
+	}
+	var live, err = rr.client.GetRequiredActions(p.accessToken, p.realmName)
+	if err != nil {
+		return err
+	}
+	var changes []RealmChange
+	var liveOrder []string
+	for _, action := range live {
+		if action.Alias != nil && slices.ContainsFunc(config.RequiredActions, func(spec RequiredActionSpec) bool { return spec.Alias == *action.Alias }) {
+			liveOrder = append(liveOrder, *action.Alias)
+		}
+	}
+	for _, spec := range config.RequiredActions {
+		var current = findRequiredAction(live, spec.Alias)
+		if current == nil {
+			changes = append(changes, RealmChange{Action: RealmChangeCreate, Kind: RealmKindRequiredAction, Name: spec.Alias})
+			continue
+		}
+		var diffs []FieldDiff
+		if isTrue(current.Enabled) != spec.Enabled {
+			diffs = append(diffs, FieldDiff{Field: "enabled", Current: isTrue(current.Enabled), Desired: spec.Enabled})
+		}
+		if isTrue(current.DefaultAction) != spec.DefaultAction {
+			diffs = append(diffs, FieldDiff{Field: "defaultAction", Current: isTrue(current.DefaultAction), Desired: spec.DefaultAction})
+		}
+		if len(diffs) > 0 {
+			changes = append(changes, RealmChange{Action: RealmChangeUpdate, Kind: RealmKindRequiredAction, Name: spec.Alias, Fields: diffs})
+		}
+	}
+	var desiredOrder []string
+	for _, spec := range config.RequiredActions {
+		if slices.Contains(liveOrder, spec.Alias) {
+			desiredOrder = append(desiredOrder, spec.Alias)
+		}
+	}
+	if !slices.Equal(liveOrder, desiredOrder) {
+		changes = append(changes, RealmChange{Action: RealmChangeUpdate, Kind: RealmKindRequiredAction, Name: strings.Join(desiredOrder, ","), Detail: "priority"})
+	}
+	if len(changes) > 0 {
+		// All the required actions are ensured at once
+		changes[0].apply = func() error {
+			var _, err = EnsureRequiredActions(rr.client, p.accessToken, p.realmName, config.RequiredActions)
+			return err
+		}
+		p.changes = append(p.changes, changes...)
+	}
+	return nil
+}
+
+func (rr *RealmReconciler) planFlows(p *realmPlan, config RealmConfig) error {
+	if config.Flows == nil {
+		return nil
+	}
+	for _, flow := range config.Flows {
+		var flowChanges, err = rr.flows.Plan(p.accessToken, p.realmName, flow)
+		if err != nil {
+			return err
+		}
+		for idx, flowChange := range flowChanges {
+			var change = RealmChange{Kind: RealmKindFlow, Name: flowChange.Path}
+			switch flowChange.Kind {
+			case FlowChangeCreate, FlowChangeDelete:
+				change.Action = flowChange.Kind
+				change.Detail = flowChange.Detail
+			default:
+				change.Action = RealmChangeUpdate
+				change.Detail = strings.TrimSpace(flowChange.Kind + " " + flowChange.Detail)
+			}
+			if idx == 0 {
+				// The whole flow is reconciled at once
+				change.apply = func() error {
+					var _, err = rr.flows.Apply(p.accessToken, p.realmName, flow)
+					return err
+				}
+			}
+			p.changes = append(p.changes, change)
+		}
+	}
+	if !p.prune {
+		return nil
+	}
+	var boundFlows, err = rr.boundFlows(p, config)
+	if err != nil {
+		return err
+	}
+	var live []keycloak.AuthenticationFlowRepresentation
+	if live, err = rr.client.GetAuthenticationFlows(p.accessToken, p.realmName); err != nil {
+		return err
+	}
+	for _, flow := range live {
+		if isTrue(flow.BuiltIn) || (flow.TopLevel != nil && !*flow.TopLevel) || flow.Alias == nil || flow.ID == nil {
+			continue
+		}
+		if slices.ContainsFunc(config.Flows, func(spec FlowSpec) bool { return spec.Alias == *flow.Alias }) ||
+			slices.Contains(boundFlows, *flow.Alias) || slices.Contains(boundFlows, *flow.ID) {
+			continue
+		}
+		p.deletions = append(p.deletions, RealmChange{Action: RealmChangeDelete, Kind: RealmKindFlow, Name: *flow.Alias, apply: func() error {
+			return rr.client.DeleteAuthenticationFlow(p.accessToken, p.realmName, *flow.ID)
+		}})
+	}
+	return nil
+}
+
+// boundFlows returns the flows which can't be pruned: the ones bound to the realm and the ones used by the live or
+// desired clients and identity providers. Clients refer to the flows by ID, the realm and the identity providers by
+// alias.
+func (rr *RealmReconciler) boundFlows(p *realmPlan, config RealmConfig) ([]string, error) {
+	var clients, err = rr.client.GetClients(p.accessToken, p.realmName)
+	if err != nil {
+		return nil, err
+	}
+	var idps []keycloak.IdentityProviderRepresentation
+	if idps, err = rr.client.GetIdps(p.accessToken, p.realmName); err != nil {
+		return nil, err
+	}
+	var res = slices.Clone(p.boundFlows)
+	for _, client := range append(clients, config.Clients...) {
+		if client.AuthenticationFlowBindingOverrides != nil {
+			for _, flowID := range *client.AuthenticationFlowBindingOverrides {
+				res = append(res, flowID)
+			}
+		}
+	}
+	for _, idp := range append(idps, config.IdentityProviders...) {
+		for _, alias := range []*string{idp.FirstBrokerLoginFlowAlias, idp.PostBrokerLoginFlowAlias} {
+			if alias != nil {
+				res = append(res, *alias)
+			}
+		}
+	}
+	return res, nil
+}
+
+// realmBoundFlows returns the aliases of the flows used by the bindings of realm
+func realmBoundFlows(realm keycloak.RealmRepresentation) []string {
+	var res []string
+	for _, alias := range []*string{realm.BrowserFlow, realm.RegistrationFlow, realm.DirectGrantFlow, realm.ResetCredentialsFlow,
+		realm.ClientAuthenticationFlow, realm.DockerAuthenticationFlow} {
+		if alias != nil {
+			res = append(res, *alias)
+		}
+	}
+	return res
+}
+
+func roleName(role keycloak.RoleRepresentation) string {
+	return stringOrEmpty(role.Name)
+}
+
+func clientID(client keycloak.ClientRepresentation) string {
+	return stringOrEmpty(client.ClientID)
+}
+
+func stringOrEmpty(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
@@ -0,0 +1,184 @@
+package toolbox
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/cloudtrust/keycloak-client/v2"
+	"github.com/cloudtrust/keycloak-client/v2/toolbox/mock"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+func TestFormatRealmChanges(t *testing.T) {
+	assert.Equal(t, "No changes\n", FormatRealmChanges(nil))
+	assert.Equal(t, "+ role reader\n~ realm my-realm\n    displayName: <unset> -> \"My realm\"\n- flow old (reason)\n", FormatRealmChanges([]RealmChange{
+		{Action: RealmChangeCreate, Kind: RealmKindRole, Name: "reader"},
+		{Action: RealmChangeUpdate, Kind: RealmKindRealm, Name: "my-realm", Fields: []FieldDiff{{Field: "displayName", Desired: "My realm"}}},
+		{Action: RealmChangeDelete, Kind: RealmKindFlow, Name: "old", Detail: "reason"},
+	}))
+}
+
+func TestRealmReconciler(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	var mockClient = mock.NewRealmConfigClient(mockCtrl)
+	var out bytes.Buffer
+	var reconciler = NewRealmReconciler(mockClient, &out)
+	var liveRealm = keycloak.RealmRepresentation{ID: ptr("realm-id"), Realm: ptr(realm), DisplayName: ptr("Old"), BrowserFlow: ptr("custom-browser")}
+	var liveRoles = []keycloak.RoleRepresentation{
+		{ID: ptr("id-admin"), Name: ptr("admin"), Description: ptr("Old")},
+		{ID: ptr("id-legacy"), Name: ptr("legacy")},
+		{ID: ptr("id-offline"), Name: ptr("offline_access")},
+		{ID: ptr("id-default"), Name: ptr("default-roles-" + realm)},
+	}
+
+	t.Run("Invalid mode", func(t *testing.T) {
+		var _, err = reconciler.Reconcile(token, realm, RealmConfig{}, "invalid")
+		assert.NotNil(t, err)
+	})
+	t.Run("Can't get realm", func(t *testing.T) {
+		mockClient.EXPECT().GetRealm(token, realm).Return(keycloak.RealmRepresentation{}, errAny)
+		var _, err = reconciler.Reconcile(token, realm, RealmConfig{}, ReconcilePlan)
+		assert.Equal(t, errAny, err)
+	})
+	t.Run("Plan with all sections", func(t *testing.T) {
+		var config = RealmConfig{
+			Realm:             &keycloak.RealmRepresentation{DisplayName: ptr("New")},
+			Roles:             []keycloak.RoleRepresentation{{Name: ptr("admin"), Description: ptr("New")}, {Name: ptr("reader")}},
+			ClientRoles:       map[string][]keycloak.RoleRepresentation{"my-client": {{Name: ptr("reader")}}},
+			Groups:            []keycloak.GroupRepresentation{{Name: ptr("parent"), SubGroups: &[]keycloak.GroupRepresentation{{Name: ptr("child")}}}},
+			Clients:           []keycloak.ClientRepresentation{{ClientID: ptr("my-client"), Enabled: boolPtr(true)}},
+			IdentityProviders: []keycloak.IdentityProviderRepresentation{{Alias: ptr("my-idp")}, {Alias: ptr("other-idp"), FirstBrokerLoginFlowAlias: ptr("idp-flow")}},
+			Components:        []keycloak.ComponentRepresentation{{Name: ptr("my-component"), ProviderType: ptr("my-type")}},
+			RequiredActions:   []RequiredActionSpec{{Alias: "VERIFY_EMAIL", Enabled: true}, {Alias: "CONFIGURE_TOTP", Enabled: true}},
+			Flows:             []FlowSpec{{Alias: "my-flow"}},
+		}
+		mockClient.EXPECT().GetRealm(token, realm).Return(liveRealm, nil)
+		mockClient.EXPECT().GetRoles(token, realm).Return(liveRoles, nil)
+		mockClient.EXPECT().GetClients(token, realm).Return([]keycloak.ClientRepresentation{
+			{ID: ptr("id-account"), ClientID: ptr("account"), AuthenticationFlowBindingOverrides: &map[string]string{"browser": "id-client-flow"}},
+			{ID: ptr("id-my-client"), ClientID: ptr("my-client"), Enabled: boolPtr(true)},
+		}, nil).Times(2)
+		mockClient.EXPECT().GetClients(token, realm, "clientId", "my-client").Return([]keycloak.ClientRepresentation{{ID: ptr("id-my-client"), ClientID: ptr("my-client")}}, nil)
+		mockClient.EXPECT().GetClientRoles(token, realm, "id-my-client").Return([]keycloak.RoleRepresentation{{ID: ptr("id-old"), Name: ptr("old")}}, nil)
+		mockClient.EXPECT().GetGroups(token, realm).Return([]keycloak.GroupRepresentation{
+			{ID: ptr("id-parent"), Name: ptr("parent"), Path: ptr("/parent"), SubGroups: &[]keycloak.GroupRepresentation{
+				{ID: ptr("id-child"), Name: ptr("child"), Path: ptr("/parent/child")},
+				{ID: ptr("id-unwanted"), Name: ptr("unwanted"), Path: ptr("/parent/unwanted"), SubGroups: &[]keycloak.GroupRepresentation{
+					{ID: ptr("id-deep"), Name: ptr("deep"), Path: ptr("/parent/unwanted/deep")},
+				}},
+			}},
+		}, nil)
+		mockClient.EXPECT().GetIdps(token, realm).Return([]keycloak.IdentityProviderRepresentation{
+			{Alias: ptr("other-idp"), FirstBrokerLoginFlowAlias: ptr("idp-flow")},
+		}, nil).Times(2)
+		mockClient.EXPECT().GetComponents(token, realm).Return([]keycloak.ComponentRepresentation{
+			{ID: ptr("id-comp"), Name: ptr("my-component"), ProviderType: ptr("my-type"), ParentID: ptr("realm-id")},
+			{ID: ptr("id-other"), Name: ptr("other"), ProviderType: ptr("my-type"), ParentID: ptr("realm-id")},
+			{ID: ptr("id-keys"), Name: ptr("rsa"), ProviderType: ptr("keys"), ParentID: ptr("realm-id")},
+		}, nil)
+		mockClient.EXPECT().GetRequiredActions(token, realm).Return([]keycloak.RequiredActionProviderRepresentation{
+			createRequiredAction("CONFIGURE_TOTP", true, false),
+			createRequiredAction("VERIFY_EMAIL", false, false),
+		}, nil)
+		mockClient.EXPECT().GetAuthenticationFlows(token, realm).Return([]keycloak.AuthenticationFlowRepresentation{
+			{ID: ptr("id-browser"), Alias: ptr("browser"), BuiltIn: boolPtr(true), TopLevel: boolPtr(true)},
+			{ID: ptr("id-old-flow"), Alias: ptr("old-flow"), BuiltIn: boolPtr(false), TopLevel: boolPtr(true)},
+			{ID: ptr("id-custom-browser"), Alias: ptr("custom-browser"), BuiltIn: boolPtr(false), TopLevel: boolPtr(true)},
+			{ID: ptr("id-client-flow"), Alias: ptr("client-flow"), BuiltIn: boolPtr(false), TopLevel: boolPtr(true)},
+			{ID: ptr("id-idp-flow"), Alias: ptr("idp-flow"), BuiltIn: boolPtr(false), TopLevel: boolPtr(true)},
+		}, nil).Times(2)
+
+		var changes, err = reconciler.plan(token, realm, config, true)
+		assert.Nil(t, err)
+		var lines []string
+		for _, change := range changes {
+			lines = append(lines, change.String())
+		}
+		// The flows bound to the realm, to a client or to an identity provider are not pruned
+		assert.Equal(t, []string{
+			"~ role admin",
+			"+ role reader",
+			"+ flow my-flow (basic-flow)",
+			"+ clientRole my-client/reader",
+			"+ identityProvider my-idp",
+			"~ requiredAction VERIFY_EMAIL",
+			"~ requiredAction VERIFY_EMAIL,CONFIGURE_TOTP (priority)",
+			"~ realm my-realm",
+			"- component my-type/realm-id/other",
+			"- group /parent/unwanted",
+			"- clientRole my-client/old",
+			"- flow old-flow",
+			"- role legacy",
+		}, lines)
+		assert.Contains(t, FormatRealmChanges(changes), "    displayName: \"Old\" -> \"New\"\n")
+	})
+	t.Run("Apply", func(t *testing.T) {
+		out.Reset()
+		var config = RealmConfig{Roles: []keycloak.RoleRepresentation{{Name: ptr("reader")}}}
+		mockClient.EXPECT().GetRealm(token, realm).Return(liveRealm, nil)
+		mockClient.EXPECT().GetRoles(token, realm).Return(liveRoles, nil).Times(2)
+		mockClient.EXPECT().CreateRole(token, realm, config.Roles[0]).Return("http://localhost/roles/reader", nil)
+
+		var changes, err = reconciler.Reconcile(token, realm, config, ReconcileApply)
+		assert.Nil(t, err)
+		assert.Len(t, changes, 1)
+		assert.Equal(t, "+ role reader\n", out.String())
+	})
+	t.Run("Create and bind a flow in one apply", func(t *testing.T) {
+		var config = RealmConfig{
+			Realm: &keycloak.RealmRepresentation{BrowserFlow: ptr("new-browser")},
+			Flows: []FlowSpec{{Alias: "new-browser"}},
+		}
+		mockClient.EXPECT().GetRealm(token, realm).Return(liveRealm, nil)
+		mockClient.EXPECT().GetAuthenticationFlows(token, realm).Return(nil, nil).Times(2)
+		gomock.InOrder(
+			mockClient.EXPECT().CreateAuthenticationFlow(token, realm, gomock.Any()).Return(nil),
+			mockClient.EXPECT().GetAuthenticationExecutionsForFlow(token, realm, "new-browser").Return(nil, nil),
+			mockClient.EXPECT().UpdateRealm(token, realm, *config.Realm).Return(nil),
+		)
+		var changes, err = reconciler.Reconcile(token, realm, config, ReconcileApply)
+		assert.Nil(t, err)
+		assert.Len(t, changes, 2)
+	})
+	t.Run("Second apply is a no-op", func(t *testing.T) {
+		var config = RealmConfig{Clients: []keycloak.ClientRepresentation{{ClientID: ptr("my-client"), Attributes: &map[string]any{"a": "1"}}}}
+		var liveClient = keycloak.ClientRepresentation{ID: ptr("id-my-client"), ClientID: ptr("my-client"), Enabled: boolPtr(true),
+			Attributes: &map[string]any{"a": "1", "b": "2"}}
+		var updated keycloak.ClientRepresentation
+		mockClient.EXPECT().GetRealm(token, realm).Return(liveRealm, nil)
+		mockClient.EXPECT().GetClients(token, realm).Return([]keycloak.ClientRepresentation{liveClient}, nil)
+		mockClient.EXPECT().GetClients(token, realm, "clientId", "my-client").Return([]keycloak.ClientRepresentation{liveClient}, nil)
+		mockClient.EXPECT().UpdateClient(token, realm, "id-my-client", gomock.Any()).DoAndReturn(func(_, _, _ string, client keycloak.ClientRepresentation) error {
+			updated = client
+			return nil
+		})
+		var changes, err = reconciler.Reconcile(token, realm, config, ReconcileApply)
+		assert.Nil(t, err)
+		assert.Len(t, changes, 1)
+		assert.Equal(t, map[string]any{"a": "1"}, *updated.Attributes)
+
+		mockClient.EXPECT().GetRealm(token, realm).Return(liveRealm, nil)
+		mockClient.EXPECT().GetClients(token, realm).Return([]keycloak.ClientRepresentation{updated}, nil)
+		changes, err = reconciler.Reconcile(token, realm, config, ReconcileApply)
+		assert.Nil(t, err)
+		assert.Len(t, changes, 0)
+	})
+	t.Run("Prune fails", func(t *testing.T) {
+		var config = RealmConfig{Roles: []keycloak.RoleRepresentation{{Name: ptr("admin"), Description: ptr("Old")}}}
+		mockClient.EXPECT().GetRealm(token, realm).Return(liveRealm, nil)
+		mockClient.EXPECT().GetRoles(token, realm).Return(liveRoles, nil)
+		mockClient.EXPECT().DeleteRole(token, realm, "id-legacy").Return(errAny)
+
+		var _, err = reconciler.Reconcile(token, realm, config, ReconcilePrune)
+		assert.ErrorIs(t, err, errAny)
+	})
+	t.Run("Step fails", func(t *testing.T) {
+		mockClient.EXPECT().GetRealm(token, realm).Return(liveRealm, nil)
+		mockClient.EXPECT().GetGroups(token, realm).Return(nil, errAny)
+		var _, err = reconciler.Reconcile(token, realm, RealmConfig{Groups: []keycloak.GroupRepresentation{}}, ReconcilePlan)
+		assert.Equal(t, errAny, err)
+	})
+}
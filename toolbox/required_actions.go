@@ -0,0 +1,174 @@
+package toolbox
+
+import (
+	"errors"
+	"slices"
+
+	"github.com/cloudtrust/keycloak-client/v2"
+)
+
+// RequiredActionsClient is the part of the Keycloak client used to manage required actions
+type RequiredActionsClient interface {
+	GetRequiredActions(accessToken string, realmName string) ([]keycloak.RequiredActionProviderRepresentation, error)
+	RegisterRequiredAction(accessToken string, realmName, providerID, name string) error
+	UpdateRequiredAction(accessToken string, realmName, actionAlias string, action keycloak.RequiredActionProviderRepresentation) error
+	RaiseRequiredActionPriority(accessToken string, realmName, actionAlias string) error
+	LowerRequiredActionPriority(accessToken string, realmName, actionAlias string) error
+}
+
+// RequiredActionSpec is the expected state of a required action
+type RequiredActionSpec struct {
+	Alias         string `json:"alias"`
+	Name          string `json:"name,omitempty"` // Name used when registering the required action. Alias is used when empty
+	Enabled       bool   `json:"enabled"`
+	DefaultAction bool   `json:"defaultAction"`
+}
+
+// RequiredActionsReport lists the changes applied by EnsureRequiredActions
+type RequiredActionsReport struct {
+	Registered []string
+	Updated    []string
+	Moved      []string
+	Unchanged  []string
+}
+
+// HasChanges tells whether some changes have been applied
+func (r RequiredActionsReport) HasChanges() bool {
+	return len(r.Registered) > 0 || len(r.Updated) > 0 || len(r.Moved) > 0
+}
+
+// EnsureRequiredActions registers, enables/disables, sets as default and orders the desired required actions.
+// The desired actions are sorted according to their order in the given slice. Required actions which are not part of the
+// desired ones are left untouched. Only the differences are applied.
+func EnsureRequiredActions(client RequiredActionsClient, accessToken string, realmName string, desired []RequiredActionSpec) (RequiredActionsReport, error) {
+	var report RequiredActionsReport
+
+	var seen = map[string]bool{}
+	for _, spec := range desired {
+		if seen[spec.Alias] {
+			return report, errors.New(keycloak.MsgErrInvalidParam + ".requiredAction." + spec.Alias)
+		}
+		seen[spec.Alias] = true
+	}
+
+	var actions, err = client.GetRequiredActions(accessToken, realmName)
+	if err != nil {
+		return report, err
+	}
+
+	// Register missing required actions
+	for _, spec := range desired {
+		if findRequiredAction(actions, spec.Alias) == nil {
+			var name = spec.Name
+			if name == "" {
+				name = spec.Alias
+			}
+			if err = client.RegisterRequiredAction(accessToken, realmName, spec.Alias, name); err != nil {
+				return report, err
+			}
+			report.Registered = append(report.Registered, spec.Alias)
+		}
+	}
+	if len(report.Registered) > 0 {
+		if actions, err = client.GetRequiredActions(accessToken, realmName); err != nil {
+			return report, err
+		}
+	}
+
+	// Update enabled and default flags
+	for _, spec := range desired {
+		var action = findRequiredAction(actions, spec.Alias)
+		if action == nil {
+			return report, errors.New(keycloak.MsgErrCannotObtain + ".requiredAction." + spec.Alias)
+		}
+		if isTrue(action.Enabled) != spec.Enabled || isTrue(action.DefaultAction) != spec.DefaultAction {
+			var updated = *action
+			updated.Enabled = &spec.Enabled
+			updated.DefaultAction = &spec.DefaultAction
+			if err = client.UpdateRequiredAction(accessToken, realmName, spec.Alias, updated); err != nil {
+				return report, err
+			}
+			report.Updated = append(report.Updated, spec.Alias)
+		}
+	}
+
+	// Reorder
+	if report.Moved, err = orderRequiredActions(client, accessToken, realmName, actions, desired); err != nil {
+		return report, err
+	}
+
+	for _, spec := range desired {
+		if !slices.Contains(report.Registered, spec.Alias) && !slices.Contains(report.Updated, spec.Alias) && !slices.Contains(report.Moved, spec.Alias) {
+			report.Unchanged = append(report.Unchanged, spec.Alias)
+		}
+	}
+	return report, nil
+}
+
+// orderRequiredActions sorts the desired required actions using the slots they currently occupy in the list of all
+// required actions. Moves are done with the minimal number of priority changes.
+func orderRequiredActions(client RequiredActionsClient, accessToken string, realmName string, actions []keycloak.RequiredActionProviderRepresentation, desired []RequiredActionSpec) ([]string, error) {
+	var current []string
+	for _, action := range actions {
+		if action.Alias != nil {
+			current = append(current, *action.Alias)
+		}
+	}
+	var isDesired = map[string]bool{}
+	for _, spec := range desired {
+		isDesired[spec.Alias] = true
+	}
+
+	// Compute the target position of each required action
+	var slots []int
+	for idx, alias := range current {
+		if isDesired[alias] {
+			slots = append(slots, idx)
+		}
+	}
+	var targetPosition = map[string]int{}
+	for idx, alias := range current {
+		targetPosition[alias] = idx
+	}
+	for idx, spec := range desired {
+		targetPosition[spec.Alias] = slots[idx]
+	}
+
+	// Insertion sort using adjacent swaps only
+	var moved []string
+	for i := 1; i < len(current); i++ {
+		for j := i; j > 0 && targetPosition[current[j]] < targetPosition[current[j-1]]; j-- {
+			// Prefer to move the desired required action
+			var err error
+			var alias string
+			if isDesired[current[j]] {
+				alias = current[j]
+				err = client.RaiseRequiredActionPriority(accessToken, realmName, alias)
+			} else {
+				alias = current[j-1]
+				err = client.LowerRequiredActionPriority(accessToken, realmName, alias)
+			}
+			if err != nil {
+				return moved, err
+			}
+			if !slices.Contains(moved, alias) {
+				moved = append(moved, alias)
+			}
+			current[j-1], current[j] = current[j], current[j-1]
+		}
+	}
+	return moved, nil
+}
+
+func findRequiredAction(actions []keycloak.RequiredActionProviderRepresentation, alias string) *keycloak.RequiredActionProviderRepresentation {
+	for i := range actions {
+		if actions[i].Alias != nil && *actions[i].Alias == alias {
+			return &actions[i]
+		}
+	}
+	return nil
+}
+
+func isTrue(value *bool) bool {
+	return value != nil && *value
+}
@@ -0,0 +1,111 @@
+package toolbox
+
+import (
+	"testing"
+
+	"github.com/cloudtrust/keycloak-client/v2"
+	"github.com/cloudtrust/keycloak-client/v2/toolbox/mock"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+func createRequiredAction(alias string, enabled bool, defaultAction bool) keycloak.RequiredActionProviderRepresentation {
+	return keycloak.RequiredActionProviderRepresentation{
+		Alias:         &alias,
+		ProviderID:    &alias,
+		Name:          &alias,
+		Enabled:       &enabled,
+		DefaultAction: &defaultAction,
+	}
+}
+
+func TestEnsureRequiredActions(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	var mockClient = mock.NewRequiredActionsClient(mockCtrl)
+
+	var existing = []keycloak.RequiredActionProviderRepresentation{
+		createRequiredAction("CONFIGURE_TOTP", true, false),
+		createRequiredAction("TERMS_AND_CONDITIONS", false, false),
+		createRequiredAction("UPDATE_PASSWORD", true, false),
+		createRequiredAction("VERIFY_EMAIL", true, false),
+	}
+
+	t.Run("Duplicate desired required action", func(t *testing.T) {
+		var _, err = EnsureRequiredActions(mockClient, token, realm, []RequiredActionSpec{{Alias: "VERIFY_EMAIL"}, {Alias: "VERIFY_EMAIL"}})
+		assert.NotNil(t, err)
+	})
+	t.Run("Can't get required actions", func(t *testing.T) {
+		mockClient.EXPECT().GetRequiredActions(token, realm).Return(nil, errAny)
+		var _, err = EnsureRequiredActions(mockClient, token, realm, nil)
+		assert.Equal(t, errAny, err)
+	})
+	t.Run("Registration fails", func(t *testing.T) {
+		mockClient.EXPECT().GetRequiredActions(token, realm).Return(existing, nil)
+		mockClient.EXPECT().RegisterRequiredAction(token, realm, "CUSTOM", "Custom action").Return(errAny)
+		var _, err = EnsureRequiredActions(mockClient, token, realm, []RequiredActionSpec{{Alias: "CUSTOM", Name: "Custom action"}})
+		assert.Equal(t, errAny, err)
+	})
+	t.Run("Registered action is not found", func(t *testing.T) {
+		mockClient.EXPECT().GetRequiredActions(token, realm).Return(existing, nil).Times(2)
+		mockClient.EXPECT().RegisterRequiredAction(token, realm, "CUSTOM", "CUSTOM").Return(nil)
+		var _, err = EnsureRequiredActions(mockClient, token, realm, []RequiredActionSpec{{Alias: "CUSTOM"}})
+		assert.NotNil(t, err)
+	})
+	t.Run("Update fails", func(t *testing.T) {
+		mockClient.EXPECT().GetRequiredActions(token, realm).Return(existing, nil)
+		mockClient.EXPECT().UpdateRequiredAction(token, realm, "TERMS_AND_CONDITIONS", gomock.Any()).Return(errAny)
+		var _, err = EnsureRequiredActions(mockClient, token, realm, []RequiredActionSpec{{Alias: "TERMS_AND_CONDITIONS", Enabled: true}})
+		assert.Equal(t, errAny, err)
+	})
+	t.Run("Nothing to do", func(t *testing.T) {
+		mockClient.EXPECT().GetRequiredActions(token, realm).Return(existing, nil)
+		var report, err = EnsureRequiredActions(mockClient, token, realm, []RequiredActionSpec{
+			{Alias: "CONFIGURE_TOTP", Enabled: true},
+			{Alias: "VERIFY_EMAIL", Enabled: true},
+		})
+		assert.Nil(t, err)
+		assert.False(t, report.HasChanges())
+		assert.Equal(t, []string{"CONFIGURE_TOTP", "VERIFY_EMAIL"}, report.Unchanged)
+	})
+	t.Run("Reorder fails", func(t *testing.T) {
+		mockClient.EXPECT().GetRequiredActions(token, realm).Return(existing, nil)
+		mockClient.EXPECT().LowerRequiredActionPriority(token, realm, "CONFIGURE_TOTP").Return(errAny)
+		var _, err = EnsureRequiredActions(mockClient, token, realm, []RequiredActionSpec{
+			{Alias: "VERIFY_EMAIL", Enabled: true},
+			{Alias: "CONFIGURE_TOTP", Enabled: true},
+		})
+		assert.Equal(t, errAny, err)
+	})
+	t.Run("Register, update and reorder", func(t *testing.T) {
+		var custom = createRequiredAction("CUSTOM", true, false)
+		mockClient.EXPECT().GetRequiredActions(token, realm).Return(existing, nil)
+		mockClient.EXPECT().RegisterRequiredAction(token, realm, "CUSTOM", "CUSTOM").Return(nil)
+		mockClient.EXPECT().GetRequiredActions(token, realm).Return(append(existing, custom), nil)
+		mockClient.EXPECT().UpdateRequiredAction(token, realm, "VERIFY_EMAIL", gomock.Any()).DoAndReturn(
+			func(_, _, _ string, action keycloak.RequiredActionProviderRepresentation) error {
+				assert.True(t, *action.Enabled)
+				assert.True(t, *action.DefaultAction)
+				return nil
+			})
+		// Current order is CONFIGURE_TOTP, TERMS_AND_CONDITIONS, UPDATE_PASSWORD, VERIFY_EMAIL, CUSTOM
+		// Expected order is CUSTOM, TERMS_AND_CONDITIONS, UPDATE_PASSWORD, VERIFY_EMAIL, CONFIGURE_TOTP
+		gomock.InOrder(
+			mockClient.EXPECT().LowerRequiredActionPriority(token, realm, "CONFIGURE_TOTP").Return(nil).Times(2),
+			mockClient.EXPECT().RaiseRequiredActionPriority(token, realm, "VERIFY_EMAIL").Return(nil),
+			mockClient.EXPECT().RaiseRequiredActionPriority(token, realm, "CUSTOM").Return(nil).Times(4),
+		)
+		var report, err = EnsureRequiredActions(mockClient, token, realm, []RequiredActionSpec{
+			{Alias: "CUSTOM", Enabled: true},
+			{Alias: "VERIFY_EMAIL", Enabled: true, DefaultAction: true},
+			{Alias: "CONFIGURE_TOTP", Enabled: true},
+		})
+		assert.Nil(t, err)
+		assert.True(t, report.HasChanges())
+		assert.Equal(t, []string{"CUSTOM"}, report.Registered)
+		assert.Equal(t, []string{"VERIFY_EMAIL"}, report.Updated)
+		assert.Equal(t, []string{"CONFIGURE_TOTP", "VERIFY_EMAIL", "CUSTOM"}, report.Moved)
+		assert.Nil(t, report.Unchanged)
+	})
+}
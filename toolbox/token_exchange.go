@@ -0,0 +1,133 @@
+package toolbox
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/url"
+	"strings"
+	"sync"
+
+	errorhandler "github.com/cloudtrust/common-service/v2/errors"
+	"github.com/cloudtrust/keycloak-client/v2"
+	"golang.org/x/oauth2"
+)
+
+// Token types of the token exchange (RFC 8693)
+const (
+	TokenTypeAccessToken  = "urn:ietf:params:oauth:token-type:access_token"
+	TokenTypeRefreshToken = "urn:ietf:params:oauth:token-type:refresh_token"
+	TokenTypeIDToken      = "urn:ietf:params:oauth:token-type:id_token"
+
+	grantTypeTokenExchange = "urn:ietf:params:oauth:grant-type:token-exchange"
+	// Expired tokens are removed from the cache when it grows beyond this size
+	tokenExchangeCacheSweepSize = 1000
+)
+
+// TokenExchangeRequest describes the token expected from a token exchange
+type TokenExchangeRequest struct {
+	SubjectToken       string // Access token of the user. Can be empty when impersonating with RequestedSubject.
+	Audience           string // Client ID of the client for which the token is requested
+	RequestedSubject   string // ID or username of the impersonated user
+	Scope              string // Space-separated scopes
+	RequestedTokenType string // Defaults to TokenTypeAccessToken
+}
+
+// TokenExchanger exchanges tokens with Keycloak's token exchange grant
+type TokenExchanger interface {
+	ExchangeToken(ctx context.Context, request TokenExchangeRequest) (string, error)
+	ExchangeTokenForRealm(ctx context.Context, realm string, request TokenExchangeRequest) (string, error)
+}
+
+type tokenExchanger struct {
+	perRealmRequester map[string]clientTokenRequester
+	defaultKey        string
+	logger            Logger
+	mutex             sync.Mutex
+	cache             map[string]*oauth2.Token
+}
+
+// NewTokenExchanger creates a TokenExchanger. oauth2Config is the configuration of the client which exchanges the
+// tokens and must be a service account configuration. Exchanged tokens are cached until they expire.
+func NewTokenExchanger(kcConfig keycloak.Config, oauth2Config OAuth2Config, logger Logger) TokenExchanger {
+	return &tokenExchanger{
+		perRealmRequester: newClientTokenRequesters(kcConfig, oauth2Config, nil, logger),
+		defaultKey:        kcConfig.URIProvider.GetDefaultKey(),
+		logger:            logger,
+		cache:             map[string]*oauth2.Token{},
+	}
+}
+
+func (te *tokenExchanger) ExchangeToken(ctx context.Context, request TokenExchangeRequest) (string, error) {
+	return te.ExchangeTokenForRealm(ctx, te.defaultKey, request)
+}
+
+func (te *tokenExchanger) ExchangeTokenForRealm(ctx context.Context, realm string, request TokenExchangeRequest) (string, error) {
+	var requestToken, ok = te.perRealmRequester[strings.ToLower(realm)]
+	if !ok {
+		if realm == te.defaultKey {
+			return "", errorhandler.CreateInternalServerError("unknownRealm")
+		}
+		return te.ExchangeTokenForRealm(ctx, te.defaultKey, request)
+	}
+	if request.SubjectToken == "" && request.RequestedSubject == "" {
+		return "", errors.New(keycloak.MsgErrMissingParam + ".subjectToken")
+	}
+
+	var key = request.cacheKey(strings.ToLower(realm))
+	if token, ok := te.cachedToken(key); ok {
+		return token, nil
+	}
+
+	var params = url.Values{"grant_type": {grantTypeTokenExchange}}
+	if request.SubjectToken != "" {
+		params.Set("subject_token", request.SubjectToken)
+		params.Set("subject_token_type", TokenTypeAccessToken)
+	}
+	for name, value := range map[string]string{
+		"audience":             request.Audience,
+		"requested_subject":    request.RequestedSubject,
+		"scope":                request.Scope,
+		"requested_token_type": request.RequestedTokenType,
+	} {
+		if value != "" {
+			params.Set(name, value)
+		}
+	}
+	var token, err = requestToken(params)
+	if err != nil {
+		te.logger.Warn(ctx, "msg", "Can't exchange token", "err", err.Error())
+		return "", err
+	}
+	te.storeToken(key, token)
+	return token.AccessToken, nil
+}
+
+// cacheKey identifies the request without keeping the subject token
+func (r TokenExchangeRequest) cacheKey(realm string) string {
+	var hash = sha256.Sum256([]byte(strings.Join([]string{realm, r.SubjectToken, r.Audience, r.RequestedSubject, r.Scope, r.RequestedTokenType}, "\n")))
+	return hex.EncodeToString(hash[:])
+}
+
+func (te *tokenExchanger) cachedToken(key string) (string, bool) {
+	te.mutex.Lock()
+	defer te.mutex.Unlock()
+	if token, ok := te.cache[key]; ok && token.Valid() {
+		return token.AccessToken, true
+	}
+	return "", false
+}
+
+func (te *tokenExchanger) storeToken(key string, token *oauth2.Token) {
+	te.mutex.Lock()
+	defer te.mutex.Unlock()
+	if len(te.cache) >= tokenExchangeCacheSweepSize {
+		for cachedKey, cachedToken := range te.cache {
+			if !cachedToken.Valid() {
+				delete(te.cache, cachedKey)
+			}
+		}
+	}
+	te.cache[key] = token
+}
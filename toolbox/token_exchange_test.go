@@ -0,0 +1,103 @@
+package toolbox
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/cloudtrust/keycloak-client/v2"
+	"github.com/cloudtrust/keycloak-client/v2/toolbox/mock"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+// tokenExchangeEndpoint records the requests it receives and fails when the subject token is "invalid"
+type tokenExchangeEndpoint struct {
+	forms     []url.Values
+	forwarded string
+}
+
+func (te *tokenExchangeEndpoint) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	_ = r.ParseForm()
+	te.forms = append(te.forms, r.PostForm)
+	te.forwarded = r.Header.Get("Forwarded")
+	w.Header().Set("Content-Type", "application/json")
+	if r.PostForm.Get("subject_token") == "invalid" {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error": "invalid_token"}`))
+		return
+	}
+	w.Write([]byte(`{"access_token": "exchanged-` + r.PostForm.Get("audience") + `", "token_type": "bearer", "expires_in": 300}`))
+}
+
+func TestTokenExchanger(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	var mockLogger = mock.NewLogger(mockCtrl)
+	var endpoint = &tokenExchangeEndpoint{}
+	var ts = httptest.NewServer(endpoint)
+	defer ts.Close()
+
+	var uriProvider, _ = NewKeycloakURIProviderFromArray([]string{"http://public.domain.ch"})
+	var kcConfig = keycloak.Config{URIProvider: uriProvider, AddrInternalAPI: ts.URL}
+	var exchanger = NewTokenExchanger(kcConfig, createServiceAccount("master", "gateway", "secret"), mockLogger)
+	var ctx = context.TODO()
+
+	t.Run("Exchange for audience", func(t *testing.T) {
+		endpoint.forms = nil
+		var request = TokenExchangeRequest{SubjectToken: "user-token", Audience: "backend", Scope: "openid"}
+		var token, err = exchanger.ExchangeToken(ctx, request)
+		assert.Nil(t, err)
+		assert.Equal(t, "exchanged-backend", token)
+		assert.Equal(t, "host=public.domain.ch;proto=https", endpoint.forwarded)
+		assert.Len(t, endpoint.forms, 1)
+		var form = endpoint.forms[0]
+		assert.Equal(t, grantTypeTokenExchange, form.Get("grant_type"))
+		assert.Equal(t, "user-token", form.Get("subject_token"))
+		assert.Equal(t, TokenTypeAccessToken, form.Get("subject_token_type"))
+		assert.Equal(t, "backend", form.Get("audience"))
+		assert.Equal(t, "openid", form.Get("scope"))
+		assert.Empty(t, form.Get("requested_subject"))
+
+		// The exchanged token is cached
+		token, err = exchanger.ExchangeTokenForRealm(ctx, "unknown-realm", request)
+		assert.Nil(t, err)
+		assert.Equal(t, "exchanged-backend", token)
+		assert.Len(t, endpoint.forms, 1)
+
+		// Another audience needs another exchange
+		request.Audience = "other"
+		token, err = exchanger.ExchangeToken(ctx, request)
+		assert.Nil(t, err)
+		assert.Equal(t, "exchanged-other", token)
+		assert.Len(t, endpoint.forms, 2)
+	})
+	t.Run("Direct impersonation", func(t *testing.T) {
+		endpoint.forms = nil
+		var _, err = exchanger.ExchangeToken(ctx, TokenExchangeRequest{RequestedSubject: "john", Audience: "backend"})
+		assert.Nil(t, err)
+		assert.Len(t, endpoint.forms, 1)
+		assert.Equal(t, "john", endpoint.forms[0].Get("requested_subject"))
+		assert.Empty(t, endpoint.forms[0].Get("subject_token"))
+		assert.Empty(t, endpoint.forms[0].Get("subject_token_type"))
+	})
+	t.Run("Missing subject", func(t *testing.T) {
+		var _, err = exchanger.ExchangeToken(ctx, TokenExchangeRequest{Audience: "backend"})
+		assert.NotNil(t, err)
+	})
+	t.Run("Exchange fails", func(t *testing.T) {
+		endpoint.forms = nil
+		mockLogger.EXPECT().Warn(gomock.Any(), gomock.Any()).Times(2)
+		var request = TokenExchangeRequest{SubjectToken: "invalid", Audience: "backend"}
+		var _, err = exchanger.ExchangeToken(ctx, request)
+		assert.NotNil(t, err)
+		var calls = len(endpoint.forms)
+		// Failures are not cached
+		_, err = exchanger.ExchangeToken(ctx, request)
+		assert.NotNil(t, err)
+		assert.Greater(t, len(endpoint.forms), calls)
+	})
+}
@@ -0,0 +1,83 @@
+package toolbox
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/cloudtrust/keycloak-client/v2"
+)
+
+const minBackgroundRefreshInterval = time.Second
+
+// flightCall is a request shared by concurrent callers
+type flightCall[T any] struct {
+	done   chan struct{}
+	result T
+	err    error
+}
+
+// singleFlight makes concurrent callers wait for the request already in progress, such as a token request, instead of
+// sending their own
+type singleFlight[T any] struct {
+	mutex sync.Mutex
+	call  *flightCall[T]
+}
+
+func (sf *singleFlight[T]) do(request func() (T, error)) (T, error) {
+	sf.mutex.Lock()
+	if call := sf.call; call != nil {
+		sf.mutex.Unlock()
+		<-call.done
+		return call.result, call.err
+	}
+	var call = &flightCall[T]{done: make(chan struct{})}
+	sf.call = call
+	sf.mutex.Unlock()
+
+	// The waiting callers are released even if request panics, the panic is propagated to the caller which sent it
+	var completed = false
+	defer func() {
+		if !completed {
+			call.err = errors.New(keycloak.MsgErrCannotObtain + "." + keycloak.Response)
+		}
+		sf.mutex.Lock()
+		sf.call = nil
+		sf.mutex.Unlock()
+		close(call.done)
+	}()
+	call.result, call.err = request()
+	completed = true
+	return call.result, call.err
+}
+
+// backgroundRefresher is implemented by the token providers of this package
+type backgroundRefresher interface {
+	// refreshExpiring renews the tokens which have already been provided once and expire within margin
+	refreshExpiring(ctx context.Context, margin time.Duration)
+}
+
+// RefreshInBackground renews the tokens of the provider before they expire, until ctx is done, so that ProvideToken
+// and ProvideTokenForRealm return without calling the token endpoint. Only the tokens of the realms which have
+// already been requested are renewed. margin should be larger than the time needed to get a token. Providers which
+// are not created by this package are not refreshed.
+func RefreshInBackground(ctx context.Context, provider OidcTokenProvider, margin time.Duration) {
+	var refresher, ok = provider.(backgroundRefresher)
+	if !ok {
+		return
+	}
+	var interval = max(margin/2, minBackgroundRefreshInterval)
+	go func() {
+		var ticker = time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				refresher.refreshExpiring(ctx, margin)
+			}
+		}
+	}()
+}
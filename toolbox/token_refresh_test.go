@@ -0,0 +1,171 @@
+package toolbox
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cloudtrust/keycloak-client/v2"
+	"github.com/cloudtrust/keycloak-client/v2/toolbox/mock"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+type countingTokenEndpoint struct {
+	count     atomic.Int32
+	expiresIn string
+}
+
+func (te *countingTokenEndpoint) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	te.count.Add(1)
+	time.Sleep(20 * time.Millisecond)
+	w.Header().Set("Content-Type", "application/json")
+	w.Write([]byte(`{"access_token": "access", "token_type": "bearer", "expires_in": ` + te.expiresIn + `}`))
+}
+
+func TestSingleFlight(t *testing.T) {
+	var sf singleFlight[string]
+	var count atomic.Int32
+	var release = make(chan struct{})
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Go(func() {
+			var token, err = sf.do(func() (string, error) {
+				count.Add(1)
+				<-release
+				return "token", nil
+			})
+			assert.Nil(t, err)
+			assert.Equal(t, "token", token)
+		})
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	assert.Equal(t, int32(1), count.Load())
+}
+
+func TestSingleFlightPanic(t *testing.T) {
+	var sf singleFlight[string]
+	var started = make(chan struct{})
+	var release = make(chan struct{})
+	var panicked = make(chan any)
+	go func() {
+		defer func() { panicked <- recover() }()
+		_, _ = sf.do(func() (string, error) {
+			close(started)
+			<-release
+			panic("request failed")
+		})
+	}()
+	<-started
+	var waiterErr = make(chan error)
+	go func() {
+		var _, err = sf.do(func() (string, error) { return "not called", nil })
+		waiterErr <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+
+	// The panic is propagated to the caller which sent the request, the waiting callers are released with an error
+	assert.Equal(t, "request failed", <-panicked)
+	assert.NotNil(t, <-waiterErr)
+
+	// The next requests are sent again
+	var token, err = sf.do(func() (string, error) { return "token", nil })
+	assert.Nil(t, err)
+	assert.Equal(t, "token", token)
+}
+
+func TestConcurrentProvideToken(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	var mockLogger = mock.NewLogger(mockCtrl)
+	mockLogger.EXPECT().Warn(gomock.Any(), gomock.Any()).AnyTimes()
+
+	var endpoint = &countingTokenEndpoint{expiresIn: "300"}
+	var ts = httptest.NewServer(endpoint)
+	defer ts.Close()
+
+	var uriProvider, _ = NewKeycloakURIProviderFromArray([]string{ts.URL})
+	var config = keycloak.Config{URIProvider: uriProvider, AddrInternalAPI: ts.URL}
+
+	for name, provider := range map[string]OidcTokenProvider{
+		"Technical user":  NewOAuth2TokenProvider(config, createTechnicalUser("master", "user", "passwd", "clientID"), mockLogger),
+		"Service account": NewOAuth2TokenProvider(config, createServiceAccount("master", "clientID", "secret"), mockLogger),
+	} {
+		t.Run(name, func(t *testing.T) {
+			endpoint.count.Store(0)
+			var wg sync.WaitGroup
+			for range 20 {
+				wg.Go(func() {
+					var token, err = provider.ProvideToken(context.TODO())
+					assert.Nil(t, err)
+					assert.Equal(t, "access", token)
+				})
+			}
+			wg.Wait()
+			assert.Equal(t, int32(1), endpoint.count.Load())
+		})
+	}
+}
+
+func TestRefreshExpiring(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	var mockLogger = mock.NewLogger(mockCtrl)
+	mockLogger.EXPECT().Warn(gomock.Any(), gomock.Any()).AnyTimes()
+
+	var endpoint = &countingTokenEndpoint{expiresIn: "60"}
+	var ts = httptest.NewServer(endpoint)
+	defer ts.Close()
+
+	var uriProvider, _ = NewKeycloakURIProviderFromArray([]string{ts.URL})
+	var config = keycloak.Config{URIProvider: uriProvider, AddrInternalAPI: ts.URL}
+	var ctx = context.TODO()
+
+	for name, provider := range map[string]OidcTokenProvider{
+		"Technical user":  NewOAuth2TokenProvider(config, createTechnicalUser("master", "user", "passwd", "clientID"), mockLogger),
+		"Service account": NewOAuth2TokenProvider(config, createServiceAccount("master", "clientID", "secret"), mockLogger),
+	} {
+		t.Run(name, func(t *testing.T) {
+			endpoint.count.Store(0)
+			var refresher = provider.(backgroundRefresher)
+
+			// Tokens which have never been requested are not refreshed
+			refresher.refreshExpiring(ctx, time.Minute)
+			assert.Equal(t, int32(0), endpoint.count.Load())
+
+			var _, err = provider.ProvideToken(ctx)
+			assert.Nil(t, err)
+			refresher.refreshExpiring(ctx, time.Second)
+			assert.Equal(t, int32(1), endpoint.count.Load())
+
+			// The token expires within the margin
+			refresher.refreshExpiring(ctx, 2*time.Minute)
+			assert.Equal(t, int32(2), endpoint.count.Load())
+			_, err = provider.ProvideToken(ctx)
+			assert.Nil(t, err)
+			assert.Equal(t, int32(2), endpoint.count.Load())
+		})
+	}
+}
+
+func TestRefreshInBackground(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	var ctx, cancel = context.WithCancel(context.TODO())
+	cancel()
+	// Providers which are not created by this package are ignored
+	RefreshInBackground(ctx, mock.NewOidcTokenProvider(mockCtrl), time.Minute)
+
+	var uriProvider, _ = NewKeycloakURIProviderFromArray([]string{"http://localhost"})
+	RefreshInBackground(ctx, NewOidcTokenProvider(keycloak.Config{URIProvider: uriProvider}, "master", "user", "passwd", "clientID", nil), time.Minute)
+}